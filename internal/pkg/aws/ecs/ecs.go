@@ -7,6 +7,7 @@ package ecs
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
@@ -24,6 +25,7 @@ type api interface {
 	ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error)
 	RunTask(input *ecs.RunTaskInput) (*ecs.RunTaskOutput, error)
 	StopTask(input *ecs.StopTaskInput) (*ecs.StopTaskOutput, error)
+	UpdateService(input *ecs.UpdateServiceInput) (*ecs.UpdateServiceOutput, error)
 	WaitUntilTasksRunning(input *ecs.DescribeTasksInput) error
 }
 
@@ -49,6 +51,14 @@ func New(s *session.Session) *ECS {
 	}
 }
 
+// NewWithEndpoint returns a Service configured against the input session that sends requests to a custom endpoint,
+// for example a LocalStack container for integration tests.
+func NewWithEndpoint(s *session.Session, endpoint string) *ECS {
+	return &ECS{
+		client: ecs.New(s, aws.NewConfig().WithEndpoint(endpoint)),
+	}
+}
+
 // TaskDefinition calls ECS API and returns the task definition.
 func (e *ECS) TaskDefinition(taskDefName string) (*TaskDefinition, error) {
 	resp, err := e.client.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
@@ -62,6 +72,7 @@ func (e *ECS) TaskDefinition(taskDefName string) (*TaskDefinition, error) {
 }
 
 // Service calls ECS API and returns the specified service running in the cluster.
+// If the service is reported as missing, the returned error matches ErrServiceNotFound.
 func (e *ECS) Service(clusterName, serviceName string) (*Service, error) {
 	resp, err := e.client.DescribeServices(&ecs.DescribeServicesInput{
 		Cluster:  aws.String(clusterName),
@@ -70,13 +81,29 @@ func (e *ECS) Service(clusterName, serviceName string) (*Service, error) {
 	if err != nil {
 		return nil, fmt.Errorf("describe service %s: %w", serviceName, err)
 	}
-	for _, service := range resp.Services {
-		if aws.StringValue(service.ServiceName) == serviceName {
-			svc := Service(*service)
+	return ServiceFromOutput(resp, serviceName)
+}
+
+// ServiceFromOutput returns the service, identified by its name or ARN, from the output of a DescribeServices call.
+// If the service is reported as a failure, the returned error is an *ErrServiceFailure that matches ErrServiceNotFound
+// if the service is missing.
+func ServiceFromOutput(out *ecs.DescribeServicesOutput, service string) (*Service, error) {
+	for _, described := range out.Services {
+		if aws.StringValue(described.ServiceName) == service || aws.StringValue(described.ServiceArn) == service {
+			svc := Service(*described)
 			return &svc, nil
 		}
 	}
-	return nil, fmt.Errorf("cannot find service %s", serviceName)
+	for _, failure := range out.Failures {
+		if arn := ServiceArn(aws.StringValue(failure.Arn)); isServiceArn(arn, service) {
+			return nil, &ErrServiceFailure{
+				Service: service,
+				Reason:  aws.StringValue(failure.Reason),
+				Detail:  aws.StringValue(failure.Detail),
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot find service %s", service)
 }
 
 // ServiceTasks calls ECS API and returns ECS tasks running by a service.
@@ -90,6 +117,29 @@ func (e *ECS) RunningTasksInFamily(cluster, family string) ([]*Task, error) {
 	return e.listTasks(cluster, withFamily(family), withRunningTasks())
 }
 
+// StoppedTasksInFamily calls ECS API and returns recently stopped ECS tasks within the same task definition family.
+func (e *ECS) StoppedTasksInFamily(cluster, family string) ([]*Task, error) {
+	return e.listTasks(cluster, withFamily(family), withStoppedTasks())
+}
+
+// StoppedServiceTasks calls ECS API and returns up to maxResults recently stopped ECS tasks of a service.
+// ECS only lists tasks that stopped within the last hour.
+func (e *ECS) StoppedServiceTasks(cluster, service string, maxResults int) ([]*Task, error) {
+	resp, err := e.client.ListTasks(&ecs.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+		MaxResults:    aws.Int64(int64(maxResults)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list stopped tasks of service %s: %w", service, err)
+	}
+	if len(resp.TaskArns) == 0 {
+		return nil, nil
+	}
+	return e.DescribeTasks(cluster, aws.StringValueSlice(resp.TaskArns))
+}
+
 func (e *ECS) RunningTasks(cluster string) ([]*Task, error) {
 	return e.listTasks(cluster, withRunningTasks())
 }
@@ -114,6 +164,12 @@ func withRunningTasks() listTasksOpts {
 	}
 }
 
+func withStoppedTasks() listTasksOpts {
+	return func(in *ecs.ListTasksInput) {
+		in.DesiredStatus = aws.String(ecs.DesiredStatusStopped)
+	}
+}
+
 func (e *ECS) listTasks(cluster string, opts ...listTasksOpts) ([]*Task, error) {
 	var tasks []*Task
 	in := &ecs.ListTasksInput{
@@ -181,6 +237,19 @@ func (e *ECS) StopTasks(tasks []string, opts ...StopTasksOpts) error {
 	return nil
 }
 
+// UpdateServiceTaskDefinition starts a new deployment of the service with the task definition.
+func (e *ECS) UpdateServiceTaskDefinition(cluster, service, taskDefinition string) error {
+	_, err := e.client.UpdateService(&ecs.UpdateServiceInput{
+		Cluster:        aws.String(cluster),
+		Service:        aws.String(service),
+		TaskDefinition: aws.String(taskDefinition),
+	})
+	if err != nil {
+		return fmt.Errorf("update service %s with task definition %s: %w", service, taskDefinition, err)
+	}
+	return nil
+}
+
 // DefaultCluster returns the default cluster ARN in the account and region.
 func (e *ECS) DefaultCluster() (string, error) {
 	resp, err := e.client.DescribeClusters(&ecs.DescribeClustersInput{})
@@ -273,6 +342,19 @@ func (e *ECS) DescribeTasks(cluster string, taskARNs []string) ([]*Task, error)
 	return tasks, nil
 }
 
+// isServiceArn returns true if the ARN of a service, or the service name as is, refers to the service named name.
+func isServiceArn(arn ServiceArn, name string) bool {
+	if string(arn) == name {
+		return true
+	}
+	svcName, err := arn.ServiceName()
+	if err != nil {
+		// The ARN might be in the old format without the cluster name: arn:aws:ecs:region:account:service/name.
+		return strings.HasSuffix(string(arn), "/"+name)
+	}
+	return svcName == name
+}
+
 func isRequestTimeoutErr(err error) bool {
 	if aerr, ok := err.(awserr.Error); ok {
 		return aerr.Code() == request.WaiterResourceNotReadyErrorCode
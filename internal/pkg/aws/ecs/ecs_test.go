@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/copilot-cli/internal/pkg/aws/ecs/mocks"
 	"github.com/golang/mock/gomock"
@@ -102,14 +103,29 @@ func TestECS_TaskDefinition(t *testing.T) {
 	}
 }
 
+func TestNewWithEndpoint(t *testing.T) {
+	// GIVEN
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String("us-west-2"),
+	})
+	require.NoError(t, err)
+
+	// WHEN
+	client := NewWithEndpoint(sess, "http://localhost:4566")
+
+	// THEN
+	require.Equal(t, "http://localhost:4566", client.client.(*ecs.ECS).Endpoint)
+}
+
 func TestECS_Service(t *testing.T) {
 	testCases := map[string]struct {
 		clusterName   string
 		serviceName   string
 		mockECSClient func(m *mocks.Mockapi)
 
-		wantErr error
-		wantSvc *Service
+		wantErr       error
+		wantErrIsType error
+		wantSvc       *Service
 	}{
 		"success": {
 			clusterName: "mockCluster",
@@ -158,6 +174,44 @@ func TestECS_Service(t *testing.T) {
 			},
 			wantErr: fmt.Errorf("cannot find service mockService"),
 		},
+		"errors with ErrServiceNotFound if the service is missing": {
+			clusterName: "mockCluster",
+			serviceName: "mockService",
+			mockECSClient: func(m *mocks.Mockapi) {
+				m.EXPECT().DescribeServices(&ecs.DescribeServicesInput{
+					Cluster:  aws.String("mockCluster"),
+					Services: aws.StringSlice([]string{"mockService"}),
+				}).Return(&ecs.DescribeServicesOutput{
+					Failures: []*ecs.Failure{
+						{
+							Arn:    aws.String("arn:aws:ecs:us-west-2:1234567890:service/mockCluster/mockService"),
+							Reason: aws.String("MISSING"),
+						},
+					},
+				}, nil)
+			},
+			wantErr:       fmt.Errorf("service mockService failed to be described: MISSING"),
+			wantErrIsType: ErrServiceNotFound,
+		},
+		"errors with the failure reason if the service can't be described for another reason": {
+			clusterName: "mockCluster",
+			serviceName: "mockService",
+			mockECSClient: func(m *mocks.Mockapi) {
+				m.EXPECT().DescribeServices(&ecs.DescribeServicesInput{
+					Cluster:  aws.String("mockCluster"),
+					Services: aws.StringSlice([]string{"mockService"}),
+				}).Return(&ecs.DescribeServicesOutput{
+					Failures: []*ecs.Failure{
+						{
+							Arn:    aws.String("arn:aws:ecs:us-west-2:1234567890:service/mockService"),
+							Reason: aws.String("INTERNAL"),
+							Detail: aws.String("try again"),
+						},
+					},
+				}, nil)
+			},
+			wantErr: fmt.Errorf("service mockService failed to be described: INTERNAL (try again)"),
+		},
 	}
 
 	for name, tc := range testCases {
@@ -177,6 +231,11 @@ func TestECS_Service(t *testing.T) {
 
 			if gotErr != nil {
 				require.EqualError(t, tc.wantErr, gotErr.Error())
+				if tc.wantErrIsType != nil {
+					require.True(t, errors.Is(gotErr, tc.wantErrIsType))
+				} else {
+					require.False(t, errors.Is(gotErr, ErrServiceNotFound))
+				}
 			} else {
 				require.Equal(t, tc.wantSvc, gotSvc)
 			}
@@ -185,6 +244,73 @@ func TestECS_Service(t *testing.T) {
 	}
 }
 
+func TestServiceFromOutput(t *testing.T) {
+	const (
+		name = "mockService"
+		arn  = "arn:aws:ecs:us-west-2:1234567890:service/mockCluster/mockService"
+	)
+	out := &ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceName: aws.String(name),
+				ServiceArn:  aws.String(arn),
+			},
+		},
+		Failures: []*ecs.Failure{
+			{
+				Arn:    aws.String("arn:aws:ecs:us-west-2:1234567890:service/mockCluster/otherService"),
+				Reason: aws.String("MISSING"),
+			},
+		},
+	}
+	testCases := map[string]struct {
+		service string
+
+		wantSvc       *Service
+		wantErr       error
+		wantErrIsType error
+	}{
+		"finds the service by name": {
+			service: name,
+			wantSvc: &Service{ServiceName: aws.String(name), ServiceArn: aws.String(arn)},
+		},
+		"finds the service by ARN": {
+			service: arn,
+			wantSvc: &Service{ServiceName: aws.String(name), ServiceArn: aws.String(arn)},
+		},
+		"errors with ErrServiceNotFound if the service is missing by name": {
+			service:       "otherService",
+			wantErr:       fmt.Errorf("service otherService failed to be described: MISSING"),
+			wantErrIsType: ErrServiceNotFound,
+		},
+		"errors with ErrServiceNotFound if the service is missing by ARN": {
+			service:       "arn:aws:ecs:us-west-2:1234567890:service/mockCluster/otherService",
+			wantErr:       fmt.Errorf("service arn:aws:ecs:us-west-2:1234567890:service/mockCluster/otherService failed to be described: MISSING"),
+			wantErrIsType: ErrServiceNotFound,
+		},
+		"errors if the service is not in the output": {
+			service: "unknownService",
+			wantErr: fmt.Errorf("cannot find service unknownService"),
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			gotSvc, gotErr := ServiceFromOutput(out, tc.service)
+
+			if tc.wantErr != nil {
+				require.EqualError(t, gotErr, tc.wantErr.Error())
+				if tc.wantErrIsType != nil {
+					require.True(t, errors.Is(gotErr, tc.wantErrIsType))
+				}
+			} else {
+				require.NoError(t, gotErr)
+				require.Equal(t, tc.wantSvc, gotSvc)
+			}
+		})
+	}
+}
+
 func TestECS_Tasks(t *testing.T) {
 	testCases := map[string]struct {
 		clusterName   string
@@ -327,6 +453,120 @@ func TestECS_Tasks(t *testing.T) {
 	}
 }
 
+func TestECS_StoppedTasksInFamily(t *testing.T) {
+	// GIVEN
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockECSClient := mocks.NewMockapi(ctrl)
+	mockECSClient.EXPECT().ListTasks(&ecs.ListTasksInput{
+		Cluster:       aws.String("mockCluster"),
+		Family:        aws.String("mockFamily"),
+		DesiredStatus: aws.String("STOPPED"),
+	}).Return(&ecs.ListTasksOutput{
+		TaskArns: aws.StringSlice([]string{"mockTaskArn"}),
+	}, nil)
+	mockECSClient.EXPECT().DescribeTasks(&ecs.DescribeTasksInput{
+		Cluster: aws.String("mockCluster"),
+		Tasks:   aws.StringSlice([]string{"mockTaskArn"}),
+	}).Return(&ecs.DescribeTasksOutput{
+		Tasks: []*ecs.Task{
+			{
+				TaskArn: aws.String("mockTaskArn"),
+			},
+		},
+	}, nil)
+	service := ECS{
+		client: mockECSClient,
+	}
+
+	// WHEN
+	gotTasks, gotErr := service.StoppedTasksInFamily("mockCluster", "mockFamily")
+
+	// THEN
+	require.NoError(t, gotErr)
+	require.Equal(t, []*Task{
+		{
+			TaskArn: aws.String("mockTaskArn"),
+		},
+	}, gotTasks)
+}
+
+func TestECS_StoppedServiceTasks(t *testing.T) {
+	t.Run("returns the described stopped tasks of the service", func(t *testing.T) {
+		// GIVEN
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockECSClient := mocks.NewMockapi(ctrl)
+		mockECSClient.EXPECT().ListTasks(&ecs.ListTasksInput{
+			Cluster:       aws.String("mockCluster"),
+			ServiceName:   aws.String("mockService"),
+			DesiredStatus: aws.String("STOPPED"),
+			MaxResults:    aws.Int64(10),
+		}).Return(&ecs.ListTasksOutput{
+			TaskArns: aws.StringSlice([]string{"mockTaskArn"}),
+		}, nil)
+		mockECSClient.EXPECT().DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String("mockCluster"),
+			Tasks:   aws.StringSlice([]string{"mockTaskArn"}),
+		}).Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{
+				{
+					TaskArn:       aws.String("mockTaskArn"),
+					StoppedReason: aws.String("Essential container in task exited"),
+				},
+			},
+		}, nil)
+		service := ECS{
+			client: mockECSClient,
+		}
+
+		// WHEN
+		gotTasks, gotErr := service.StoppedServiceTasks("mockCluster", "mockService", 10)
+
+		// THEN
+		require.NoError(t, gotErr)
+		require.Equal(t, []*Task{
+			{
+				TaskArn:       aws.String("mockTaskArn"),
+				StoppedReason: aws.String("Essential container in task exited"),
+			},
+		}, gotTasks)
+	})
+	t.Run("does not describe tasks if none stopped", func(t *testing.T) {
+		// GIVEN
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockECSClient := mocks.NewMockapi(ctrl)
+		mockECSClient.EXPECT().ListTasks(gomock.Any()).Return(&ecs.ListTasksOutput{}, nil)
+		service := ECS{
+			client: mockECSClient,
+		}
+
+		// WHEN
+		gotTasks, gotErr := service.StoppedServiceTasks("mockCluster", "mockService", 10)
+
+		// THEN
+		require.NoError(t, gotErr)
+		require.Empty(t, gotTasks)
+	})
+	t.Run("wraps the error listing tasks", func(t *testing.T) {
+		// GIVEN
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		mockECSClient := mocks.NewMockapi(ctrl)
+		mockECSClient.EXPECT().ListTasks(gomock.Any()).Return(nil, errors.New("some error"))
+		service := ECS{
+			client: mockECSClient,
+		}
+
+		// WHEN
+		_, gotErr := service.StoppedServiceTasks("mockCluster", "mockService", 10)
+
+		// THEN
+		require.EqualError(t, gotErr, "list stopped tasks of service mockService: some error")
+	})
+}
+
 func TestECS_StopTasks(t *testing.T) {
 	mockTasks := []string{"mockTask1", "mockTask2"}
 	mockError := errors.New("some error")
@@ -400,6 +640,53 @@ func TestECS_StopTasks(t *testing.T) {
 	}
 }
 
+func TestECS_UpdateServiceTaskDefinition(t *testing.T) {
+	testCases := map[string]struct {
+		mockECSClient func(m *mocks.Mockapi)
+
+		wantErr error
+	}{
+		"errors if failed to update the service": {
+			mockECSClient: func(m *mocks.Mockapi) {
+				m.EXPECT().UpdateService(gomock.Any()).Return(nil, errors.New("some error"))
+			},
+			wantErr: errors.New("update service mockService with task definition mockTaskDef:1: some error"),
+		},
+		"success": {
+			mockECSClient: func(m *mocks.Mockapi) {
+				m.EXPECT().UpdateService(&ecs.UpdateServiceInput{
+					Cluster:        aws.String("mockCluster"),
+					Service:        aws.String("mockService"),
+					TaskDefinition: aws.String("mockTaskDef:1"),
+				}).Return(&ecs.UpdateServiceOutput{}, nil)
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockECSClient := mocks.NewMockapi(ctrl)
+			tc.mockECSClient(mockECSClient)
+
+			service := ECS{
+				client: mockECSClient,
+			}
+
+			gotErr := service.UpdateServiceTaskDefinition("mockCluster", "mockService", "mockTaskDef:1")
+
+			if tc.wantErr != nil {
+				require.EqualError(t, gotErr, tc.wantErr.Error())
+			} else {
+				require.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
 func TestECS_DefaultCluster(t *testing.T) {
 	testCases := map[string]struct {
 		mockECSClient func(m *mocks.Mockapi)
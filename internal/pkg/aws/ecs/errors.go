@@ -16,11 +16,35 @@ const (
 	DesiredStatusStopped = ecs.DesiredStatusStopped
 
 	fmtErrContainerStopped = "task %s: %s"
+
+	serviceFailureReasonMissing = "MISSING"
 )
 
 // ErrNoDefaultCluster occurs when the default cluster is not found.
 var ErrNoDefaultCluster = errors.New("default cluster does not exist")
 
+// ErrServiceNotFound occurs when a service does not exist in the cluster.
+var ErrServiceNotFound = errors.New("service not found")
+
+// ErrServiceFailure occurs when a service is returned as a failure instead of being described.
+type ErrServiceFailure struct {
+	Service string
+	Reason  string
+	Detail  string
+}
+
+func (e *ErrServiceFailure) Error() string {
+	if e.Detail == "" {
+		return fmt.Sprintf("service %s failed to be described: %s", e.Service, e.Reason)
+	}
+	return fmt.Sprintf("service %s failed to be described: %s (%s)", e.Service, e.Reason, e.Detail)
+}
+
+// Is returns true if target is ErrServiceNotFound and the service is reported as missing.
+func (e *ErrServiceFailure) Is(target error) bool {
+	return target == ErrServiceNotFound && e.Reason == serviceFailureReasonMissing
+}
+
 // ErrWaiterResourceNotReadyForTasks contains the STOPPED reason for the container of the first task that failed to start.
 type ErrWaiterResourceNotReadyForTasks struct {
 	tasks                  []*Task
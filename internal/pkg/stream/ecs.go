@@ -4,145 +4,3083 @@
 package stream
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
 )
 
 const (
 	ecsPrimaryDeploymentStatus = "PRIMARY"
+	ecsActiveDeploymentStatus  = "ACTIVE"
+	ecsRunningTaskStatus       = "RUNNING"
+
+	defaultMaxECSFetchRetries      = 5  // Maximum number of consecutive transient Fetch errors to retry before giving up.
+	defaultMaxECSTrackedTasks      = 50 // Maximum number of tasks whose transitions are reported if not overridden.
+	defaultMaxECSExpiredCredsRetry = 3  // Maximum number of consecutive expired credentials errors to retry before giving up.
+	defaultECSRecentSnapshots      = 10 // Number of recent snapshots retained if not overridden.
+
+	defaultECSAccessDeniedGracePeriod = 30 * time.Second // How long AccessDenied errors are retried since the first Fetch if not overridden.
+	defaultECSMaxEventLookback        = 24 * time.Hour   // How far back service events are reported before the first Fetch if not overridden.
+	ecsFailureCauseWindow             = 10 * time.Minute // How long before a summary failure a specific failure can have caused it.
+
+	ecsEventsUnavailableFetches = 3  // Number of consecutive fetches with failed tasks but no service events before warning.
+	ecsMaxStoppedTasks          = 10 // Maximum number of stopped tasks described once a deployment fails.
+
+	defaultECSDeploymentFailureReason = "deployment failed"
+	ecsDeploymentTimedOutReason       = "ECS deployment timed out"
+)
+
+// ECSDeploymentOutcome is the result of a deployment once an ECSDeploymentStreamer is done.
+type ECSDeploymentOutcome string
+
+// Outcomes of a deployment. The outcome is empty while the deployment is in progress.
+const (
+	ECSDeploymentSucceeded ECSDeploymentOutcome = "SUCCEEDED"
+	ECSDeploymentFailed    ECSDeploymentOutcome = "FAILED"
+
+	// ECSDeploymentRolledBack is the outcome of an aborted deployment once the service is rolled back.
+	ECSDeploymentRolledBack ECSDeploymentOutcome = "ROLLED_BACK"
+
+	// ECSDeploymentSuperseded is the outcome of a deployment watched WithDeploymentID once it's no longer the primary
+	// deployment of the service, for example because another deployment started.
+	ECSDeploymentSuperseded ECSDeploymentOutcome = "SUPERSEDED"
+
+	// ECSDeploymentNoChanges is the outcome of a deploy that didn't start a new deployment, because the primary
+	// deployment was created before the deployment creation time and is already completed on the first Fetch.
+	// The service is steady like after a successful deployment.
+	ECSDeploymentNoChanges ECSDeploymentOutcome = "NO_CHANGES"
+)
+
+// isSuccessful returns true if the service is steady on the wanted revision once the deployment is done.
+func (o ECSDeploymentOutcome) isSuccessful() bool {
+	return o == ECSDeploymentSucceeded || o == ECSDeploymentNoChanges
+}
+
+// ErrStreamerClosed is returned when attempting to reuse a streamer whose subscriptions are closed.
+var ErrStreamerClosed = errors.New("streamer is closed")
+
+// errFetchTimeout is returned when describing the service takes longer than the fetch timeout.
+var errFetchTimeout = errors.New("describe service timed out")
+
+// ErrAbortUnsupported is returned when aborting a deployment with a streamer created without WithServiceUpdater.
+var ErrAbortUnsupported = errors.New("streamer cannot update the service to abort the deployment")
+
+// ErrServiceNotWatchable is returned by Start when the service exists but the streamer can't watch its deployment.
+var ErrServiceNotWatchable = errors.New("service is not watchable")
+
+// ECSServiceDescriber is the interface to describe an ECS service.
+type ECSServiceDescriber interface {
+	Service(clusterName, serviceName string) (*ecs.Service, error)
+}
+
+// ECSServiceTasksDescriber is the interface to describe the tasks of an ECS service.
+type ECSServiceTasksDescriber interface {
+	ServiceTasks(clusterName, serviceName string) ([]*ecs.Task, error)
+}
+
+// ECSStoppedTasksDescriber is the interface to describe the recently stopped tasks of an ECS service.
+type ECSStoppedTasksDescriber interface {
+	StoppedServiceTasks(clusterName, serviceName string, maxResults int) ([]*ecs.Task, error)
+}
+
+// ECSTargetHealthDescriber is the interface to list the healthy targets of a load balancer target group.
+type ECSTargetHealthDescriber interface {
+	HealthyTargets(targetGroupARN string) ([]ECSTarget, error)
+}
+
+// ECSTarget is a target registered in a load balancer target group.
+type ECSTarget struct {
+	ID   string // IP address of the task for the ip target type, or ID of its EC2 instance for the instance target type.
+	Port int
+}
+
+// ECSServiceUpdater is the interface needed to roll back a service to the task definition of a previous deployment.
+type ECSServiceUpdater interface {
+	UpdateServiceTaskDefinition(clusterName, serviceName, taskDefinition string) error
+}
+
+// ECSDeploymentHook is the interface to record the progress and outcome of a deployment in a telemetry system,
+// for example as events and attributes of a tracing span.
+type ECSDeploymentHook interface {
+	// RecordEvent is called with each snapshot that has new failures or notices, or whose deployments changed.
+	RecordEvent(ev ECSService)
+	// RecordCompletion is called once the deployment is done. The failure reason is empty unless the deployment failed.
+	RecordCompletion(completion ECSDeploymentCompletion, failureReason string)
+}
+
+// ECSRolloutState is the normalized rollout state of an ECS deployment.
+type ECSRolloutState string
+
+// Rollout states of a deployment. Raw rollout states that aren't known, including empty ones, are ECSRolloutStateUnknown.
+const (
+	ECSRolloutStateUnknown    ECSRolloutState = "UNKNOWN"
+	ECSRolloutStateInProgress ECSRolloutState = awsecs.DeploymentRolloutStateInProgress
+	ECSRolloutStateCompleted  ECSRolloutState = awsecs.DeploymentRolloutStateCompleted
+	ECSRolloutStateFailed     ECSRolloutState = awsecs.DeploymentRolloutStateFailed
+)
+
+// ParseECSRolloutState returns the rollout state matching the raw rollout state of a deployment.
+func ParseECSRolloutState(raw string) ECSRolloutState {
+	switch state := ECSRolloutState(raw); state {
+	case ECSRolloutStateInProgress, ECSRolloutStateCompleted, ECSRolloutStateFailed:
+		return state
+	default:
+		return ECSRolloutStateUnknown
+	}
+}
+
+// IsTerminal returns true if the deployment is no longer rolling out.
+func (s ECSRolloutState) IsTerminal() bool {
+	return s == ECSRolloutStateCompleted || s == ECSRolloutStateFailed
+}
+
+// IsFailed returns true if the deployment failed to roll out.
+func (s ECSRolloutState) IsFailed() bool {
+	return s == ECSRolloutStateFailed
+}
+
+// rolloutState returns the normalized rollout state of an ECS deployment.
+func rolloutState(deployment *awsecs.Deployment) ECSRolloutState {
+	return ParseECSRolloutState(aws.StringValue(deployment.RolloutState))
+}
+
+// ECSDeployment represent an ECS rolling update deployment.
+type ECSDeployment struct {
+	Status          string `json:"status"`
+	TaskDefRevision string `json:"taskDefRevision"`
+	DesiredCount    int    `json:"desiredCount"`
+	RunningCount    int    `json:"runningCount"`
+	FailedCount     int    `json:"failedCount"`
+	PendingCount    int    `json:"pendingCount"`
+	RolloutState    string `json:"rolloutState,omitempty"` // Raw rollout state, see ParsedRolloutState.
+
+	// RolloutStateReason explains the RolloutState.
+	// It is empty if the streamer is created WithDedupedRolloutStateReasons and the reason did not change since the last description.
+	RolloutStateReason string `json:"rolloutStateReason,omitempty"`
+
+	// StabilityStatus is the stability of a task set, it is only set if the streamer is created WithTaskSets.
+	StabilityStatus string `json:"stabilityStatus,omitempty"`
+
+	// LaunchType, such as FARGATE or EC2, and PlatformVersion of the deployment's tasks.
+	// They are only set if the streamer is created WithLaunchDetails.
+	LaunchType      string `json:"launchType,omitempty"`
+	PlatformVersion string `json:"platformVersion,omitempty"`
+
+	// ID of the deployment, or of the task set if the streamer is created WithTaskSets. Deployments forced without
+	// a new task definition share the revision of the previous deployment, so the ID tells them apart.
+	ID string `json:"id,omitempty"`
+}
+
+// ParsedRolloutState returns the normalized RolloutState of the deployment.
+func (d ECSDeployment) ParsedRolloutState() ECSRolloutState {
+	return ParseECSRolloutState(d.RolloutState)
+}
+
+// ECSNoticeSeverity is the severity of an ECSNotice.
+type ECSNoticeSeverity string
+
+// Severities for an ECSNotice.
+const (
+	ECSNoticeInfo    ECSNoticeSeverity = "INFO"
+	ECSNoticeWarning ECSNoticeSeverity = "WARNING"
+)
+
+// ECSNoticeKind identifies the condition that an ECSNotice reports.
+type ECSNoticeKind string
+
+// Kinds of ECSNotice.
+const (
+	ECSNoticeRevisionChanged ECSNoticeKind = "RevisionChanged"
+	ECSNoticeClusterChanged  ECSNoticeKind = "ClusterChanged"
+	ECSNoticeSerialRollout   ECSNoticeKind = "SerialRollout"
+
+	// ECSNoticeEventsUnavailable reports that tasks are failing while ECS returns no service events, for example
+	// because of restricted permissions, so the lack of failure events doesn't mean that the deployment is healthy.
+	ECSNoticeEventsUnavailable ECSNoticeKind = "EventsUnavailable"
+
+	// ECSNoticeDesiredCountChanged reports that the desired count of the primary deployment changed without a new
+	// deployment, usually because service auto scaling moved the completion target of the deployment.
+	ECSNoticeDesiredCountChanged ECSNoticeKind = "DesiredCountChanged"
+
+	// ECSNoticeSpotInterruption reports a service event about Spot tasks being interrupted, which explains a drop
+	// of the running count without being a deployment failure.
+	ECSNoticeSpotInterruption ECSNoticeKind = "SpotInterruption"
+
+	// ECSNoticeTasksStuckPending reports that many tasks of the primary deployment stayed PENDING without any new
+	// running task, which usually means that the tasks can't be placed or that the cluster lacks capacity.
+	ECSNoticeTasksStuckPending ECSNoticeKind = "TasksStuckPending"
+
+	// ECSNoticeWaitingForPriorDeployment reports that the watched deployment can't start yet because ECS is still
+	// rolling out an earlier deployment of the service, so the counts are the ones of the earlier deployment.
+	ECSNoticeWaitingForPriorDeployment ECSNoticeKind = "WaitingForPriorDeployment"
+
+	// ECSNoticeAlreadyCompleted reports that the deployment had already succeeded when the streamer first fetched
+	// the service, so there is no progress to report.
+	ECSNoticeAlreadyCompleted ECSNoticeKind = "AlreadyCompleted"
+
+	// ECSNoticeEventLookbackClamped reports that the deployment creation time was further in the past than the maximum
+	// event lookback, so older service events are ignored, see WithMaxEventLookback.
+	ECSNoticeEventLookbackClamped ECSNoticeKind = "EventLookbackClamped"
+
+	// ECSNoticeHealthCheckGracePeriod reports a service event about tasks failing their load balancer health checks
+	// while still within the health check grace period, which is expected while the tasks start up.
+	ECSNoticeHealthCheckGracePeriod ECSNoticeKind = "HealthCheckGracePeriod"
+
+	// ECSNoticeLikelyToFail is an early warning that ECS is unable to consistently start tasks of the service,
+	// which usually precedes the deployment circuit breaker failing the deployment and rolling it back.
+	ECSNoticeLikelyToFail ECSNoticeKind = "LikelyToFail"
 )
 
-var ecsEventFailureKeywords = []string{"fail", "unhealthy", "error", "throttle", "unable", "missing"}
+// ECSNotice is a message about the deployment inferred by the streamer rather than reported by ECS as a service event.
+type ECSNotice struct {
+	Kind     ECSNoticeKind     `json:"kind"`
+	Severity ECSNoticeSeverity `json:"severity"`
+	Message  string            `json:"message"`
+}
+
+// ECSObservedRevision is a task definition revision observed on the primary deployment while streaming.
+type ECSObservedRevision struct {
+	Revision  string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// ECSRolloutPhase is a period of time during which the primary deployment stayed in the same rollout state.
+type ECSRolloutPhase struct {
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"startedAt"` // Time of the Fetch that first observed the state.
+	EndedAt   time.Time `json:"endedAt"`   // Time of the Fetch that observed the next state, zero for the current phase.
+}
+
+// ECSDeploymentStart marks the first description of the service emitted by a streamer created WithStartEvent.
+type ECSDeploymentStart struct {
+	DeploymentCreationTime time.Time `json:"deploymentCreationTime"` // Service events created before are ignored.
+	WatchStartedAt         time.Time `json:"watchStartedAt"`
+
+	// CircuitBreaker is the deployment circuit breaker configuration of the service, nil if the service has none.
+	CircuitBreaker *ECSCircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// ECSCircuitBreaker is the deployment circuit breaker configuration of a service, which tells what happens
+// when the deployment fails to reach a steady state.
+type ECSCircuitBreaker struct {
+	Enabled  bool `json:"enabled"`  // True if the deployment fails instead of retrying to launch tasks.
+	Rollback bool `json:"rollback"` // True if the service rolls back to the last completed deployment on failure.
+}
+
+// ECSDeploymentCompletion describes when and how a deployment ended.
+type ECSDeploymentCompletion struct {
+	Outcome     ECSDeploymentOutcome `json:"outcome"`
+	StartedAt   time.Time            `json:"startedAt"`   // Deployment creation time.
+	CompletedAt time.Time            `json:"completedAt"` // Time at which the streamer observed the outcome.
+	Elapsed     time.Duration        `json:"elapsed"`
+
+	// MinRunningCount and MaxRunningCount are the range of running counts of the primary deployment
+	// observed while watching, a large swing reveals tasks that were replaced during the deployment.
+	MinRunningCount int `json:"minRunningCount"`
+	MaxRunningCount int `json:"maxRunningCount"`
+
+	// LoadBalancers are the load balancers that route traffic to the service, for example to link to their
+	// target groups in the console. It is only set if the streamer is created WithLoadBalancers.
+	LoadBalancers []ECSLoadBalancer `json:"loadBalancers,omitempty"`
+}
+
+// ECSLoadBalancer is a load balancer that routes traffic to a container of the service.
+type ECSLoadBalancer struct {
+	TargetGroupARN   string `json:"targetGroupArn,omitempty"`
+	LoadBalancerName string `json:"loadBalancerName,omitempty"` // Only set for Classic Load Balancers.
+	ContainerName    string `json:"containerName"`
+	ContainerPort    int    `json:"containerPort"`
+}
+
+// ECSAttemptFailure is a failure reported while watching one of the deployments of the service, see WithFailureHistory.
+type ECSAttemptFailure struct {
+	Attempt int               `json:"attempt"` // Number of the deployment, starting at 1 and incremented by each Reset.
+	Failure ECSServiceFailure `json:"failure"`
+}
+
+// ECSTaskPlacement is the number of tasks of the primary deployment in an availability zone.
+// Tasks that are not placed yet are reported under an empty AvailabilityZone.
+type ECSTaskPlacement struct {
+	AvailabilityZone string `json:"availabilityZone"`
+	RunningCount     int    `json:"runningCount"`
+	PendingCount     int    `json:"pendingCount"`
+}
+
+// ECSService is a description of an ECS service.
+// Every subscriber receives its own copy of the description, so it is safe for subscribers to mutate it.
+type ECSService struct {
+	Deployments         []ECSDeployment          `json:"deployments"`
+	LatestFailureEvents []string                 `json:"latestFailureEvents,omitempty"`
+	LatestFailures      []ECSServiceFailure      `json:"latestFailures,omitempty"` // Classification of LatestFailureEvents, in the same order.
+	TaskPlacement       []ECSTaskPlacement       `json:"taskPlacement,omitempty"`  // Only set if the streamer is created WithTaskPlacement.
+	Notices             []ECSNotice              `json:"notices,omitempty"`
+	Completion          *ECSDeploymentCompletion `json:"completion,omitempty"` // Only set on the last description, once the deployment is done.
+	EventStaleness      time.Duration            `json:"eventStaleness"`       // Time elapsed since the most recent service event, or since the deployment started if there is none.
+
+	// OtherDeploymentsCount is the number of deployments omitted from Deployments besides the primary one.
+	// It is only set if the streamer is created WithPrimaryDeploymentOnly.
+	OtherDeploymentsCount int `json:"otherDeploymentsCount,omitempty"`
+
+	// Start is only set on the synthetic first description emitted by a streamer created WithStartEvent.
+	Start *ECSDeploymentStart `json:"start,omitempty"`
+
+	// Sequence is assigned by Notify to each emitted description, starting at 1 and incremented by one per description.
+	// It is shared by all subscribers so that a gap reveals descriptions a filtered subscriber did not receive.
+	Sequence uint64 `json:"sequence,omitempty"`
+
+	// FailureCategoryCounts is the number of failure events reported by category since the streamer started watching,
+	// including the ones of previous descriptions. It is nil until a failure is reported.
+	FailureCategoryCounts map[ECSFailureCategory]int `json:"failureCategoryCounts,omitempty"`
+
+	// WaitingFor is the ID of the earlier deployment that is still in progress, empty unless the watched deployment
+	// waits for it to finish before starting.
+	WaitingFor string `json:"waitingFor,omitempty"`
+
+	// CorrelationID is the ID supplied WithCorrelationID to tie the descriptions to the operation that started the
+	// deployment, it is set on each description emitted by Notify.
+	CorrelationID string `json:"correlationID,omitempty"`
+
+	// Draining is the drain progress of the previous deployments still ACTIVE while the primary one rolls out.
+	// It is only set if the streamer is created WithDrainProgress.
+	Draining []ECSDrainProgress `json:"draining,omitempty"`
+
+	// TaskTransitions are the tasks of the primary deployment whose status changed since the previous description.
+	// It is only set if the streamer is created WithTaskTransitions.
+	TaskTransitions []ECSTaskTransition `json:"taskTransitions,omitempty"`
+}
+
+// ECSTaskTransition is a change of the last status of a task, such as from PROVISIONING to PENDING.
+type ECSTaskTransition struct {
+	TaskARN string    `json:"taskARN"`
+	From    string    `json:"from"` // Empty when the task is first observed.
+	To      string    `json:"to"`
+	At      time.Time `json:"at"` // Time of the Fetch that observed the transition.
+}
+
+// ECSDrainProgress is how many tasks of a previous deployment are still running as the primary deployment replaces them.
+type ECSDrainProgress struct {
+	TaskDefRevision string `json:"taskDefRevision"`
+	FromCount       int    `json:"fromCount"` // Running count of the deployment when the streamer first observed it as ACTIVE.
+	RunningCount    int    `json:"runningCount"`
+}
+
+// Primary returns the primary deployment of the service, and false if there is none.
+func (s ECSService) Primary() (ECSDeployment, bool) {
+	for _, d := range s.Deployments {
+		if d.Status == ecsPrimaryDeploymentStatus {
+			return d, true
+		}
+	}
+	return ECSDeployment{}, false
+}
+
+// RunningCountByRevision returns the number of running tasks of each task definition revision across deployments,
+// for example while the tasks of a new deployment replace the ones of the previous deployment.
+// Revisions without running tasks are omitted.
+func (s ECSService) RunningCountByRevision() map[string]int {
+	counts := make(map[string]int)
+	for _, d := range s.Deployments {
+		if d.RunningCount > 0 {
+			counts[d.TaskDefRevision] += d.RunningCount
+		}
+	}
+	return counts
+}
+
+// coalesce returns a copy of the next snapshot that also holds the failures and notices of s.
+// The failures of next are more recent, so they come first to keep the failures sorted from newest to oldest.
+func (s ECSService) coalesce(next ECSService) ECSService {
+	c := next.clone()
+	c.LatestFailureEvents = append(c.LatestFailureEvents, s.LatestFailureEvents...)
+	c.LatestFailures = append(c.LatestFailures, s.LatestFailures...)
+	c.Notices = append(s.Notices, c.Notices...)
+	c.TaskTransitions = append(s.TaskTransitions, c.TaskTransitions...)
+	if c.Completion == nil {
+		c.Completion = s.Completion
+	}
+	return c
+}
+
+// isMeaningfulSince returns true if the snapshot has new failures, notices, task transitions or a completion,
+// or if its deployments differ from the ones of the previous snapshot.
+func (s ECSService) isMeaningfulSince(prev ECSService) bool {
+	if len(s.LatestFailureEvents) > 0 || len(s.Notices) > 0 || len(s.TaskTransitions) > 0 || s.Completion != nil {
+		return true
+	}
+	if len(s.Deployments) != len(prev.Deployments) {
+		return true
+	}
+	for i := range s.Deployments {
+		if s.Deployments[i] != prev.Deployments[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// clone returns a deep copy of the service description so that subscribers don't share slices.
+func (s ECSService) clone() ECSService {
+	c := s
+	if s.Deployments != nil {
+		c.Deployments = make([]ECSDeployment, len(s.Deployments))
+		copy(c.Deployments, s.Deployments)
+	}
+	if s.LatestFailureEvents != nil {
+		c.LatestFailureEvents = make([]string, len(s.LatestFailureEvents))
+		copy(c.LatestFailureEvents, s.LatestFailureEvents)
+	}
+	if s.LatestFailures != nil {
+		c.LatestFailures = make([]ECSServiceFailure, len(s.LatestFailures))
+		copy(c.LatestFailures, s.LatestFailures)
+	}
+	if s.TaskPlacement != nil {
+		c.TaskPlacement = make([]ECSTaskPlacement, len(s.TaskPlacement))
+		copy(c.TaskPlacement, s.TaskPlacement)
+	}
+	if s.Notices != nil {
+		c.Notices = make([]ECSNotice, len(s.Notices))
+		copy(c.Notices, s.Notices)
+	}
+	if s.Completion != nil {
+		completion := *s.Completion
+		if s.Completion.LoadBalancers != nil {
+			completion.LoadBalancers = make([]ECSLoadBalancer, len(s.Completion.LoadBalancers))
+			copy(completion.LoadBalancers, s.Completion.LoadBalancers)
+		}
+		c.Completion = &completion
+	}
+	if s.Start != nil {
+		start := *s.Start
+		if s.Start.CircuitBreaker != nil {
+			cb := *s.Start.CircuitBreaker
+			start.CircuitBreaker = &cb
+		}
+		c.Start = &start
+	}
+	if s.FailureCategoryCounts != nil {
+		c.FailureCategoryCounts = copyFailureCategoryCounts(s.FailureCategoryCounts)
+	}
+	if s.Draining != nil {
+		c.Draining = make([]ECSDrainProgress, len(s.Draining))
+		copy(c.Draining, s.Draining)
+	}
+	if s.TaskTransitions != nil {
+		c.TaskTransitions = make([]ECSTaskTransition, len(s.TaskTransitions))
+		copy(c.TaskTransitions, s.TaskTransitions)
+	}
+	return c
+}
+
+// ECSDeploymentStreamerDebugState is a snapshot of the internal state of an ECSDeploymentStreamer.
+type ECSDeploymentStreamerDebugState struct {
+	Cluster                string               `json:"cluster"`
+	Service                string               `json:"service"`
+	DeploymentCreationTime time.Time            `json:"deploymentCreationTime"`
+	LastFetchedAt          time.Time            `json:"lastFetchedAt"`
+	Deployments            []ECSDeployment      `json:"deployments"`
+	RunningCountByRevision map[string]int       `json:"runningCountByRevision"`
+	PastEventsCount        int                  `json:"pastEventsCount"`
+	PendingEventsCount     int                  `json:"pendingEventsCount"`
+	Outcome                ECSDeploymentOutcome `json:"outcome"`
+
+	MinRunningCount int `json:"minRunningCount"`
+	MaxRunningCount int `json:"maxRunningCount"`
+	SubscriberCount int `json:"subscriberCount"` // See ECSDeploymentStreamer.SubscriberCount.
+
+	// LastRequestID is the AWS request ID of the last DescribeServices call, only set with an ECSRequestIDDescriber.
+	LastRequestID string `json:"lastRequestID,omitempty"`
+}
+
+// ECSDeploymentStreamer is a Streamer for ECSService descriptions until the deployment is completed.
+type ECSDeploymentStreamer struct {
+	client                 ECSServiceDescriber
+	cluster                string
+	service                string
+	deploymentCreationTime time.Time
+
+	// Optional configuration.
+	tasksClient ECSServiceTasksDescriber
+
+	targetRunningCount   int64 // Number of running tasks to wait for instead of the desired count.
+	targetRunningPercent int64 // Percentage of the desired count of running tasks to wait for.
+	dedupeReasons        bool
+	watchTaskSets        bool
+	launchDetails        bool
+	stabilityDwell       time.Duration   // How long the service must stay steady before the deployment succeeds.
+	onFetchError         func(err error) // Called with each transient error that is retried.
+	isRetryable          func(err error) bool
+	refreshCredentials   func() error
+	correlationID        string
+	maxRecentSnapshots   int
+	writers              []*ecsEventWriter
+	minEmitInterval      time.Duration // Minimum time between two snapshots sent to subscribers.
+	resolveCluster       func(service string) (string, error)
+	updater              ECSServiceUpdater
+	stallTimeout         time.Duration // How long the primary deployment can go without progress before failing.
+	maxFailedTasks       int64         // Number of failed tasks without progress before failing.
+	maxInitialEventAge   time.Duration // Maximum age of the events reported on the first Fetch.
+	fetchTimeout         time.Duration // Maximum time to wait for the service description.
+	onSteadyState        func(ECSService)
+	onFailure            func(ECSService)
+	primaryOnly          bool
+	targetHealth         ECSTargetHealthDescriber
+	healthTasks          ECSServiceTasksDescriber // Describes the tasks whose targets are counted WithTargetHealth.
+	recordPhases         bool
+	expectedRevision     string // Task definition revision that the primary deployment must be on.
+	emitStart            bool
+	hook                 ECSDeploymentHook
+	anchorToPrimary      bool
+	deploymentID         string // ID of the deployment to watch instead of the primary one.
+	stoppedTasks         ECSStoppedTasksDescriber
+	logger               ECSEventLogger
+	broadMissing         bool          // True if any service event containing "missing" is a failure.
+	initialTimeout       time.Duration // How long the deployment that creates the service can take before failing.
+	updateTimeout        time.Duration // How long the deployment of an existing service can take before failing.
+	pendingThreshold     int64         // Number of PENDING tasks from which the primary deployment may be stuck.
+	pendingWindow        time.Duration // How long the tasks can stay PENDING without progress before warning.
+	accessDeniedGrace    time.Duration // How long AccessDenied errors are retried since the first Fetch.
+	emitLoadBalancers    bool
+	quietUntilFailure    bool
+	maxFailureHistory    int           // Maximum number of failures retained across deployments, only set WithFailureHistory.
+	emitEvery            time.Duration // Interval at which snapshots are emitted by Stream, only set WithEmitSchedule.
+	maxEventLookback     time.Duration // How far back service events are reported before the first Fetch.
+	requireMinHealthy    bool          // True if the running count must meet the minimum healthy percent of the initial desired count.
+	drainProgress        bool
+	formatFailure        func(msg string, category ECSFailureCategory) string
+	transitionTasks      ECSServiceTasksDescriber
+	maxTrackedTasks      int
+
+	now func() time.Time // Overridden in tests.
+
+	mu               sync.Mutex // Guards the state below, which can be read by accessors while the streamer is running.
+	subscribers      []chan ECSService
+	deltaSubscribers []chan ECSServiceDelta
+	filters          map[chan ECSService]func(ECSService) bool // Predicates of the subscribers created with SubscribeFiltered.
+	overflows        map[chan ECSService]ECSOverflowPolicy     // Policies of the subscribers created with SubscribeBuffered.
+	closed           bool
+	done             chan struct{}
+	eventsToFlush    []ECSService
+	attempt          int                 // Number of the deployment being watched, incremented by each Reset.
+	failureHistory   []ECSAttemptFailure // Failures of the current and previous deployments, oldest first.
+
+	ecsDeploymentState // State of the deployment being watched, which Reset starts over.
+}
+
+// ecsDeploymentState is the state that an ECSDeploymentStreamer tracks for the deployment it watches.
+type ecsDeploymentState struct {
+	pastEventIDs  map[string]bool
+	startToFlush  *ECSService // Start event to send before eventsToFlush, only set WithStartEvent.
+	hasFetched    bool
+	lastFetchedAt time.Time
+	firstFetchAt  time.Time       // When Fetch was first called, which starts the AccessDenied grace period.
+	lastEventAt   time.Time       // Creation time of the most recent service event observed.
+	lastEventIDs  map[string]bool // IDs of the service events seen that were created at lastEventAt.
+	resumedAt     time.Time       // Creation time of the most recent service event observed by the state restored with UnmarshalState.
+	deployments   []ECSDeployment // Deployments as of the last Fetch.
+	latest        ECSService      // Snapshot stored by the last Fetch.
+
+	primaryRevision   string                // Task definition revision of the primary deployment when last fetched.
+	primaryID         string                // ID of the primary deployment when last fetched.
+	primaryDesired    int64                 // Desired count of the primary deployment when last fetched.
+	observedRevisions []ECSObservedRevision // Revisions of the primary deployment in the order they were first seen.
+	phases            []ECSRolloutPhase     // Rollout phases of the primary deployment, only recorded WithPhaseLog.
+	outcome           ECSDeploymentOutcome
+	failureReason     string
+	failureCategory   ECSFailureCategory     // Category of the failure event used as failureReason, if any.
+	rolloutCause      ECSRolloutFailureCause // Set if ECS failed the rollout of the deployment.
+	completedAt       time.Time
+	steadySince       time.Time // When the service last reached its target without new failures.
+	noticedSerial     bool      // True if the serial rollout notice was already emitted.
+	noticedNoEvents   bool      // True if the events unavailable notice was already emitted.
+	noEventFetches    int       // Number of consecutive fetches with failed tasks but no service events.
+	rollbackTaskDef   string    // Task definition ARN of the most recent deployment before the primary one.
+	aborted           bool      // True if the deployment was aborted and the service is rolling back.
+	latestClassified  string    // Message of the most recent failure event with a known category.
+	failuresAnchor    time.Time // Creation time of the oldest failure events reported WithFailuresSincePrimary.
+	lastProgressAt    time.Time // When the running count of the primary deployment last reached a new high.
+	peakRunning       int64     // Highest running count of the primary deployment since it was first tracked.
+	progressID        string    // ID of the primary deployment whose progress is tracked.
+	failedAtProgress  int64     // Failed tasks count of the primary deployment when progress was last observed.
+	pendingSince      time.Time // When the primary deployment reached the pending threshold without progress since.
+	minRunning        int       // Lowest running count of the primary deployment observed while watching.
+	maxRunning        int       // Highest running count of the primary deployment observed while watching.
+	observedRunning   bool      // True once the running count of a primary deployment was observed.
+	initialDeployment bool      // True if the first Fetch found a service created since the deployment creation time.
+	noticedPending    bool      // True if the stuck pending notice was emitted since pendingSince.
+
+	emittedReasons map[string]string // Last rollout state reason emitted by deployment ID.
+	retries        int               // Number of consecutive transient Fetch errors.
+	expiredRetries int               // Number of consecutive expired credentials Fetch errors.
+
+	recentSnapshots []ECSService // Ring buffer of the last maxRecentSnapshots snapshots.
+	recentStart     int          // Index of the oldest snapshot in recentSnapshots once full.
+
+	pendingEmit   *ECSService // Snapshot coalescing the events throttled since lastEmittedAt.
+	lastEmittedAt time.Time
+	sequence      uint64     // Sequence number of the last description emitted by Notify.
+	lastEmitted   ECSService // Last description emitted, which the next delta is computed against.
+
+	failureCounts  map[ECSFailureCategory]int // Number of failure events reported by category.
+	loadBalancers  []ECSLoadBalancer          // Load balancers of the service as of the last Fetch, only set WithLoadBalancers.
+	waitingFor     string                     // ID of the earlier deployment in progress that the watched one waits for.
+	stoppedReasons []string                   // Distinct stopped reasons of the failed deployment's tasks, only set WithStoppedTaskReasons.
+	failedID       string                     // ID of the deployment or task set whose tasks failed the deployment.
+
+	minHealthyPercent int64            // Minimum healthy percent of the service as of the last Fetch, only set WithMinimumHealthyPercent.
+	initialDesired    map[string]int64 // Desired count of each deployment when first observed, only set WithMinimumHealthyPercent.
+	drainFrom         map[string]int   // Running count of each ACTIVE revision when first observed, only set WithDrainProgress.
+
+	latestCause   ECSServiceFailure // Most recent failure with a known category, which can explain a subsequent summary failure.
+	latestCauseAt time.Time         // Creation time of the service event of latestCause.
+
+	trackedDeployment string            // ID of the deployment whose tasks are tracked, only set WithTaskTransitions.
+	taskStatuses      map[string]string // Last status of each tracked task by ARN, only set WithTaskTransitions.
+
+	latestCategory ECSFailureCategory // Category of latestClassified.
+	failureMsgs    []string           // Messages of the failures reported while watching the deployment, oldest first.
+}
+
+// newECSDeploymentState returns the state of a deployment that wasn't fetched yet.
+func newECSDeploymentState() ecsDeploymentState {
+	return ecsDeploymentState{
+		pastEventIDs:   make(map[string]bool),
+		emittedReasons: make(map[string]string),
+	}
+}
+
+// ECSDeploymentStreamerOpt configures an optional setting of an ECSDeploymentStreamer.
+type ECSDeploymentStreamerOpt func(*ECSDeploymentStreamer)
+
+// WithTaskPlacement describes the tasks of the primary deployment on every Fetch to report
+// how many of them are running or pending in each availability zone.
+// This results in an additional API call per Fetch, so it should only be used for debugging.
+func WithTaskPlacement(tasks ECSServiceTasksDescriber) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.tasksClient = tasks
+	}
+}
+
+// WithTaskTransitions describes the tasks of the primary deployment on every Fetch to report each change of their
+// last status, such as PROVISIONING to PENDING to RUNNING, in TaskTransitions. A task that is no longer listed
+// transitions to STOPPED. At most max tasks are tracked at once, further tasks are ignored until tracked ones stop.
+// If max is not positive, at most 50 tasks are tracked. This results in an additional API call per Fetch, so it should
+// only be used for debugging, such as slow task starts. With WithTaskPlacement, the tasks are only described once.
+func WithTaskTransitions(tasks ECSServiceTasksDescriber, max int) ECSDeploymentStreamerOpt {
+	if max <= 0 {
+		max = defaultMaxECSTrackedTasks
+	}
+	return func(s *ECSDeploymentStreamer) {
+		s.transitionTasks = tasks
+		s.maxTrackedTasks = max
+	}
+}
+
+// WithStoppedTaskReasons describes the recently stopped tasks of the deployment once it fails, to report why
+// they stopped with StoppedTaskReasons, such as "CannotPullContainerError: ...", which is often more precise
+// than the service events. At most 10 stopped tasks are described.
+func WithStoppedTaskReasons(tasks ECSStoppedTasksDescriber) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.stoppedTasks = tasks
+	}
+}
+
+// WithTargetRunningCount considers the deployment completed once the primary deployment has at least count running tasks,
+// instead of waiting for all of its desired tasks. The target is capped to the desired count of the deployment.
+func WithTargetRunningCount(count int) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.targetRunningCount = int64(count)
+	}
+}
+
+// WithTargetRunningPercent considers the deployment completed once the primary deployment has at least
+// percent of its desired tasks running, instead of waiting for all of them.
+func WithTargetRunningPercent(percent int) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.targetRunningPercent = int64(percent)
+	}
+}
+
+// WithMinimumHealthyPercent also requires the running count of the primary deployment to meet the minimum healthy
+// percent of the service applied to the desired count the deployment was first observed with, before considering the
+// deployment completed. It avoids completing early while the desired count is temporarily lowered during the rollout.
+// The requirement is waived once ECS reports the rollout as completed.
+func WithMinimumHealthyPercent() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.requireMinHealthy = true
+	}
+}
+
+// WithDrainProgress reports how many tasks of the previous deployments are still running in ECSService.Draining,
+// alongside the progress of the primary deployment, for example to show that the old revision went from 4 to 2 tasks.
+func WithDrainProgress() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.drainProgress = true
+	}
+}
+
+// WithDedupedRolloutStateReasons only sets the RolloutStateReason of a deployment when it differs from the
+// reason sent in a previous description, so that consumers don't repeatedly display the same reason.
+func WithDedupedRolloutStateReasons() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.dedupeReasons = true
+	}
+}
+
+// WithOnFetchError calls fn with each transient error, such as throttling, encountered while fetching
+// the service description. These errors are retried on the next Fetch instead of terminating the stream.
+// Errors that are not retried are still returned by Fetch and are not passed to fn.
+func WithOnFetchError(fn func(err error)) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.onFetchError = fn
+	}
+}
+
+// WithIsRetryable classifies the errors encountered while fetching the service description with fn instead of the
+// built-in classification, which retries throttling, transient and, for a grace period, AccessDenied errors.
+// Errors for which fn returns true are retried like transient errors, up to the same number of consecutive retries,
+// and passed to the WithOnFetchError callback. Other errors are returned by Fetch.
+func WithIsRetryable(fn func(err error) bool) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.isRetryable = fn
+	}
+}
+
+// WithCredentialsRefresher calls refresh when describing the service fails because the credentials of the session
+// expired, such as short-lived assumed-role credentials outlasted by a long deployment, before retrying on the next Fetch.
+// If refresh returns an error, Fetch returns it. Expired credentials are retried a few consecutive times with or without
+// this option, so that a session that refreshes its credentials by itself recovers.
+func WithCredentialsRefresher(refresh func() error) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.refreshCredentials = refresh
+	}
+}
+
+// WithCorrelationID sets id as the CorrelationID of each description emitted, and adds it to the records written
+// WithEventLogger, so that the output of the streamer can be tied to the operation that started the deployment,
+// such as the ID of a deploy orchestrated by the caller.
+func WithCorrelationID(id string) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.correlationID = id
+	}
+}
+
+// WithFailureHistory retains the failures reported while watching the deployment, and carries them over when the streamer
+// is Reset to watch a subsequent deployment. Each failure is tagged with the attempt it was reported in, so that the
+// failures of a deployment can be shown along with the ones of the re-deploy that followed, see FailureHistory.
+// At most max failures are retained, the oldest ones are dropped first. If max is not positive, no failure is retained.
+func WithFailureHistory(max int) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.maxFailureHistory = max
+	}
+}
+
+// WithRecentSnapshots retains the last n snapshots of the service, available through RecentSnapshots.
+// If n is not positive, no snapshot is retained.
+func WithRecentSnapshots(n int) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.maxRecentSnapshots = n
+	}
+}
+
+// WithLaunchDetails sets the launch type and platform version of each deployment.
+func WithLaunchDetails() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.launchDetails = true
+	}
+}
+
+// WithStabilityDwell waits for the service to hold its target running count, without new failure events,
+// for the duration d before the deployment is considered successful.
+// If the running count drops below the target or a failure occurs, the wait starts over.
+func WithStabilityDwell(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.stabilityDwell = d
+	}
+}
+
+// WithMinEmitInterval sends at most one snapshot every interval d to subscribers and writers.
+// Snapshots fetched in between are coalesced: the latest snapshot is sent along with the failures and notices
+// of all the coalesced ones. The last snapshot is sent without delay once the deployment is done.
+func WithMinEmitInterval(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.minEmitInterval = d
+	}
+}
+
+// WithClusterResolver calls resolve to find the new cluster of the service if the service or its cluster can no longer
+// be found, for example because the cluster was re-created, and continues streaming from the new cluster.
+// If resolve returns an error, Fetch returns it. By default, Fetch errors if the service cannot be found.
+func WithClusterResolver(resolve func(service string) (cluster string, err error)) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.resolveCluster = resolve
+	}
+}
+
+// WithServiceUpdater allows the streamer to abort the deployment with AbortDeployment.
+func WithServiceUpdater(updater ECSServiceUpdater) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.updater = updater
+	}
+}
+
+// WithStallTimeout fails the deployment if the running count of the primary deployment does not exceed its highest
+// value so far for the duration d before reaching its target.
+func WithStallTimeout(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.stallTimeout = d
+	}
+}
+
+// WithMaxFailedTasks fails the deployment early once n tasks of the primary deployment failed to start
+// since its running count last reached a new high.
+func WithMaxFailedTasks(n int) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.maxFailedTasks = int64(n)
+	}
+}
+
+// WithPendingWarning emits a warning once at least threshold tasks of the primary deployment stayed PENDING
+// for the duration window without any new running task. Unlike WithStallTimeout, the deployment doesn't fail.
+func WithPendingWarning(threshold int, window time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.pendingThreshold = int64(threshold)
+		s.pendingWindow = window
+	}
+}
+
+// WithDeploymentTimeouts fails the deployment if it doesn't complete within a timeout since the deployment creation
+// time. The initial deployment of a newly created service, which provisions network interfaces, pulls images and
+// passes the first health checks, is given the initial timeout, while updates of an existing service are given
+// the update timeout. A zero timeout doesn't limit the corresponding kind of deployment.
+func WithDeploymentTimeouts(initial, update time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.initialTimeout = initial
+		s.updateTimeout = update
+	}
+}
+
+// WithAccessDeniedGracePeriod retries AccessDenied errors for the duration d since the first Fetch, after which they
+// terminate the stream. Permissions can take a while to propagate right after the service's role or policies were
+// created or updated, so describing the service can be denied at first. A zero duration never retries them.
+func WithAccessDeniedGracePeriod(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.accessDeniedGrace = d
+	}
+}
+
+// WithFailureMessageFormatter rewrites the message of each failure event with format, given the raw message and its
+// category, for example to only keep the salient clause or to prepend an icon. The formatted message is the one reported
+// in LatestFailureEvents, LatestFailures and the failure reason. By default, messages are reported as is.
+func WithFailureMessageFormatter(format func(msg string, category ECSFailureCategory) string) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.formatFailure = format
+	}
+}
+
+// WithMissingKeyword reports any service event containing "missing" as a failure. By default, only the events
+// about a missing image, secret or parameter are failures, since the keyword alone also matches benign events.
+func WithMissingKeyword() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.broadMissing = true
+	}
+}
+
+// WithMaxEventLookback ignores the service events created more than d before the first Fetch, even if they were created
+// after the deployment creation time. It guards against a deployment creation time far in the past reporting a flood of
+// old events, and a warning notice is emitted if the creation time is clamped. By default, the lookback is 24 hours.
+// A zero duration doesn't bound the lookback.
+func WithMaxEventLookback(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.maxEventLookback = d
+	}
+}
+
+// WithMaxInitialEventAge ignores the events older than age on the first Fetch, even if they were created after the
+// deployment creation time. It prevents reporting past failures again when resuming a watch with an approximate
+// deployment creation time.
+func WithMaxInitialEventAge(age time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.maxInitialEventAge = age
+	}
+}
+
+// WithFetchTimeout bounds the time spent waiting for the service description on each Fetch.
+// A description that takes longer is treated as a transient error, and retried on the next Fetch.
+func WithFetchTimeout(d time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.fetchTimeout = d
+	}
+}
+
+// WithOnSteadyState calls fn once with the last snapshot of the service when the deployment succeeds,
+// including when there were no changes to deploy, before the streamer's Done channel is closed.
+// It is not called if the deployment fails.
+func WithOnSteadyState(fn func(ECSService)) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.onSteadyState = fn
+	}
+}
+
+// WithOnFailure calls fn once with the last snapshot of the service when the deployment fails,
+// before the streamer's Done channel is closed.
+func WithOnFailure(fn func(ECSService)) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.onFailure = fn
+	}
+}
+
+// WithPrimaryDeploymentOnly only includes the primary deployment in the Deployments of each snapshot,
+// and counts the other ones in OtherDeploymentsCount.
+func WithPrimaryDeploymentOnly() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.primaryOnly = true
+	}
+}
+
+// WithExpectedRevision fails the deployment if the primary deployment is on a task definition revision other
+// than the expected one, for example because another deployment overwrote it, instead of reporting the wrong revision
+// as completed. The deployment fails once no deployment is on the expected revision anymore, or the deployment on
+// the other revision completes.
+func WithExpectedRevision(revision string) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.expectedRevision = revision
+	}
+}
+
+// WithStartEvent emits a synthetic description of the service before any other one, holding the deployments with their
+// initial counts as of the first Fetch, and the deployment creation time and circuit breaker configuration in Start. The start event is sent on the
+// first Notify and is never coalesced with other descriptions, so that subscribers can initialize deterministically.
+func WithStartEvent() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.emitStart = true
+	}
+}
+
+// WithDeploymentHook calls hook with the meaningful snapshots of the service and on completion,
+// after Fetch stores them, so that the deployment can be observed with existing telemetry.
+func WithDeploymentHook(hook ECSDeploymentHook) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.hook = hook
+	}
+}
+
+// WithFailuresSincePrimary only reports the failure events created since the current primary deployment was created,
+// instead of since the deployment creation time of the streamer. When another revision is deployed over a failed one,
+// the failures of the previous revision are no longer reported nor used as the failure reason.
+func WithFailuresSincePrimary() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.anchorToPrimary = true
+	}
+}
+
+// WithDeploymentID keys the completion of the streamer to the deployment with the given ID, such as the deployment
+// started by a CI job, instead of to whichever deployment is primary. The deployment succeeds once it reaches its
+// running target while primary, fails if its rollout fails, and completes with the ECSDeploymentSuperseded outcome
+// as soon as it's no longer the primary deployment of the service. Once the deployment is aborted with AbortDeployment,
+// the rollback deployment is watched instead. The option doesn't apply to task sets.
+func WithDeploymentID(id string) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.deploymentID = id
+	}
+}
+
+// WithPhaseLog records the rollout states of the primary deployment as a compact log of phases, where consecutive
+// fetches in the same state are coalesced into a single phase. The log is available from PhaseLog.
+// Task sets, when watched WithTaskSets, are logged by their stability status.
+func WithPhaseLog() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.recordPhases = true
+	}
+}
+
+// WithTargetHealth waits for the targets of the primary deployment's tasks to be healthy in each of its load balancer
+// target groups before the deployment is considered successful, since ECS counts a task as running before it passes
+// health checks. The tasks are described on every Fetch to tell their targets apart from the ones of the tasks of
+// previous deployments: targets are matched by the IP address of the tasks, or by the host port bound by their
+// containers for the instance target type. Services without a target group only rely on the running count.
+func WithTargetHealth(health ECSTargetHealthDescriber, tasks ECSServiceTasksDescriber) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.targetHealth = health
+		s.healthTasks = tasks
+	}
+}
+
+// WithEmitSchedule emits a snapshot every interval when the streamer is driven by Stream, instead of after each Fetch,
+// so that dashboards are updated at a steady pace even though fetches are delayed by retries. Each tick emits the
+// snapshots fetched since the previous tick coalesced into one, or repeats the latest snapshot without its failures
+// and notices if nothing was fetched since. The start event and the completion are still emitted right away.
+// It takes precedence over WithMinEmitInterval.
+func WithEmitSchedule(interval time.Duration) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.emitEvery = interval
+	}
+}
+
+// WithQuietUntilFailure only emits the descriptions with new failures and the last description once the deployment
+// is done, so that the progress of many services deployed in parallel doesn't drown out their failures.
+// Unlike subscribers created with SubscribeFiltered, it applies to all subscribers and writers.
+func WithQuietUntilFailure() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.quietUntilFailure = true
+	}
+}
+
+// WithLoadBalancers reports the load balancers of the service on the completion of the deployment, so that
+// the target groups can be linked to once the deployment is done. Services without a load balancer report none.
+func WithLoadBalancers() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.emitLoadBalancers = true
+	}
+}
+
+// WithTaskSets watches the task sets of the service instead of its deployments.
+// Services with an EXTERNAL deployment controller report their progress through task sets,
+// and the deployment is considered done once the PRIMARY task set reaches a steady state.
+func WithTaskSets() ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.watchTaskSets = true
+	}
+}
+
+// NewECSDeploymentStreamer creates a new ECSDeploymentStreamer that streams service descriptions
+// since the deployment creation time and until the primary deployment is completed.
+func NewECSDeploymentStreamer(ecs ECSServiceDescriber, cluster, service string, deploymentCreationTime time.Time, opts ...ECSDeploymentStreamerOpt) *ECSDeploymentStreamer {
+	s := &ECSDeploymentStreamer{
+		client:                 ecs,
+		cluster:                cluster,
+		service:                service,
+		deploymentCreationTime: deploymentCreationTime,
+		done:                   make(chan struct{}),
+		ecsDeploymentState:     newECSDeploymentState(),
+		onFetchError:           func(error) {},
+		maxRecentSnapshots:     defaultECSRecentSnapshots,
+		accessDeniedGrace:      defaultECSAccessDeniedGracePeriod,
+		maxEventLookback:       defaultECSMaxEventLookback,
+		attempt:                1,
+		now:                    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Cluster returns the name of the cluster of the service watched by the streamer.
+func (s *ECSDeploymentStreamer) Cluster() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cluster
+}
+
+// Service returns the name of the service watched by the streamer.
+func (s *ECSDeploymentStreamer) Service() string {
+	return s.service
+}
+
+// Subscribe returns a read-only channel that will receive service descriptions from the ECSDeploymentStreamer.
+func (s *ECSDeploymentStreamer) Subscribe() <-chan ECSService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(chan ECSService)
+	s.subscribers = append(s.subscribers, c)
+	return c
+}
+
+// SubscribeFiltered returns a read-only channel that only receives the service descriptions for which pred returns true,
+// for example only the descriptions with failures. Descriptions that don't match are skipped for this subscriber
+// without being queued, so they never block it.
+func (s *ECSDeploymentStreamer) SubscribeFiltered(pred func(ECSService) bool) <-chan ECSService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(chan ECSService)
+	s.subscribers = append(s.subscribers, c)
+	if s.filters == nil {
+		s.filters = make(map[chan ECSService]func(ECSService) bool)
+	}
+	s.filters[c] = pred
+	return c
+}
+
+// ECSOverflowPolicy is what happens to a description sent to a subscriber whose buffer is full.
+type ECSOverflowPolicy string
+
+// Overflow policies of the subscribers created with SubscribeBuffered.
+const (
+	// ECSOverflowBlock waits for the subscriber to receive from its full buffer, which delays the descriptions sent to
+	// the other subscribers and writers until then. No description is lost. It's the policy of Subscribe.
+	ECSOverflowBlock ECSOverflowPolicy = "BLOCK"
+
+	// ECSOverflowDropOldest discards the oldest description of the full buffer to queue the new one, so that the
+	// subscriber always receives the most recent descriptions without ever blocking the others.
+	ECSOverflowDropOldest ECSOverflowPolicy = "DROP_OLDEST"
+
+	// ECSOverflowDropNewest discards the new description while the buffer is full, so that the subscriber receives
+	// the descriptions queued first without ever blocking the others.
+	ECSOverflowDropNewest ECSOverflowPolicy = "DROP_NEWEST"
+)
+
+// SubscribeBuffered returns a read-only channel buffering up to size service descriptions, and that applies the
+// overflow policy to the descriptions sent while the buffer is full. A size that isn't positive buffers one description.
+// Descriptions dropped by the policy are never sent to the subscriber, however the completion can only be dropped by
+// ECSOverflowDropNewest, so consumers that must see it should receive until the channel is closed.
+func (s *ECSDeploymentStreamer) SubscribeBuffered(size int, policy ECSOverflowPolicy) <-chan ECSService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size <= 0 {
+		size = 1
+	}
+	c := make(chan ECSService, size)
+	s.subscribers = append(s.subscribers, c)
+	if s.overflows == nil {
+		s.overflows = make(map[chan ECSService]ECSOverflowPolicy)
+	}
+	s.overflows[c] = policy
+	return c
+}
+
+// Fetch retrieves and stores ECSService descriptions since the deployment's creation time
+// until the primary deployment's running count is equal to its desired count.
+// If an error occurs from describe service, returns a wrapped err that matches ecs.ErrServiceNotFound if the service is missing.
+// Otherwise, returns the time the next Fetch should be attempted.
+func (s *ECSDeploymentStreamer) Fetch() (next time.Time, err error) {
+	return s.fetch(context.Background(), false)
+}
+
+// Start performs the first Fetch and validates that the deployment of the service can be watched, so that a command
+// can fail fast before streaming, for example before drawing any progress. It returns a wrapped ecs.ErrServiceNotFound
+// if the service is missing, and an error matching ErrServiceNotWatchable if the service uses a deployment controller
+// that the streamer isn't created for, or has no primary deployment. Unlike Fetch, errors are never retried.
+// If ctx is canceled before the service is described, Start returns the error of ctx without waiting for the description.
+func (s *ECSDeploymentStreamer) Start(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := s.fetch(ctx, true)
+	return err
+}
+
+// fetch implements Fetch, and validates that the service can be watched before storing its description if validate is true.
+// The service description is abandoned if ctx is canceled.
+func (s *ECSDeploymentStreamer) fetch(ctx context.Context, validate bool) (next time.Time, err error) {
+	s.mu.Lock()
+	if s.firstFetchAt.IsZero() {
+		s.firstFetchAt = s.now()
+	}
+	s.mu.Unlock()
+	desc, err := s.describe(ctx)
+	var notices []ECSNotice
+	if (errors.Is(err, ecs.ErrServiceNotFound) || isClusterNotFoundError(err)) && s.resolveCluster != nil {
+		var notice ECSNotice
+		if notice, err = s.updateCluster(); err != nil {
+			s.logFetchError(err, false)
+			return next, err
+		}
+		notices = append(notices, notice)
+		desc, err = s.describe(ctx)
+	}
+	if err == nil && validate {
+		err = s.validateWatchable(desc.service)
+	}
+	if err != nil && validate {
+		s.logFetchError(err, false)
+		return next, err
+	}
+	if err != nil {
+		return s.retry(err)
+	}
+
+	s.mu.Lock()
+	s.retries = 0
+	s.expiredRetries = 0
+	first := !s.hasFetched
+	if first {
+		s.initialDeployment = s.isCreatedByDeployment(desc.service)
+		notices = append(notices, s.lookbackClampedNotice()...)
+	}
+	wasDone := s.outcome != ""
+	prev := s.latest
+	var ev ECSService
+	var eventNotices []ECSNotice
+	ev.LatestFailureEvents, ev.LatestFailures, eventNotices = s.newFailures(desc.service.Events, s.failuresSince(desc.service))
+	notices = append(notices, eventNotices...)
+	if s.failureCounts != nil {
+		ev.FailureCategoryCounts = copyFailureCategoryCounts(s.failureCounts)
+	}
+	s.recordFailureHistory(ev.LatestFailures)
+	if len(ev.LatestFailures) > 0 {
+		s.steadySince = time.Time{} // New failures restart the stability dwell time.
+	}
+	if s.watchTaskSets {
+		ev.Deployments, ev.Notices = s.updateTaskSets(desc.service.TaskSets)
+	} else {
+		if s.requireMinHealthy {
+			s.updateMinimumHealthy(desc.service)
+		}
+		ev.Deployments, ev.Notices = s.updateDeployments(desc.service.Deployments, desc.healthyTargets)
+		if s.drainProgress {
+			ev.Draining = s.updateDraining(ev.Deployments)
+		}
+		ev.Notices = append(ev.Notices, s.serialRolloutNotice(desc.service)...)
+		ev.Notices = append(ev.Notices, s.eventsUnavailableNotice(desc)...)
+	}
+	ev.Notices = append(notices, ev.Notices...)
+	ev.TaskPlacement = desc.placement
+	if s.transitionTasks != nil && desc.primaryID != "" {
+		ev.TaskTransitions = s.taskTransitions(desc.primaryID, desc.primaryTasks)
+	}
+	ev.WaitingFor = s.waitingFor
+	if s.emitLoadBalancers {
+		s.loadBalancers = loadBalancers(desc.service.LoadBalancers)
+	}
+	s.deployments = ev.Deployments
+	s.observeRunningCount()
+	if s.primaryOnly {
+		primary, ok := ev.Primary()
+		ev.Deployments, ev.OtherDeploymentsCount = nil, len(s.deployments)
+		if ok {
+			ev.Deployments, ev.OtherDeploymentsCount = []ECSDeployment{primary}, len(s.deployments)-1
+		}
+	}
+	if s.emitStart && !s.hasFetched {
+		s.startToFlush = &ECSService{
+			Deployments: ev.Deployments,
+			Start: &ECSDeploymentStart{
+				DeploymentCreationTime: s.deploymentCreationTime,
+				WatchStartedAt:         s.now(),
+				CircuitBreaker:         circuitBreaker(desc.service),
+			},
+		}
+	}
+	s.hasFetched = true
+	s.lastFetchedAt = s.now()
+	ev.EventStaleness = s.eventStaleness()
+	if s.recordPhases {
+		s.recordPhase()
+	}
+	if !wasDone && s.outcome != "" {
+		ev.Completion = s.completion()
+		s.endPhase(s.completedAt)
+		if first && s.outcome == ECSDeploymentSucceeded {
+			ev.Notices = append(ev.Notices, ECSNotice{
+				Kind:     ECSNoticeAlreadyCompleted,
+				Severity: ECSNoticeInfo,
+				Message:  "the deployment had already completed when the watch started, there is no progress to report",
+			})
+		}
+	}
+	s.eventsToFlush = append(s.eventsToFlush, ev)
+	s.latest = ev
+	s.recordSnapshot(ev)
+	next = s.lastFetchedAt.Add(streamerFetchIntervalDuration)
+	done := s.done
+	failureReason := s.failureReason
+	category := s.failureCategory
+	cluster := s.cluster
+	failedID := s.failedID
+	s.mu.Unlock()
+
+	if ev.Completion != nil && ev.Completion.Outcome == ECSDeploymentFailed && s.stoppedTasks != nil {
+		s.recordStoppedTaskReasons(failedID)
+	}
+	if s.logger != nil && ev.isMeaningfulSince(prev) {
+		s.logEvent(cluster, ev, failureReason, category)
+	}
+	if s.hook != nil {
+		if ev.isMeaningfulSince(prev) {
+			s.hook.RecordEvent(ev.clone())
+		}
+		if ev.Completion != nil {
+			s.hook.RecordCompletion(*ev.Completion, failureReason)
+		}
+	}
+	if ev.Completion != nil {
+		// Run the callbacks without holding the lock, so that they can use the streamer's accessors.
+		s.runCompletionCallback(ev)
+		close(done)
+	}
+	return next, nil
+}
+
+// recordStoppedTaskReasons keeps the distinct reasons why the recently stopped tasks of the deployment stopped,
+// most recently stopped first. The deployment is already done, so the stopped tasks are reported on a best effort
+// basis: an error describing them doesn't fail the Fetch that completed the deployment.
+func (s *ECSDeploymentStreamer) recordStoppedTaskReasons(deploymentID string) {
+	if deploymentID == "" {
+		return
+	}
+	tasks, err := s.stoppedTasks.StoppedServiceTasks(s.Cluster(), s.service, ecsMaxStoppedTasks)
+	if err != nil {
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return aws.TimeValue(tasks[i].StoppedAt).After(aws.TimeValue(tasks[j].StoppedAt))
+	})
+	var reasons []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		reason := aws.StringValue(task.StoppedReason)
+		if aws.StringValue(task.StartedBy) != deploymentID || reason == "" || seen[reason] {
+			continue
+		}
+		seen[reason] = true
+		reasons = append(reasons, reason)
+	}
+	s.mu.Lock()
+	s.stoppedReasons = reasons
+	s.mu.Unlock()
+}
+
+// StoppedTaskReasons returns the distinct reasons why the tasks of the deployment stopped, most recent first,
+// once the streamer is done with the ECSDeploymentFailed outcome. It is only set if the streamer is created
+// WithStoppedTaskReasons, and empty if the stopped tasks couldn't be described.
+func (s *ECSDeploymentStreamer) StoppedTaskReasons() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reasons := make([]string, len(s.stoppedReasons))
+	copy(reasons, s.stoppedReasons)
+	return reasons
+}
+
+// FailureCategoryCounts returns the number of failure events reported by category since the streamer started watching,
+// for example to summarize the failures of the deployment once it's done.
+func (s *ECSDeploymentStreamer) FailureCategoryCounts() map[ECSFailureCategory]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyFailureCategoryCounts(s.failureCounts)
+}
+
+func copyFailureCategoryCounts(counts map[ECSFailureCategory]int) map[ECSFailureCategory]int {
+	c := make(map[ECSFailureCategory]int, len(counts))
+	for category, n := range counts {
+		c[category] = n
+	}
+	return c
+}
+
+// observeRunningCount widens the range of running counts with the running count of the primary deployment.
+func (s *ECSDeploymentStreamer) observeRunningCount() {
+	primary, ok := ECSService{Deployments: s.deployments}.Primary()
+	if !ok {
+		return
+	}
+	running := primary.RunningCount
+	if !s.observedRunning || running < s.minRunning {
+		s.minRunning = running
+	}
+	if !s.observedRunning || running > s.maxRunning {
+		s.maxRunning = running
+	}
+	s.observedRunning = true
+}
+
+// RunningCountRange returns the lowest and highest running counts of the primary deployment observed while watching.
+// Both are 0 until a primary deployment is fetched.
+func (s *ECSDeploymentStreamer) RunningCountRange() (min, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minRunning, s.maxRunning
+}
+
+// failureKeywords returns the keywords that make an unclassified service event a failure.
+func (s *ECSDeploymentStreamer) failureKeywords() []string {
+	if !s.broadMissing {
+		return ecsEventFailureKeywords
+	}
+	return append(ecsEventFailureKeywords[:len(ecsEventFailureKeywords):len(ecsEventFailureKeywords)], ecsBroadMissingKeyword)
+}
+
+// recordPhase starts a new phase if the state of the primary deployment changed since the last Fetch.
+func (s *ECSDeploymentStreamer) recordPhase() {
+	var state string
+	for _, d := range s.deployments {
+		if d.Status != ecsPrimaryDeploymentStatus {
+			continue
+		}
+		state = d.RolloutState
+		if s.watchTaskSets {
+			state = d.StabilityStatus
+		}
+	}
+	if state == "" {
+		return
+	}
+	if n := len(s.phases); n > 0 && s.phases[n-1].State == state {
+		return
+	}
+	s.endPhase(s.lastFetchedAt)
+	s.phases = append(s.phases, ECSRolloutPhase{
+		State:     state,
+		StartedAt: s.lastFetchedAt,
+	})
+}
+
+// endPhase ends the current phase at the given time, if there is one.
+func (s *ECSDeploymentStreamer) endPhase(at time.Time) {
+	if n := len(s.phases); n > 0 && s.phases[n-1].EndedAt.IsZero() {
+		s.phases[n-1].EndedAt = at
+	}
+}
+
+// PhaseLog returns the phases of the primary deployment's rollout in the order they started, if the streamer is
+// created WithPhaseLog. The last phase ends once the deployment is done, and has a zero EndedAt before.
+func (s *ECSDeploymentStreamer) PhaseLog() []ECSRolloutPhase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phases := make([]ECSRolloutPhase, len(s.phases))
+	copy(phases, s.phases)
+	return phases
+}
+
+// runCompletionCallback calls the callback matching the outcome of the completed deployment with its last snapshot.
+func (s *ECSDeploymentStreamer) runCompletionCallback(ev ECSService) {
+	switch ev.Completion.Outcome {
+	case ECSDeploymentSucceeded, ECSDeploymentNoChanges:
+		if s.onSteadyState != nil {
+			s.onSteadyState(ev.clone())
+		}
+	case ECSDeploymentFailed:
+		if s.onFailure != nil {
+			s.onFailure(ev.clone())
+		}
+	}
+}
+
+// recordSnapshot adds ev to the ring buffer of recent snapshots, overwriting the oldest one if it's full.
+func (s *ECSDeploymentStreamer) recordSnapshot(ev ECSService) {
+	if s.maxRecentSnapshots <= 0 {
+		return
+	}
+	if len(s.recentSnapshots) < s.maxRecentSnapshots {
+		s.recentSnapshots = append(s.recentSnapshots, ev)
+		return
+	}
+	s.recentSnapshots[s.recentStart] = ev
+	s.recentStart = (s.recentStart + 1) % len(s.recentSnapshots)
+}
+
+// recordFailureHistory retains the new failures of the current attempt, dropping the oldest failures beyond the limit.
+// The failures are sorted from newest to oldest, like the service events they were parsed from.
+func (s *ECSDeploymentStreamer) recordFailureHistory(failures []ECSServiceFailure) {
+	if s.maxFailureHistory <= 0 {
+		return
+	}
+	for i := len(failures) - 1; i >= 0; i-- {
+		s.failureHistory = append(s.failureHistory, ECSAttemptFailure{
+			Attempt: s.attempt,
+			Failure: failures[i],
+		})
+	}
+	if extra := len(s.failureHistory) - s.maxFailureHistory; extra > 0 {
+		s.failureHistory = append([]ECSAttemptFailure(nil), s.failureHistory[extra:]...)
+	}
+}
+
+// FailureHistory returns the failures reported while watching the current and previous deployments, oldest first.
+// It is only set if the streamer is created WithFailureHistory.
+func (s *ECSDeploymentStreamer) FailureHistory() []ECSAttemptFailure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := make([]ECSAttemptFailure, len(s.failureHistory))
+	copy(history, s.failureHistory)
+	return history
+}
+
+// Attempt returns the number of the deployment being watched, starting at 1 and incremented by each Reset.
+func (s *ECSDeploymentStreamer) Attempt() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempt
+}
+
+// RecentSnapshots returns copies of the most recently fetched snapshots of the service, oldest first.
+// By default the last 10 snapshots are retained, see WithRecentSnapshots.
+func (s *ECSDeploymentStreamer) RecentSnapshots() []ECSService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshots := make([]ECSService, 0, len(s.recentSnapshots))
+	for i := range s.recentSnapshots {
+		snapshots = append(snapshots, s.recentSnapshots[(s.recentStart+i)%len(s.recentSnapshots)].clone())
+	}
+	return snapshots
+}
+
+// latestSnapshot returns a copy of the snapshot stored by the last Fetch, and false if the service wasn't fetched yet.
+func (s *ECSDeploymentStreamer) latestSnapshot() (ECSService, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest.clone(), s.hasFetched
+}
+
+// ecsDescription holds the data retrieved by Fetch before updating the state of the streamer.
+type ecsDescription struct {
+	service        *ecs.Service
+	placement      []ECSTaskPlacement // Placement of the primary deployment's tasks.
+	primaryID      string             // ID of the primary deployment, only set if its tasks were described.
+	primaryTasks   []*ecs.Task        // Tasks of the primary deployment, only set if its tasks were described.
+	healthyTargets map[string]int     // Number of healthy targets by target group ARN of the service.
+}
+
+// describe returns the service description along with its events and the optional data the streamer is configured with.
+func (s *ECSDeploymentStreamer) describe(ctx context.Context) (*ecsDescription, error) {
+	out, err := s.describeService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch service description: %w", err)
+	}
+	desc := &ecsDescription{
+		service: out,
+	}
+	if primary := primaryDeployment(out.Deployments); (s.tasksClient != nil || s.transitionTasks != nil || s.targetHealth != nil) && primary != nil {
+		desc.primaryID = aws.StringValue(primary.Id)
+		desc.primaryTasks, err = s.deploymentTasks(desc.primaryID)
+		if err != nil {
+			return nil, err
+		}
+		if s.tasksClient != nil {
+			desc.placement = taskPlacement(desc.primaryTasks)
+		}
+	}
+	if s.targetHealth != nil {
+		desc.healthyTargets, err = s.healthyTargets(out.LoadBalancers, desc.primaryTasks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return desc, nil
+}
+
+// healthyTargets returns the number of healthy targets of the primary deployment's tasks in each target group
+// of the service's load balancers. The healthy targets of the tasks of other deployments are not counted.
+func (s *ECSDeploymentStreamer) healthyTargets(lbs []*awsecs.LoadBalancer, primaryTasks []*ecs.Task) (map[string]int, error) {
+	ips, ports := make(map[string]bool), make(map[int]bool)
+	for _, task := range primaryTasks {
+		for _, container := range task.Containers {
+			for _, eni := range container.NetworkInterfaces {
+				ips[aws.StringValue(eni.PrivateIpv4Address)] = true
+			}
+			for _, binding := range container.NetworkBindings {
+				ports[int(aws.Int64Value(binding.HostPort))] = true
+			}
+		}
+	}
+	delete(ips, "")
+	var healthy map[string]int
+	for _, lb := range lbs {
+		arn := aws.StringValue(lb.TargetGroupArn)
+		if arn == "" {
+			continue // Classic load balancers are not supported.
+		}
+		targets, err := s.targetHealth.HealthyTargets(arn)
+		if err != nil {
+			return nil, fmt.Errorf("count healthy targets in target group %s: %w", arn, err)
+		}
+		if healthy == nil {
+			healthy = make(map[string]int)
+		}
+		healthy[arn] = 0
+		for _, target := range targets {
+			// Tasks without a network interface of their own are registered with the host port of their containers.
+			if ips[target.ID] || (len(ips) == 0 && ports[target.Port]) {
+				healthy[arn]++
+			}
+		}
+	}
+	return healthy, nil
+}
+
+// updateCluster resolves the new cluster of the service and returns a notice about the change.
+func (s *ECSDeploymentStreamer) updateCluster() (ECSNotice, error) {
+	cluster, err := s.resolveCluster(s.service)
+	if err != nil {
+		return ECSNotice{}, fmt.Errorf("resolve cluster of service %s: %w", s.service, err)
+	}
+	s.mu.Lock()
+	prev := s.cluster
+	s.cluster = cluster
+	s.mu.Unlock()
+	return ECSNotice{
+		Kind:     ECSNoticeClusterChanged,
+		Severity: ECSNoticeInfo,
+		Message:  fmt.Sprintf("service %s is no longer in cluster %s, watching it in cluster %s", s.service, prev, cluster),
+	}, nil
+}
+
+// describeService returns the description of the service, or errFetchTimeout if it takes longer than the fetch timeout,
+// or the error of ctx if it is canceled first.
+func (s *ECSDeploymentStreamer) describeService(ctx context.Context) (*ecs.Service, error) {
+	if s.fetchTimeout <= 0 && ctx.Done() == nil {
+		return s.client.Service(s.cluster, s.service)
+	}
+	type result struct {
+		out *ecs.Service
+		err error
+	}
+	c := make(chan result, 1) // Buffered so that a late description doesn't block the goroutine forever.
+	cluster, service := s.cluster, s.service
+	go func() {
+		out, err := s.client.Service(cluster, service)
+		c <- result{out, err}
+	}()
+	var timeout <-chan time.Time // Never fires without a fetch timeout.
+	if s.fetchTimeout > 0 {
+		timeout = time.After(s.fetchTimeout)
+	}
+	select {
+	case r := <-c:
+		return r.out, r.err
+	case <-timeout:
+		return nil, fmt.Errorf("%w after %s", errFetchTimeout, s.fetchTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// retry schedules the next Fetch if err is transient and there were not too many consecutive transient errors,
+// if err is an AccessDenied error within the grace period, or if err reports expired credentials and there were not
+// too many consecutive ones. Otherwise, err is returned to terminate the stream.
+func (s *ECSDeploymentStreamer) retry(err error) (time.Time, error) {
+	var accessDenied, expired, retryable bool
+	if s.isRetryable != nil {
+		retryable = s.isRetryable(err)
+	} else {
+		accessDenied = isAccessDeniedError(err)
+		expired = isExpiredCredentialsError(err)
+		retryable = accessDenied || expired || isTransientError(err)
+	}
+	if !retryable {
+		s.logFetchError(err, false)
+		return time.Time{}, err
+	}
+	s.mu.Lock()
+	var exhausted bool
+	switch {
+	case accessDenied:
+		// The grace period bounds the retries of AccessDenied errors instead of the number of consecutive errors.
+		exhausted = !s.now().Before(s.firstFetchAt.Add(s.accessDeniedGrace))
+	case expired:
+		s.expiredRetries++
+		exhausted = s.expiredRetries > defaultMaxECSExpiredCredsRetry
+	default:
+		s.retries++
+		exhausted = s.retries > defaultMaxECSFetchRetries
+	}
+	s.mu.Unlock()
+	if exhausted {
+		s.logFetchError(err, false)
+		return time.Time{}, err
+	}
+	if expired && s.refreshCredentials != nil {
+		if rerr := s.refreshCredentials(); rerr != nil {
+			s.logFetchError(err, false)
+			return time.Time{}, fmt.Errorf("refresh expired credentials: %w", rerr)
+		}
+	}
+	s.logFetchError(err, true)
+	s.onFetchError(err)
+	return s.now().Add(streamerFetchIntervalDuration), nil
+}
+
+// validateWatchable returns an error matching ErrServiceNotWatchable if the streamer can't watch the deployment of the service.
+func (s *ECSDeploymentStreamer) validateWatchable(out *ecs.Service) error {
+	controller := awsecs.DeploymentControllerTypeEcs
+	if out.DeploymentController != nil && aws.StringValue(out.DeploymentController.Type) != "" {
+		controller = aws.StringValue(out.DeploymentController.Type)
+	}
+	if !s.watchTaskSets {
+		if controller != awsecs.DeploymentControllerTypeEcs {
+			return fmt.Errorf("%w: %s uses the %s deployment controller, watch its task sets WithTaskSets",
+				ErrServiceNotWatchable, s.service, controller)
+		}
+		if primaryDeployment(out.Deployments) == nil {
+			return fmt.Errorf("%w: %s has no %s deployment", ErrServiceNotWatchable, s.service, ecsPrimaryDeploymentStatus)
+		}
+		return nil
+	}
+	if controller == awsecs.DeploymentControllerTypeEcs {
+		return fmt.Errorf("%w: %s uses the %s deployment controller, which has no task sets", ErrServiceNotWatchable, s.service, controller)
+	}
+	for _, taskSet := range out.TaskSets {
+		if aws.StringValue(taskSet.Status) == ecsPrimaryDeploymentStatus {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has no %s task set", ErrServiceNotWatchable, s.service, ecsPrimaryDeploymentStatus)
+}
+
+// updateDeployments converts the service's deployments and marks the streamer as done
+// if the watched deployment succeeded or failed.
+func (s *ECSDeploymentStreamer) updateDeployments(in []*awsecs.Deployment, healthyTargets map[string]int) ([]ECSDeployment, []ECSNotice) {
+	var deployments []ECSDeployment
+	var notices []ECSNotice
+	var primary, failed, previous *awsecs.Deployment
+	var hasExpected bool
+	for _, deployment := range in {
+		status := aws.StringValue(deployment.Status)
+		revision := parseRevisionFromTaskDefARN(aws.StringValue(deployment.TaskDefinition))
+		hasExpected = hasExpected || revision == s.expectedRevision
+		if status == ecsPrimaryDeploymentStatus {
+			primary = deployment
+			notices = append(notices, s.updatePrimaryRevision(revision)...)
+		} else if previous == nil || aws.TimeValue(deployment.CreatedAt).After(aws.TimeValue(previous.CreatedAt)) {
+			previous = deployment
+		}
+		if s.isFailedDeployment(deployment) {
+			failed = deployment
+		}
+		deployments = append(deployments, ECSDeployment{
+			Status:          status,
+			TaskDefRevision: revision,
+			DesiredCount:    int(aws.Int64Value(deployment.DesiredCount)),
+			RunningCount:    int(aws.Int64Value(deployment.RunningCount)),
+			FailedCount:     int(aws.Int64Value(deployment.FailedTasks)),
+			PendingCount:    int(aws.Int64Value(deployment.PendingCount)),
+			RolloutState:    aws.StringValue(deployment.RolloutState),
+
+			RolloutStateReason: s.rolloutStateReason(deployment),
+
+			ID: aws.StringValue(deployment.Id),
+		})
+		if s.launchDetails {
+			deployments[len(deployments)-1].LaunchType = aws.StringValue(deployment.LaunchType)
+			deployments[len(deployments)-1].PlatformVersion = aws.StringValue(deployment.PlatformVersion)
+		}
+	}
+	if previous != nil {
+		s.rollbackTaskDef = aws.StringValue(previous.TaskDefinition)
+	}
+	if primary != nil {
+		notices = append(notices, s.updatePrimaryDesiredCount(primary)...)
+	}
+	prior := s.priorDeployment(in, primary)
+	notices = append(notices, s.updateWaitingFor(prior)...)
+	var superseded string
+	if s.deploymentID != "" && prior == nil {
+		// Key the completion to the watched deployment instead of the primary one.
+		primary, failed, superseded = s.watchedDeployment(in, primary)
+	}
+	var noProgress, watchedID string
+	if primary != nil {
+		watchedID = aws.StringValue(primary.Id)
+		noProgress = s.trackProgress(primary)
+		notices = append(notices, s.stuckPendingNotice(primary)...)
+	}
+	primaryCompleted := primary != nil && (rolloutState(primary) == ECSRolloutStateCompleted || s.isRunningTargetReached(primary))
+	unexpected, timedOut := s.unexpectedRevision(hasExpected, primaryCompleted), s.timedOut()
+	switch {
+	case prior != nil:
+		// The counts are the ones of the earlier deployment until the watched one starts.
+		s.steadySince = time.Time{}
+	case unexpected != "":
+		s.markFailedBy(s.deploymentID, unexpected)
+	case failed != nil:
+		// After a circuit breaker rollback, the failed deployment is no longer the primary one.
+		reason, category := s.failureReasonOf(failed)
+		if s.outcome == "" {
+			s.rolloutCause = parseRolloutFailureCause(aws.StringValue(failed.RolloutStateReason))
+			s.failureCategory = category
+		}
+		s.markFailedBy(aws.StringValue(failed.Id), reason)
+	case superseded != "":
+		s.markDone(ECSDeploymentSuperseded, superseded)
+	case primary != nil && s.isUnchanged(in, primary) && s.isRunningTargetReached(primary):
+		s.markDone(ECSDeploymentNoChanges, "")
+	case primary != nil && s.isRunningTargetReached(primary) && s.areTargetsHealthy(primary, healthyTargets):
+		s.markSteady()
+	case noProgress != "":
+		s.markFailedBy(watchedID, noProgress)
+	case timedOut != "":
+		s.markFailedBy(watchedID, timedOut)
+	default:
+		s.steadySince = time.Time{}
+	}
+	return deployments, notices
+}
+
+// priorDeployment returns the primary deployment if it's an earlier deployment still in progress, which the watched
+// deployment waits for before starting. The watched deployment is either the one WithDeploymentID, found among the
+// deployments but created after the primary one, or a deployment created since the deployment creation time.
+// Once the deployment is aborted, the primary deployment is the rollback deployment, which is watched instead.
+func (s *ECSDeploymentStreamer) priorDeployment(in []*awsecs.Deployment, primary *awsecs.Deployment) *awsecs.Deployment {
+	if primary == nil || s.aborted || rolloutState(primary) != ECSRolloutStateInProgress {
+		return nil
+	}
+	primaryCreatedAt := aws.TimeValue(primary.CreatedAt)
+	if primaryCreatedAt.IsZero() {
+		return nil
+	}
+	if s.deploymentID == "" {
+		if primaryCreatedAt.Before(s.deploymentCreationTime) {
+			return primary
+		}
+		return nil
+	}
+	for _, deployment := range in {
+		if aws.StringValue(deployment.Id) == s.deploymentID && deployment != primary &&
+			aws.TimeValue(deployment.CreatedAt).After(primaryCreatedAt) {
+			return primary
+		}
+	}
+	return nil
+}
+
+// updateWaitingFor records the earlier deployment that the watched deployment waits for, and returns a notice
+// when the watched deployment starts waiting for it.
+func (s *ECSDeploymentStreamer) updateWaitingFor(prior *awsecs.Deployment) []ECSNotice {
+	prev := s.waitingFor
+	s.waitingFor = ""
+	if prior == nil {
+		return nil
+	}
+	s.waitingFor = aws.StringValue(prior.Id)
+	if s.waitingFor == prev {
+		return nil
+	}
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeWaitingForPriorDeployment,
+			Severity: ECSNoticeInfo,
+			Message: fmt.Sprintf("waiting for deployment %s on revision %s to finish before the deployment starts",
+				s.waitingFor, parseRevisionFromTaskDefARN(aws.StringValue(prior.TaskDefinition))),
+		},
+	}
+}
+
+// watchedDeployment returns the deployment watched WithDeploymentID if it's primary, and the deployment again as failed
+// if its rollout failed. If the deployment is superseded by another one, returns the reason instead.
+// Once the deployment is aborted, the primary deployment is the one created by the rollback, which is watched instead.
+func (s *ECSDeploymentStreamer) watchedDeployment(in []*awsecs.Deployment, primary *awsecs.Deployment) (watched, failed *awsecs.Deployment, superseded string) {
+	if s.aborted {
+		if primary != nil && rolloutState(primary).IsFailed() {
+			return primary, primary, ""
+		}
+		return primary, nil, ""
+	}
+	for _, deployment := range in {
+		if aws.StringValue(deployment.Id) == s.deploymentID {
+			watched = deployment
+		}
+	}
+	switch {
+	case watched == nil:
+		return nil, nil, fmt.Sprintf("deployment %s is no longer a deployment of the service", s.deploymentID)
+	case rolloutState(watched).IsFailed():
+		return watched, watched, ""
+	case aws.StringValue(watched.Status) != ecsPrimaryDeploymentStatus:
+		if primary == nil {
+			return nil, nil, fmt.Sprintf("deployment %s was superseded", s.deploymentID)
+		}
+		return nil, nil, fmt.Sprintf("deployment %s was superseded by deployment %s", s.deploymentID, aws.StringValue(primary.Id))
+	}
+	return watched, nil, ""
+}
+
+// updateTaskSets converts the service's task sets and marks the streamer as done
+// once the primary task set is stable.
+func (s *ECSDeploymentStreamer) updateTaskSets(in []*awsecs.TaskSet) ([]ECSDeployment, []ECSNotice) {
+	var deployments []ECSDeployment
+	var notices []ECSNotice
+	var primary *awsecs.TaskSet
+	var hasExpected bool
+	for _, taskSet := range in {
+		status := aws.StringValue(taskSet.Status)
+		revision := parseRevisionFromTaskDefARN(aws.StringValue(taskSet.TaskDefinition))
+		hasExpected = hasExpected || revision == s.expectedRevision
+		if status == ecsPrimaryDeploymentStatus {
+			primary = taskSet
+			notices = append(notices, s.updatePrimaryRevision(revision)...)
+		}
+		deployments = append(deployments, ECSDeployment{
+			Status:          status,
+			TaskDefRevision: revision,
+			DesiredCount:    int(aws.Int64Value(taskSet.ComputedDesiredCount)),
+			RunningCount:    int(aws.Int64Value(taskSet.RunningCount)),
+			PendingCount:    int(aws.Int64Value(taskSet.PendingCount)),
+			StabilityStatus: aws.StringValue(taskSet.StabilityStatus),
+			ID:              aws.StringValue(taskSet.Id),
+		})
+		if s.launchDetails {
+			deployments[len(deployments)-1].LaunchType = aws.StringValue(taskSet.LaunchType)
+			deployments[len(deployments)-1].PlatformVersion = aws.StringValue(taskSet.PlatformVersion)
+		}
+	}
+	var primaryID string
+	if primary != nil {
+		primaryID = aws.StringValue(primary.Id)
+	}
+	steady := primary != nil && aws.StringValue(primary.StabilityStatus) == awsecs.StabilityStatusSteadyState
+	unexpected, timedOut := s.unexpectedRevision(hasExpected, steady), s.timedOut()
+	switch {
+	case unexpected != "":
+		s.markFailedBy(s.deploymentID, unexpected)
+	case steady:
+		s.markSteady()
+	case timedOut != "":
+		s.markFailedBy(primaryID, timedOut)
+	default:
+		s.steadySince = time.Time{}
+	}
+	return deployments, notices
+}
+
+// serialRolloutNotice returns a notice explaining that the rollout is slow because of the deployment configuration,
+// the first time new tasks wait for old ones to stop due to a maximum percent of at most 100%.
+func (s *ECSDeploymentStreamer) serialRolloutNotice(out *ecs.Service) []ECSNotice {
+	if s.noticedSerial || out.DeploymentConfiguration == nil || out.DeploymentConfiguration.MaximumPercent == nil {
+		return nil
+	}
+	maxPercent := aws.Int64Value(out.DeploymentConfiguration.MaximumPercent)
+	if maxPercent > 100 {
+		return nil
+	}
+	primary := primaryDeployment(out.Deployments)
+	if primary == nil || aws.Int64Value(primary.RunningCount) >= aws.Int64Value(primary.DesiredCount) {
+		return nil
+	}
+	var draining bool
+	for _, d := range out.Deployments {
+		if d != primary && aws.Int64Value(d.RunningCount) > 0 {
+			draining = true
+		}
+	}
+	if !draining {
+		return nil
+	}
+	s.noticedSerial = true
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeSerialRollout,
+			Severity: ECSNoticeInfo,
+			Message: fmt.Sprintf("the deployment configuration allows at most %d%% of the desired tasks to run, "+
+				"so old tasks must stop before new ones start and the rollout happens in steps", maxPercent),
+		},
+	}
+}
+
+// eventsUnavailableNotice returns a warning, once, if the primary deployment reports failed tasks while the service
+// had no events for several consecutive fetches, which suggests that the failure events can't be read.
+func (s *ECSDeploymentStreamer) eventsUnavailableNotice(desc *ecsDescription) []ECSNotice {
+	if s.noticedNoEvents {
+		return nil
+	}
+	primary := primaryDeployment(desc.service.Deployments)
+	if primary == nil || aws.Int64Value(primary.FailedTasks) == 0 || len(desc.service.Events) > 0 {
+		s.noEventFetches = 0
+		return nil
+	}
+	s.noEventFetches++
+	if s.noEventFetches < ecsEventsUnavailableFetches {
+		return nil
+	}
+	s.noticedNoEvents = true
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeEventsUnavailable,
+			Severity: ECSNoticeWarning,
+			Message: fmt.Sprintf("%d tasks failed but the service has no events, failure details may be unavailable "+
+				"due to restricted permissions", aws.Int64Value(primary.FailedTasks)),
+		},
+	}
+}
+
+// updatePrimaryRevision records the task definition revision of the primary deployment,
+// and returns a warning if it changed since the last Fetch.
+func (s *ECSDeploymentStreamer) updatePrimaryRevision(revision string) []ECSNotice {
+	s.observeRevision(revision)
+	prev := s.primaryRevision
+	s.primaryRevision = revision
+	if prev == "" || prev == revision {
+		return nil
+	}
+	return []ECSNotice{revisionChangedNotice(prev, revision)}
+}
+
+// updatePrimaryDesiredCount records the desired count of the primary deployment, and returns an informational notice
+// if it changed since the last Fetch while the primary deployment stayed the same.
+func (s *ECSDeploymentStreamer) updatePrimaryDesiredCount(primary *awsecs.Deployment) []ECSNotice {
+	id, desired := aws.StringValue(primary.Id), aws.Int64Value(primary.DesiredCount)
+	prevID, prevDesired := s.primaryID, s.primaryDesired
+	s.primaryID, s.primaryDesired = id, desired
+	if prevID == "" || prevID != id || prevDesired == desired {
+		return nil
+	}
+	change := "raised"
+	if desired < prevDesired {
+		change = "lowered"
+	}
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeDesiredCountChanged,
+			Severity: ECSNoticeInfo,
+			Message:  fmt.Sprintf("desired count %s from %d to %d outside of the deployment, likely by service auto scaling", change, prevDesired, desired),
+		},
+	}
+}
+
+// unexpectedRevision returns a failure reason if the streamer is created WithExpectedRevision and the primary
+// deployment is on another revision, once the expected revision can no longer become primary: no deployment of the
+// service is on it anymore, or the primary deployment completed. The rollback of an aborted deployment is expected
+// to move the primary deployment off the expected revision.
+func (s *ECSDeploymentStreamer) unexpectedRevision(hasExpected, primaryCompleted bool) string {
+	if s.aborted || s.expectedRevision == "" || s.primaryRevision == "" || s.primaryRevision == s.expectedRevision {
+		return ""
+	}
+	if hasExpected && !primaryCompleted {
+		return ""
+	}
+	return fmt.Sprintf("primary deployment is on revision %s instead of the expected revision %s, another deployment may have overwritten it",
+		s.primaryRevision, s.expectedRevision)
+}
+
+// observeRevision records that the revision was seen on the primary deployment.
+func (s *ECSDeploymentStreamer) observeRevision(revision string) {
+	now := s.now()
+	for i := range s.observedRevisions {
+		if s.observedRevisions[i].Revision == revision {
+			s.observedRevisions[i].LastSeen = now
+			return
+		}
+	}
+	s.observedRevisions = append(s.observedRevisions, ECSObservedRevision{
+		Revision:  revision,
+		FirstSeen: now,
+		LastSeen:  now,
+	})
+}
+
+// ObservedRevisions returns the distinct task definition revisions seen on the primary deployment,
+// in the order they were first seen. More than one revision means that the deployment was overwritten.
+func (s *ECSDeploymentStreamer) ObservedRevisions() []ECSObservedRevision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revisions := make([]ECSObservedRevision, len(s.observedRevisions))
+	copy(revisions, s.observedRevisions)
+	return revisions
+}
+
+// markEventSeen records the ID of a service event so that it's not reported again.
+func (s *ECSDeploymentStreamer) markEventSeen(id string, createdAt time.Time) {
+	s.pastEventIDs[id] = true
+	if !createdAt.Equal(s.lastEventAt) {
+		return
+	}
+	if s.lastEventIDs == nil {
+		s.lastEventIDs = make(map[string]bool)
+	}
+	s.lastEventIDs[id] = true
+}
+
+// newFailures returns the failure events created at or after since that were not seen before,
+// and notices for the new Spot interruption, health check grace period and inconsistent start events.
+func (s *ECSDeploymentStreamer) newFailures(events []*awsecs.ServiceEvent, since time.Time) ([]string, []ECSServiceFailure, []ECSNotice) {
+	if len(events) > 0 {
+		if createdAt := aws.TimeValue(events[0].CreatedAt); createdAt.After(s.lastEventAt) {
+			s.lastEventAt, s.lastEventIDs = createdAt, nil
+		}
+	}
+	var staleBefore time.Time
+	if !s.hasFetched && s.maxInitialEventAge > 0 {
+		staleBefore = s.now().Add(-s.maxInitialEventAge)
+	}
+	var failureMsgs []string
+	var failures []ECSServiceFailure
+	var failedAt []time.Time
+	var summaries []bool
+	var eventNotices []ECSNotice
+	for _, event := range events {
+		createdAt := aws.TimeValue(event.CreatedAt)
+		if createdAt.Before(since) {
+			break
+		}
+		id := aws.StringValue(event.Id)
+		if _, ok := s.pastEventIDs[id]; ok {
+			break
+		}
+		s.markEventSeen(id, createdAt)
+		if createdAt.Before(staleBefore) {
+			continue // Don't report the stale event on subsequent fetches either.
+		}
+		msg := aws.StringValue(event.Message)
+		if isSpotInterruptionServiceEvent(msg) {
+			eventNotices = append(eventNotices, ECSNotice{
+				Kind:     ECSNoticeSpotInterruption,
+				Severity: ECSNoticeInfo,
+				Message:  msg,
+			})
+			continue
+		}
+		if isHealthCheckGraceServiceEvent(msg) {
+			eventNotices = append(eventNotices, ECSNotice{
+				Kind:     ECSNoticeHealthCheckGracePeriod,
+				Severity: ECSNoticeInfo,
+				Message:  msg,
+			})
+			continue
+		}
+		if isInconsistentStartServiceEvent(msg) {
+			eventNotices = append(eventNotices, ECSNotice{
+				Kind:     ECSNoticeLikelyToFail,
+				Severity: ECSNoticeWarning,
+				Message:  msg,
+			})
+			continue
+		}
+		if failure, ok := parseFailureServiceEvent(msg, s.failureKeywords()); ok {
+			if s.formatFailure != nil {
+				failure.Message = s.formatFailure(failure.Message, failure.Category)
+			}
+			failures = append(failures, failure)
+			failedAt = append(failedAt, createdAt)
+			summaries = append(summaries, isSummaryFailureServiceEvent(msg))
+			if s.failureCounts == nil {
+				s.failureCounts = make(map[ECSFailureCategory]int)
+			}
+			s.failureCounts[failure.Category]++
+		}
+	}
+	s.attributeCauses(failures, failedAt, summaries)
+	for _, failure := range failures {
+		failureMsgs = append(failureMsgs, failure.Message)
+	}
+	s.recordFailureMessages(failureMsgs)
+	for _, failure := range failures { // Events are sorted from newest to oldest.
+		if failure.Category != ECSFailureCategoryUnknown {
+			s.latestClassified, s.latestCategory = failure.Message, failure.Category
+			break
+		}
+	}
+	return failureMsgs, failures, eventNotices
+}
+
+// attributeCauses sets the cause of each summary failure, such as "deployment failed: tasks failed to start", to the most
+// recent failure with a known category reported at most ecsFailureCauseWindow before it, including in previous fetches.
+// The failures are sorted from newest to oldest, and failedAt and summaries are their creation times and whether they are
+// summary failures.
+func (s *ECSDeploymentStreamer) attributeCauses(failures []ECSServiceFailure, failedAt []time.Time, summaries []bool) {
+	for i := len(failures) - 1; i >= 0; i-- {
+		if summaries[i] {
+			if s.latestCause.Message != "" && !failedAt[i].After(s.latestCauseAt.Add(ecsFailureCauseWindow)) {
+				failures[i].Cause = failureCause(s.latestCause)
+				failures[i].CauseMessage = s.latestCause.Message
+			}
+			continue
+		}
+		if failures[i].Category != ECSFailureCategoryUnknown {
+			s.latestCause, s.latestCauseAt = failures[i], failedAt[i]
+		}
+	}
+}
+
+// eventsBoundary returns the creation time of the oldest service events to report, which is the lookback boundary
+// unless the streamer was restored with UnmarshalState from a state that had seen more recent events.
+func (s *ECSDeploymentStreamer) eventsBoundary() time.Time {
+	boundary := s.lookbackBoundary()
+	if s.resumedAt.After(boundary) {
+		return s.resumedAt
+	}
+	return boundary
+}
+
+// lookbackBoundary returns the deployment creation time unless it's further in the past than the maximum event lookback
+// before the first Fetch.
+func (s *ECSDeploymentStreamer) lookbackBoundary() time.Time {
+	if s.maxEventLookback <= 0 || s.firstFetchAt.IsZero() {
+		return s.deploymentCreationTime
+	}
+	if floor := s.firstFetchAt.Add(-s.maxEventLookback); s.deploymentCreationTime.Before(floor) {
+		return floor
+	}
+	return s.deploymentCreationTime
+}
+
+// lookbackClampedNotice returns a warning if the events boundary is clamped by the maximum event lookback.
+func (s *ECSDeploymentStreamer) lookbackClampedNotice() []ECSNotice {
+	boundary := s.lookbackBoundary()
+	if !boundary.After(s.deploymentCreationTime) {
+		return nil
+	}
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeEventLookbackClamped,
+			Severity: ECSNoticeWarning,
+			Message: fmt.Sprintf("the deployment creation time %s is more than %s ago, ignoring the service events created before %s",
+				s.deploymentCreationTime.Format(time.RFC3339), s.maxEventLookback, boundary.Format(time.RFC3339)),
+		},
+	}
+}
+
+// failuresSince returns the creation time of the oldest failure events to report. It is the deployment creation time,
+// or the creation time of the primary deployment if it's more recent and the streamer is created WithFailuresSincePrimary.
+func (s *ECSDeploymentStreamer) failuresSince(out *ecs.Service) time.Time {
+	since := s.eventsBoundary()
+	if !s.anchorToPrimary {
+		return since
+	}
+	var primaryCreatedAt time.Time
+	if s.watchTaskSets {
+		for _, taskSet := range out.TaskSets {
+			if aws.StringValue(taskSet.Status) == ecsPrimaryDeploymentStatus {
+				primaryCreatedAt = aws.TimeValue(taskSet.CreatedAt)
+			}
+		}
+	} else if primary := primaryDeployment(out.Deployments); primary != nil {
+		primaryCreatedAt = aws.TimeValue(primary.CreatedAt)
+	}
+	if primaryCreatedAt.After(since) {
+		since = primaryCreatedAt
+	}
+	if since.After(s.failuresAnchor) {
+		// The failures of the previous primary deployment no longer explain a failure of the current one.
+		s.latestClassified, s.latestCategory = "", ""
+		s.latestCause, s.latestCauseAt = ECSServiceFailure{}, time.Time{}
+		s.failuresAnchor = since
+	}
+	return since
+}
 
-// ECSServiceDescriber is the interface to describe an ECS service.
-type ECSServiceDescriber interface {
-	Service(clusterName, serviceName string) (*ecs.Service, error)
+// Notify flushes all new events to the streamer's subscribers and writers.
+func (s *ECSDeploymentStreamer) Notify() {
+	// Release the lock before sending so that accessors don't block on slow subscribers.
+	s.mu.Lock()
+	events := s.eventsToFlush
+	s.eventsToFlush = nil // reset after flushing all events.
+	if s.emitEvery > 0 {
+		events = s.holdForSchedule(events)
+	} else if s.minEmitInterval > 0 {
+		events = s.throttle(events)
+	}
+	if s.quietUntilFailure {
+		events = failuresOrCompletion(events)
+	}
+	if s.startToFlush != nil {
+		events = append([]ECSService{*s.startToFlush}, events...)
+		s.startToFlush = nil
+	}
+	subscribers, filters, overflows := s.sequenceEvents(events)
+	deltas, deltaSubscribers := s.deltas(events)
+	s.mu.Unlock()
+
+	s.send(events, subscribers, filters, overflows)
+	sendDeltas(deltas, deltaSubscribers)
 }
 
-// ECSDeployment represent an ECS rolling update deployment.
-type ECSDeployment struct {
-	Status          string
-	TaskDefRevision string
-	DesiredCount    int
-	RunningCount    int
-	FailedCount     int
-	PendingCount    int
-	RolloutState    string
+// emitInterval returns the interval at which Stream calls emitScheduled, zero unless created WithEmitSchedule.
+func (s *ECSDeploymentStreamer) emitInterval() time.Duration {
+	return s.emitEvery
 }
 
-// ECSService is a description of an ECS service.
-type ECSService struct {
-	Deployments         []ECSDeployment
-	LatestFailureEvents []string
+// emitScheduled emits the snapshots held since the previous tick coalesced into one, or the latest snapshot
+// without its failures and notices if there were none. Nothing is emitted before the first Fetch
+// or once the completion was emitted.
+func (s *ECSDeploymentStreamer) emitScheduled() {
+	s.mu.Lock()
+	var events []ECSService
+	switch {
+	case s.pendingEmit != nil:
+		events = []ECSService{*s.pendingEmit}
+		s.pendingEmit = nil
+	case s.hasFetched && s.outcome == "":
+		latest := s.latest.clone()
+		latest.LatestFailureEvents, latest.LatestFailures, latest.Notices = nil, nil, nil
+		events = []ECSService{latest}
+	}
+	if s.quietUntilFailure {
+		events = failuresOrCompletion(events)
+	}
+	subscribers, filters, overflows := s.sequenceEvents(events)
+	deltas, deltaSubscribers := s.deltas(events)
+	s.mu.Unlock()
+
+	s.send(events, subscribers, filters, overflows)
+	sendDeltas(deltas, deltaSubscribers)
 }
 
-// ECSDeploymentStreamer is a Streamer for ECSService descriptions until the deployment is completed.
-type ECSDeploymentStreamer struct {
-	client                 ECSServiceDescriber
-	cluster                string
-	service                string
-	deploymentCreationTime time.Time
+// holdForSchedule coalesces events with the ones that were not emitted yet, and only returns the coalesced snapshot
+// once the deployment is done. Otherwise, it's emitted by the next emitScheduled.
+func (s *ECSDeploymentStreamer) holdForSchedule(events []ECSService) []ECSService {
+	s.coalescePending(events)
+	if s.pendingEmit == nil || s.outcome == "" {
+		return nil
+	}
+	ev := *s.pendingEmit
+	s.pendingEmit = nil
+	return []ECSService{ev}
+}
 
-	subscribers   []chan ECSService
-	done          chan struct{}
-	pastEventIDs  map[string]bool
-	eventsToFlush []ECSService
+// sequenceEvents assigns the next sequence numbers and the correlation ID to events, and returns the subscribers to send them to
+// along with their filters and overflow policies. It must be called with the lock held.
+func (s *ECSDeploymentStreamer) sequenceEvents(events []ECSService) ([]chan ECSService, []func(ECSService) bool, []ECSOverflowPolicy) {
+	for i := range events {
+		s.sequence++
+		events[i].Sequence = s.sequence
+		events[i].CorrelationID = s.correlationID
+	}
+	subscribers := s.subscribers
+	filters := make([]func(ECSService) bool, len(subscribers))
+	overflows := make([]ECSOverflowPolicy, len(subscribers))
+	for i, sub := range subscribers {
+		filters[i] = s.filters[sub]
+		overflows[i] = s.overflows[sub]
+	}
+	return subscribers, filters, overflows
 }
 
-// NewECSDeploymentStreamer creates a new ECSDeploymentStreamer that streams service descriptions
-// since the deployment creation time and until the primary deployment is completed.
-func NewECSDeploymentStreamer(ecs ECSServiceDescriber, cluster, service string, deploymentCreationTime time.Time) *ECSDeploymentStreamer {
-	return &ECSDeploymentStreamer{
-		client:                 ecs,
-		cluster:                cluster,
-		service:                service,
-		deploymentCreationTime: deploymentCreationTime,
-		done:                   make(chan struct{}),
-		pastEventIDs:           make(map[string]bool),
+// send sends events to the subscribers whose filter accepts them, and writes them to the writers.
+// It must be called without the lock held, since subscribers can be slow.
+func (s *ECSDeploymentStreamer) send(events []ECSService, subscribers []chan ECSService, filters []func(ECSService) bool, overflows []ECSOverflowPolicy) {
+	for _, event := range events {
+		for i, sub := range subscribers {
+			if filters[i] != nil && !filters[i](event) {
+				continue
+			}
+			sendWithOverflow(sub, event.clone(), overflows[i])
+		}
+		for _, w := range s.writers {
+			w.write(event)
+		}
 	}
 }
 
-// Subscribe returns a read-only channel that will receive service descriptions from the ECSDeploymentStreamer.
-func (s *ECSDeploymentStreamer) Subscribe() <-chan ECSService {
-	c := make(chan ECSService)
-	s.subscribers = append(s.subscribers, c)
-	return c
+// sendWithOverflow sends ev to the subscriber, applying the overflow policy if its buffer is full.
+// Subscribers without a policy block like ECSOverflowBlock.
+func sendWithOverflow(sub chan ECSService, ev ECSService, policy ECSOverflowPolicy) {
+	switch policy {
+	case ECSOverflowDropNewest:
+		select {
+		case sub <- ev:
+		default:
+		}
+	case ECSOverflowDropOldest:
+		for {
+			select {
+			case sub <- ev:
+				return
+			default:
+			}
+			select {
+			case <-sub: // Discard the oldest description to make room for ev.
+			default:
+			}
+		}
+	default:
+		sub <- ev
+	}
 }
 
-// Fetch retrieves and stores ECSService descriptions since the deployment's creation time
-// until the primary deployment's running count is equal to its desired count.
-// If an error occurs from describe service, returns a wrapped err.
-// Otherwise, returns the time the next Fetch should be attempted.
-func (s *ECSDeploymentStreamer) Fetch() (next time.Time, err error) {
-	out, err := s.client.Service(s.cluster, s.service)
-	if err != nil {
-		return next, fmt.Errorf("fetch service description: %w", err)
+// coalescePending coalesces events with the ones that were not emitted yet.
+func (s *ECSDeploymentStreamer) coalescePending(events []ECSService) {
+	for _, ev := range events {
+		if s.pendingEmit == nil {
+			pending := ev.clone()
+			s.pendingEmit = &pending
+			continue
+		}
+		coalesced := s.pendingEmit.coalesce(ev)
+		s.pendingEmit = &coalesced
 	}
-	var deployments []ECSDeployment
-	for _, deployment := range out.Deployments {
-		status := aws.StringValue(deployment.Status)
-		desiredCount, runningCount := aws.Int64Value(deployment.DesiredCount), aws.Int64Value(deployment.RunningCount)
-		deployments = append(deployments, ECSDeployment{
-			Status:          status,
-			TaskDefRevision: parseRevisionFromTaskDefARN(aws.StringValue(deployment.TaskDefinition)),
-			DesiredCount:    int(desiredCount),
-			RunningCount:    int(runningCount),
-			FailedCount:     int(aws.Int64Value(deployment.FailedTasks)),
-			PendingCount:    int(aws.Int64Value(deployment.PendingCount)),
-			RolloutState:    aws.StringValue(deployment.RolloutState),
-		})
-		if status == ecsPrimaryDeploymentStatus && desiredCount == runningCount {
-			// The deployment is done, notify that there is no need for another Fetch call beyond this point.
-			close(s.done)
+}
+
+// failuresOrCompletion returns the events with new failures or a completion, in order.
+func failuresOrCompletion(events []ECSService) []ECSService {
+	var kept []ECSService
+	for _, ev := range events {
+		if len(ev.LatestFailureEvents) > 0 || ev.Completion != nil {
+			kept = append(kept, ev)
 		}
 	}
-	var failureMsgs []string
-	for _, event := range out.Events {
-		if createdAt := aws.TimeValue(event.CreatedAt); createdAt.Before(s.deploymentCreationTime) {
-			break
+	return kept
+}
+
+// throttle coalesces events with the ones that were not emitted yet, and returns the coalesced snapshot
+// if the minimum emit interval elapsed since the last emitted snapshot or if the deployment is done.
+func (s *ECSDeploymentStreamer) throttle(events []ECSService) []ECSService {
+	s.coalescePending(events)
+	if s.pendingEmit == nil {
+		return nil
+	}
+	now := s.now()
+	if s.outcome == "" && !s.lastEmittedAt.IsZero() && now.Sub(s.lastEmittedAt) < s.minEmitInterval {
+		return nil
+	}
+	ev := *s.pendingEmit
+	s.pendingEmit = nil
+	s.lastEmittedAt = now
+	return []ECSService{ev}
+}
+
+// Outcome returns the result of the deployment once the streamer is done, and an empty outcome before.
+func (s *ECSDeploymentStreamer) Outcome() ECSDeploymentOutcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.outcome
+}
+
+// EventStaleness returns how long it has been since the most recent service event, or since the deployment
+// creation time if no event was observed after it. A large staleness while the deployment is not progressing
+// can indicate that ECS is delayed in reporting events.
+func (s *ECSDeploymentStreamer) EventStaleness() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.eventStaleness()
+}
+
+func (s *ECSDeploymentStreamer) eventStaleness() time.Duration {
+	since := s.lastEventAt
+	if since.Before(s.deploymentCreationTime) {
+		since = s.deploymentCreationTime
+	}
+	return s.now().Sub(since)
+}
+
+// DeployDuration returns how long the deployment took from its creation time until the streamer observed its outcome,
+// whether it succeeded or failed. Returns 0 if the deployment is still in progress.
+func (s *ECSDeploymentStreamer) DeployDuration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome == "" {
+		return 0
+	}
+	return s.completedAt.Sub(s.deploymentCreationTime)
+}
+
+func (s *ECSDeploymentStreamer) completion() *ECSDeploymentCompletion {
+	return &ECSDeploymentCompletion{
+		Outcome:     s.outcome,
+		StartedAt:   s.deploymentCreationTime,
+		CompletedAt: s.completedAt,
+		Elapsed:     s.completedAt.Sub(s.deploymentCreationTime),
+
+		MinRunningCount: s.minRunning,
+		MaxRunningCount: s.maxRunning,
+
+		LoadBalancers: s.loadBalancers,
+	}
+}
+
+// loadBalancers converts the load balancers of the service.
+func loadBalancers(in []*awsecs.LoadBalancer) []ECSLoadBalancer {
+	var lbs []ECSLoadBalancer
+	for _, lb := range in {
+		lbs = append(lbs, ECSLoadBalancer{
+			TargetGroupARN:   aws.StringValue(lb.TargetGroupArn),
+			LoadBalancerName: aws.StringValue(lb.LoadBalancerName),
+			ContainerName:    aws.StringValue(lb.ContainerName),
+			ContainerPort:    int(aws.Int64Value(lb.ContainerPort)),
+		})
+	}
+	return lbs
+}
+
+// markSteady marks the deployment as succeeded once the service has been steady for the stability dwell time.
+func (s *ECSDeploymentStreamer) markSteady() {
+	now := s.now()
+	if s.steadySince.IsZero() {
+		s.steadySince = now
+	}
+	if now.Sub(s.steadySince) < s.stabilityDwell {
+		return
+	}
+	if s.aborted {
+		s.markDone(ECSDeploymentRolledBack, "")
+		return
+	}
+	s.markDone(ECSDeploymentSucceeded, "")
+}
+
+// trackProgress records the progress of the primary deployment, and returns a failure reason if the deployment
+// stalled or too many of its tasks failed since its running count last reached a new high.
+// Any new high of the running count resets both trackers, so a deployment that recovers is not failed prematurely,
+// while tasks that crash and restart don't count as progress. A new primary deployment, such as a rollback, is tracked
+// from scratch. A deployment that reached its running target is only waiting for its targets to be healthy, so it
+// isn't stalled.
+func (s *ECSDeploymentStreamer) trackProgress(primary *awsecs.Deployment) string {
+	now := s.now()
+	id, running, failed := aws.StringValue(primary.Id), aws.Int64Value(primary.RunningCount), aws.Int64Value(primary.FailedTasks)
+	if id != s.progressID {
+		s.progressID, s.peakRunning, s.lastProgressAt = id, 0, time.Time{}
+	}
+	if s.lastProgressAt.IsZero() || running > s.peakRunning || s.isRunningTargetReached(primary) {
+		s.lastProgressAt = now
+		s.failedAtProgress = failed
+	}
+	if running > s.peakRunning {
+		s.peakRunning = running
+	}
+	if n := failed - s.failedAtProgress; s.maxFailedTasks > 0 && n >= s.maxFailedTasks {
+		return fmt.Sprintf("%d tasks failed to start without any new running task", n)
+	}
+	if stalled := now.Sub(s.lastProgressAt); s.stallTimeout > 0 && stalled >= s.stallTimeout {
+		return fmt.Sprintf("no new running task for %s", stalled)
+	}
+	return ""
+}
+
+// stuckPendingNotice returns a warning if the streamer is created WithPendingWarning and the primary deployment
+// has had at least the threshold of PENDING tasks for the warning window without any new running task.
+// It must be called after trackProgress. The warning is emitted once until the tasks are no longer stuck.
+func (s *ECSDeploymentStreamer) stuckPendingNotice(primary *awsecs.Deployment) []ECSNotice {
+	if s.pendingThreshold <= 0 {
+		return nil
+	}
+	pending := aws.Int64Value(primary.PendingCount)
+	if pending < s.pendingThreshold {
+		s.pendingSince, s.noticedPending = time.Time{}, false
+		return nil
+	}
+	now := s.now()
+	if s.pendingSince.IsZero() || s.lastProgressAt.After(s.pendingSince) {
+		s.pendingSince, s.noticedPending = now, false
+	}
+	stuck := now.Sub(s.pendingSince)
+	if s.noticedPending || stuck < s.pendingWindow {
+		return nil
+	}
+	s.noticedPending = true
+	return []ECSNotice{
+		{
+			Kind:     ECSNoticeTasksStuckPending,
+			Severity: ECSNoticeWarning,
+			Message: fmt.Sprintf("%d tasks stuck in PENDING for %s without any new running task, the tasks may not be placeable or the cluster may lack capacity",
+				pending, stuck),
+		},
+	}
+}
+
+// isCreatedByDeployment returns true if the service was created at or after the deployment creation time,
+// meaning that the deployment is the initial deployment of the service.
+func (s *ECSDeploymentStreamer) isCreatedByDeployment(out *ecs.Service) bool {
+	return out.CreatedAt != nil && !aws.TimeValue(out.CreatedAt).Before(s.deploymentCreationTime)
+}
+
+// timedOut returns a failure reason if the streamer is created WithDeploymentTimeouts and the deployment
+// is still in progress after the timeout matching whether it's the initial deployment of the service.
+func (s *ECSDeploymentStreamer) timedOut() string {
+	timeout, kind := s.updateTimeout, "deployment"
+	if s.initialDeployment {
+		timeout, kind = s.initialTimeout, "initial deployment"
+	}
+	if timeout <= 0 || s.now().Sub(s.deploymentCreationTime) < timeout {
+		return ""
+	}
+	return fmt.Sprintf("%s did not complete within %s", kind, timeout)
+}
+
+// IsInitialDeployment returns true if the first Fetch found that the service was created by the deployment,
+// rather than updated by it.
+func (s *ECSDeploymentStreamer) IsInitialDeployment() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.initialDeployment
+}
+
+// failureReasonOf returns the most specific reason for the failed deployment. If ECS timed out the deployment,
+// the reason says so to distinguish it from a timeout of the caller. Otherwise, it is the most recent classified
+// failure event, the rollout state reason of the deployment, or a generic reason if neither is known.
+// The category is the one of the failure event used as the reason, ECSFailureCategoryUnknown otherwise.
+func (s *ECSDeploymentStreamer) failureReasonOf(failed *awsecs.Deployment) (string, ECSFailureCategory) {
+	reason := aws.StringValue(failed.RolloutStateReason)
+	if parseRolloutFailureCause(reason) == ECSRolloutFailureCauseTimeout {
+		return ecsDeploymentTimedOutReason, ECSFailureCategoryUnknown
+	}
+	if s.latestClassified != "" {
+		return s.latestClassified, s.latestCategory
+	}
+	if reason != "" {
+		return reason, ECSFailureCategoryUnknown
+	}
+	return defaultECSDeploymentFailureReason, ECSFailureCategoryUnknown
+}
+
+// RolloutFailureCause returns why ECS failed the rollout, such as its deployment circuit breaker or a timeout,
+// once the streamer is done because ECS failed the deployment. Returns an empty cause otherwise, including
+// when the streamer failed the deployment itself.
+func (s *ECSDeploymentStreamer) RolloutFailureCause() ECSRolloutFailureCause {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rolloutCause
+}
+
+// FailureReason returns a single reason explaining why the deployment failed once the streamer is done
+// with the ECSDeploymentFailed or ECSDeploymentSuperseded outcome, and an empty string otherwise.
+func (s *ECSDeploymentStreamer) FailureReason() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome != ECSDeploymentFailed && s.outcome != ECSDeploymentSuperseded {
+		return ""
+	}
+	return s.failureReason
+}
+
+// FailureCategory returns the category of the failure service event used as FailureReason once the streamer is done
+// with the ECSDeploymentFailed or ECSDeploymentSuperseded outcome, or ECSFailureCategoryUnknown if the reason doesn't
+// come from a classified service event, such as a stall or a timeout. Returns an empty category otherwise.
+func (s *ECSDeploymentStreamer) FailureCategory() ECSFailureCategory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome != ECSDeploymentFailed && s.outcome != ECSDeploymentSuperseded {
+		return ""
+	}
+	if s.failureCategory == "" {
+		return ECSFailureCategoryUnknown
+	}
+	return s.failureCategory
+}
+
+// markDone records the outcome of the deployment.
+// Fetch closes the done channel once the last snapshot is recorded, notifying that there is no need
+// for another Fetch call beyond this point.
+func (s *ECSDeploymentStreamer) markDone(outcome ECSDeploymentOutcome, failureReason string) {
+	if s.outcome != "" {
+		return
+	}
+	s.outcome = outcome
+	s.failureReason = failureReason
+	s.completedAt = s.now()
+}
+
+// markFailedBy marks the deployment as failed with the reason, because of the tasks of the deployment or task set
+// with the ID. The ID is empty if the failure can't be attributed to the tasks of the watched deployment.
+func (s *ECSDeploymentStreamer) markFailedBy(id, failureReason string) {
+	if s.outcome == "" {
+		s.failedID = id
+	}
+	s.markDone(ECSDeploymentFailed, failureReason)
+}
+
+// isUnchanged returns true if the first Fetch finds the primary deployment as the only one, already completed,
+// and created before the deployment creation time, meaning that the deploy didn't start a new deployment.
+func (s *ECSDeploymentStreamer) isUnchanged(in []*awsecs.Deployment, primary *awsecs.Deployment) bool {
+	if s.hasFetched || s.deploymentID != "" || len(in) != 1 {
+		return false
+	}
+	createdAt := aws.TimeValue(primary.CreatedAt)
+	return rolloutState(primary) == ECSRolloutStateCompleted &&
+		!createdAt.IsZero() && createdAt.Before(s.deploymentCreationTime)
+}
+
+// isFailedDeployment returns true if the deployment failed to roll out and is either the primary deployment
+// or was created since the deployment creation time, for example when the deployment circuit breaker rolled it back.
+func (s *ECSDeploymentStreamer) isFailedDeployment(deployment *awsecs.Deployment) bool {
+	if !rolloutState(deployment).IsFailed() {
+		return false
+	}
+	return aws.StringValue(deployment.Status) == ecsPrimaryDeploymentStatus ||
+		!aws.TimeValue(deployment.CreatedAt).Before(s.deploymentCreationTime)
+}
+
+// isRunningTargetReached returns true if the deployment has enough running tasks to be considered completed.
+// By default, the running count must be equal to the desired count.
+func (s *ECSDeploymentStreamer) isRunningTargetReached(deployment *awsecs.Deployment) bool {
+	running := aws.Int64Value(deployment.RunningCount)
+	if !s.isMinimumHealthyReached(deployment) {
+		return false
+	}
+	if s.targetRunningCount == 0 && s.targetRunningPercent == 0 {
+		return running == s.runningTarget(deployment)
+	}
+	return running >= s.runningTarget(deployment)
+}
+
+// updateDraining returns the drain progress of the ACTIVE deployments, recording their running count the first time
+// they are observed.
+func (s *ECSDeploymentStreamer) updateDraining(deployments []ECSDeployment) []ECSDrainProgress {
+	var draining []ECSDrainProgress
+	for _, d := range deployments {
+		if d.Status != ecsActiveDeploymentStatus {
+			continue
 		}
-		id := aws.StringValue(event.Id)
-		if _, ok := s.pastEventIDs[id]; ok {
-			break
+		if s.drainFrom == nil {
+			s.drainFrom = make(map[string]int)
 		}
-		if msg := aws.StringValue(event.Message); isFailureServiceEvent(msg) {
-			failureMsgs = append(failureMsgs, msg)
+		from, ok := s.drainFrom[d.TaskDefRevision]
+		if !ok {
+			from = d.RunningCount
+			s.drainFrom[d.TaskDefRevision] = from
 		}
-		s.pastEventIDs[id] = true
+		draining = append(draining, ECSDrainProgress{
+			TaskDefRevision: d.TaskDefRevision,
+			FromCount:       from,
+			RunningCount:    d.RunningCount,
+		})
 	}
-	s.eventsToFlush = append(s.eventsToFlush, ECSService{
-		Deployments:         deployments,
-		LatestFailureEvents: failureMsgs,
-	})
-	return time.Now().Add(streamerFetchIntervalDuration), nil
+	return draining
 }
 
-// Notify flushes all new events to the streamer's subscribers.
-func (s *ECSDeploymentStreamer) Notify() {
-	for _, event := range s.eventsToFlush {
-		for _, sub := range s.subscribers {
-			sub <- event
+// updateMinimumHealthy records the minimum healthy percent of the service and the desired count of its deployments
+// the first time they are observed.
+func (s *ECSDeploymentStreamer) updateMinimumHealthy(out *ecs.Service) {
+	s.minHealthyPercent = 0
+	if out.DeploymentConfiguration != nil {
+		s.minHealthyPercent = aws.Int64Value(out.DeploymentConfiguration.MinimumHealthyPercent)
+	}
+	if s.initialDesired == nil {
+		s.initialDesired = make(map[string]int64)
+	}
+	for _, d := range out.Deployments {
+		id := aws.StringValue(d.Id)
+		if _, ok := s.initialDesired[id]; !ok {
+			s.initialDesired[id] = aws.Int64Value(d.DesiredCount)
 		}
 	}
-	s.eventsToFlush = nil // reset after flushing all events.
+}
+
+// isMinimumHealthyReached returns true unless the streamer is created WithMinimumHealthyPercent and the deployment is
+// still rolling out with fewer running tasks than the minimum healthy percent of its initial desired count.
+func (s *ECSDeploymentStreamer) isMinimumHealthyReached(deployment *awsecs.Deployment) bool {
+	if !s.requireMinHealthy || s.minHealthyPercent <= 0 || rolloutState(deployment) == ECSRolloutStateCompleted {
+		return true
+	}
+	initial, ok := s.initialDesired[aws.StringValue(deployment.Id)]
+	if !ok {
+		return true
+	}
+	threshold := (initial*s.minHealthyPercent + 99) / 100 // Round up so that a fraction of a task is never enough.
+	return aws.Int64Value(deployment.RunningCount) >= threshold
+}
+
+// runningTarget returns the number of running tasks the deployment needs to be considered completed.
+func (s *ECSDeploymentStreamer) runningTarget(deployment *awsecs.Deployment) int64 {
+	desired := aws.Int64Value(deployment.DesiredCount)
+	switch {
+	case s.targetRunningCount > 0:
+		if s.targetRunningCount > desired {
+			return desired
+		}
+		return s.targetRunningCount
+	case s.targetRunningPercent > 0:
+		return (desired*s.targetRunningPercent + 99) / 100 // Round up so that a fraction of a task is never enough.
+	default:
+		return desired
+	}
+}
+
+// areTargetsHealthy returns true if each target group of the service has as many healthy targets of the deployment's
+// tasks as the running target of the deployment. Services without target groups, or streamers created without
+// WithTargetHealth, only rely on the running count.
+func (s *ECSDeploymentStreamer) areTargetsHealthy(deployment *awsecs.Deployment, healthyTargets map[string]int) bool {
+	target := s.runningTarget(deployment)
+	for _, healthy := range healthyTargets {
+		if int64(healthy) < target {
+			return false
+		}
+	}
+	return true
+}
+
+// AbortDeployment rolls the service back to the task definition of its previous deployment.
+// The streamer keeps streaming the rollback, and its outcome is ECSDeploymentRolledBack once the rollback completes.
+// The streamer must be created WithServiceUpdater, otherwise ErrAbortUnsupported is returned.
+func (s *ECSDeploymentStreamer) AbortDeployment() error {
+	if s.updater == nil {
+		return ErrAbortUnsupported
+	}
+	s.mu.Lock()
+	cluster, taskDef, outcome := s.cluster, s.rollbackTaskDef, s.outcome
+	s.mu.Unlock()
+	if outcome != "" {
+		return fmt.Errorf("deployment of service %s already completed with outcome %s", s.service, outcome)
+	}
+	if taskDef == "" {
+		return fmt.Errorf("no previous deployment of service %s to roll back to", s.service)
+	}
+	// The rollback deployment is created by the update, so it must not be mistaken for a prior deployment.
+	started := s.now()
+	if err := s.updater.UpdateServiceTaskDefinition(cluster, s.service, taskDef); err != nil {
+		return fmt.Errorf("roll back service %s: %w", s.service, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aborted = true
+	s.deploymentCreationTime = started // Only watch the rollback deployment from now on.
+	s.primaryRevision = ""             // The primary revision is expected to change, don't warn about it.
+	s.steadySince = time.Time{}
+	return nil
 }
 
 // Close closes all subscribed channels notifying them that no more events will be sent.
 func (s *ECSDeploymentStreamer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
 	for _, sub := range s.subscribers {
 		close(sub)
 	}
+	for _, sub := range s.deltaSubscribers {
+		close(sub)
+	}
+	s.closed = true
+}
+
+// Reset prepares the streamer to watch a subsequent deployment of the same service created at deploymentCreationTime.
+// Past events are forgotten, events that were not flushed yet are discarded, and Done returns a new open channel.
+// Existing subscribers keep receiving events on the same channels. The failures retained WithFailureHistory
+// are carried over, and the failures of the subsequent deployment are tagged with the next attempt number.
+//
+// Closed channels can't be reopened, so Reset returns ErrStreamerClosed if Close was already called.
+// Since Stream closes the streamer when it returns, a streamer that is reused across deployments
+// must be driven by calling Fetch and Notify directly instead.
+func (s *ECSDeploymentStreamer) Reset(deploymentCreationTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrStreamerClosed
+	}
+	s.deploymentCreationTime = deploymentCreationTime
+	s.done = make(chan struct{})
+	s.eventsToFlush = nil
+	s.attempt++
+	s.ecsDeploymentState = newECSDeploymentState()
+	return nil
 }
 
 // Done returns a channel that's closed when there are no more events that can be fetched.
 func (s *ECSDeploymentStreamer) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.done
 }
 
+// DebugState returns a snapshot of the internal state of the streamer to troubleshoot deployments that appear stuck.
+// It is safe to call at any time, including while the streamer is being driven by Stream.
+func (s *ECSDeploymentStreamer) DebugState() ECSDeploymentStreamerDebugState {
+	requestID := s.LastRequestID()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deployments := make([]ECSDeployment, len(s.deployments))
+	copy(deployments, s.deployments)
+	return ECSDeploymentStreamerDebugState{
+		Cluster:                s.cluster,
+		Service:                s.service,
+		DeploymentCreationTime: s.deploymentCreationTime,
+		LastFetchedAt:          s.lastFetchedAt,
+		Deployments:            deployments,
+		RunningCountByRevision: ECSService{Deployments: deployments}.RunningCountByRevision(),
+		PastEventsCount:        len(s.pastEventIDs),
+		PendingEventsCount:     len(s.eventsToFlush),
+		Outcome:                s.outcome,
+		MinRunningCount:        s.minRunning,
+		MaxRunningCount:        s.maxRunning,
+		SubscriberCount:        s.subscriberCount(),
+		LastRequestID:          requestID,
+	}
+}
+
+// SubscriberCount returns the number of channels that Notify sends descriptions or deltas to, for example to find out
+// whether a subscriber that isn't reading its channel could block Notify. It returns 0 once the streamer is closed.
+func (s *ECSDeploymentStreamer) SubscriberCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscriberCount()
+}
+
+func (s *ECSDeploymentStreamer) subscriberCount() int {
+	if s.closed {
+		return 0
+	}
+	return len(s.subscribers) + len(s.deltaSubscribers)
+}
+
+// LastRequestID returns the AWS request ID of the last call made to describe the service, if the streamer's
+// describer records it like an ECSRequestIDDescriber. Returns an empty string otherwise.
+func (s *ECSDeploymentStreamer) LastRequestID() string {
+	describer, ok := s.client.(interface{ LastRequestID() string })
+	if !ok {
+		return ""
+	}
+	return describer.LastRequestID()
+}
+
+// rolloutStateReason returns the rollout state reason of the deployment to emit.
+func (s *ECSDeploymentStreamer) rolloutStateReason(deployment *awsecs.Deployment) string {
+	reason := aws.StringValue(deployment.RolloutStateReason)
+	if !s.dedupeReasons {
+		return reason
+	}
+	id := aws.StringValue(deployment.Id)
+	if prev, ok := s.emittedReasons[id]; ok && prev == reason {
+		return ""
+	}
+	s.emittedReasons[id] = reason
+	return reason
+}
+
+// primaryDeployment returns the primary deployment in deployments, or nil if there is none.
+func primaryDeployment(deployments []*awsecs.Deployment) *awsecs.Deployment {
+	for _, deployment := range deployments {
+		if aws.StringValue(deployment.Status) == ecsPrimaryDeploymentStatus {
+			return deployment
+		}
+	}
+	return nil
+}
+
+// deploymentTasks returns the tasks of the service started by a deployment. The tasks are described with the
+// describer of WithTaskPlacement if set, or the one of WithTaskTransitions, or the one of WithTargetHealth.
+func (s *ECSDeploymentStreamer) deploymentTasks(deploymentID string) ([]*ecs.Task, error) {
+	client := s.tasksClient
+	if client == nil {
+		client = s.transitionTasks
+	}
+	if client == nil {
+		client = s.healthTasks
+	}
+	tasks, err := client.ServiceTasks(s.cluster, s.service)
+	if err != nil {
+		return nil, fmt.Errorf("describe tasks of service %s: %w", s.service, err)
+	}
+	var started []*ecs.Task
+	for _, task := range tasks {
+		if aws.StringValue(task.StartedBy) == deploymentID {
+			started = append(started, task)
+		}
+	}
+	return started, nil
+}
+
+// taskPlacement returns the number of running and pending tasks per availability zone sorted by availability zone.
+// If there are no tasks yet, returns nil.
+func taskPlacement(tasks []*ecs.Task) []ECSTaskPlacement {
+	countsByAZ := make(map[string]*ECSTaskPlacement)
+	for _, task := range tasks {
+		az := aws.StringValue(task.AvailabilityZone)
+		if _, ok := countsByAZ[az]; !ok {
+			countsByAZ[az] = &ECSTaskPlacement{AvailabilityZone: az}
+		}
+		if aws.StringValue(task.LastStatus) == ecsRunningTaskStatus {
+			countsByAZ[az].RunningCount++
+		} else {
+			countsByAZ[az].PendingCount++
+		}
+	}
+	var placement []ECSTaskPlacement
+	for _, counts := range countsByAZ {
+		placement = append(placement, *counts)
+	}
+	sort.Slice(placement, func(i, j int) bool {
+		return placement[i].AvailabilityZone < placement[j].AvailabilityZone
+	})
+	return placement
+}
+
+// taskTransitions records the last status of the tasks of the primary deployment and returns the ones that changed
+// since the previous Fetch. Tracked tasks that are no longer listed transition to STOPPED and are no longer tracked.
+// The tracked tasks are forgotten when the primary deployment changes. It must be called with the lock held.
+func (s *ECSDeploymentStreamer) taskTransitions(deploymentID string, tasks []*ecs.Task) []ECSTaskTransition {
+	if s.taskStatuses == nil || deploymentID != s.trackedDeployment {
+		s.trackedDeployment = deploymentID
+		s.taskStatuses = make(map[string]string)
+	}
+	now := s.now()
+	var transitions []ECSTaskTransition
+	listed := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		arn, status := aws.StringValue(task.TaskArn), aws.StringValue(task.LastStatus)
+		listed[arn] = true
+		prev, tracked := s.taskStatuses[arn]
+		if tracked && prev == status {
+			continue
+		}
+		if !tracked && len(s.taskStatuses) >= s.maxTrackedTasks {
+			continue
+		}
+		s.taskStatuses[arn] = status
+		transitions = append(transitions, ECSTaskTransition{TaskARN: arn, From: prev, To: status, At: now})
+	}
+	var gone []string
+	for arn := range s.taskStatuses {
+		if !listed[arn] {
+			gone = append(gone, arn)
+		}
+	}
+	sort.Strings(gone)
+	for _, arn := range gone {
+		if prev := s.taskStatuses[arn]; prev != awsecs.DesiredStatusStopped {
+			transitions = append(transitions, ECSTaskTransition{TaskARN: arn, From: prev, To: awsecs.DesiredStatusStopped, At: now})
+		}
+		delete(s.taskStatuses, arn)
+	}
+	return transitions
+}
+
+// revisionChangedNotice returns a warning that the primary deployment switched task definition revisions
+// while the streamer was watching, which usually means that a concurrent deployment raced the watched one.
+func revisionChangedNotice(oldRevision, newRevision string) ECSNotice {
+	return ECSNotice{
+		Kind:     ECSNoticeRevisionChanged,
+		Severity: ECSNoticeWarning,
+		Message: fmt.Sprintf("primary deployment changed from revision %s to %s, another deployment may have started concurrently",
+			oldRevision, newRevision),
+	}
+}
+
+// isTransientError returns true if err is expected to go away if the request is retried,
+// such as a throttling error, a server error or a timeout.
+func isTransientError(err error) bool {
+	if errors.Is(err, errFetchTimeout) {
+		return true
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return request.IsErrorThrottle(aerr) || request.IsErrorRetryable(aerr)
+}
+
+// isAccessDeniedError returns true if err reports that the caller is not authorized to perform the request.
+func isAccessDeniedError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	switch aerr.Code() {
+	case "AccessDeniedException", "AccessDenied":
+		return true
+	}
+	return false
+}
+
+// isClusterNotFoundError returns true if err reports that the cluster of the service does not exist.
+func isClusterNotFoundError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return aerr.Code() == awsecs.ErrCodeClusterNotFoundException
+}
+
+// circuitBreaker returns the deployment circuit breaker configuration of the service, or nil if it has none.
+func circuitBreaker(out *ecs.Service) *ECSCircuitBreaker {
+	if out.DeploymentConfiguration == nil || out.DeploymentConfiguration.DeploymentCircuitBreaker == nil {
+		return nil
+	}
+	cb := out.DeploymentConfiguration.DeploymentCircuitBreaker
+	return &ECSCircuitBreaker{
+		Enabled:  aws.BoolValue(cb.Enable),
+		Rollback: aws.BoolValue(cb.Rollback),
+	}
+}
+
+// isExpiredCredentialsError returns true if err reports that the credentials used to sign the request expired.
+func isExpiredCredentialsError(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return request.IsErrorExpiredCreds(aerr)
+}
+
 // parseRevisionFromTaskDefARN returns the revision number as string given the ARN of a task definition.
 // For example, given the input "arn:aws:ecs:us-west-2:1111:task-definition/webapp-test-frontend:3"
 // the output is "3".
@@ -150,12 +3088,3 @@ func parseRevisionFromTaskDefARN(arn string) string {
 	familyName := strings.Split(arn, "/")[1]
 	return strings.Split(familyName, ":")[1]
 }
-
-func isFailureServiceEvent(msg string) bool {
-	for _, kw := range ecsEventFailureKeywords {
-		if strings.Contains(msg, kw) {
-			return true
-		}
-	}
-	return false
-}
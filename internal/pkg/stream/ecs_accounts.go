@@ -0,0 +1,64 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"time"
+)
+
+// ECSDescriberFactory is the interface to build the describers of services deployed across AWS accounts.
+type ECSDescriberFactory interface {
+	// DescriberForAccount returns a describer of the services in the account and region, for example
+	// an ecs.ECS created from a session whose credentials assume a role in the account.
+	DescriberForAccount(accountID, region string) (ECSServiceDescriber, error)
+}
+
+// ECSServiceLocation identifies a service deployed in an AWS account and region.
+type ECSServiceLocation struct {
+	AccountID string `json:"accountID,omitempty"`
+	Region    string `json:"region"`
+	Cluster   string `json:"cluster"`
+	Service   string `json:"service"`
+}
+
+// name returns the name of the location in an ECSMultiRegionStreamer, such as "111111111111/us-west-2/my-cluster/my-svc".
+func (l ECSServiceLocation) name() string {
+	return fmt.Sprintf("%s/%s/%s/%s", l.AccountID, l.Region, l.Cluster, l.Service)
+}
+
+// NewECSMultiAccountStreamer creates an ECSMultiRegionStreamer for a deployment of services to several accounts,
+// such as an environment spanning multiple accounts, with a streamer per location built from the describer
+// returned by the factory. The streamerOpts are applied to each streamer, and the opts to the ECSMultiRegionStreamer.
+// Each ECSRegionalService reports its location, and failed locations are reported in FailedLocations.
+// A service can only be watched once per location.
+func NewECSMultiAccountStreamer(factory ECSDescriberFactory, locations []ECSServiceLocation, deploymentCreationTime time.Time,
+	streamerOpts []ECSDeploymentStreamerOpt, opts ...ECSMultiRegionStreamerOpt) (*ECSMultiRegionStreamer, error) {
+	regions := make([]ecsRegionStreamer, 0, len(locations))
+	watched := make(map[string]bool, len(locations))
+	describers := make(map[string]ECSServiceDescriber)
+	for _, loc := range locations {
+		if watched[loc.name()] {
+			return nil, fmt.Errorf("service %s is watched more than once in cluster %s of account %s and region %s",
+				loc.Service, loc.Cluster, loc.AccountID, loc.Region)
+		}
+		account := loc.AccountID + "/" + loc.Region
+		describer, ok := describers[account]
+		if !ok {
+			var err error
+			describer, err = factory.DescriberForAccount(loc.AccountID, loc.Region)
+			if err != nil {
+				return nil, fmt.Errorf("create describer for account %s in region %s: %w", loc.AccountID, loc.Region, err)
+			}
+			describers[account] = describer
+		}
+		watched[loc.name()] = true
+		regions = append(regions, ecsRegionStreamer{
+			name:     loc.name(),
+			location: loc,
+			streamer: NewECSDeploymentStreamer(describer, loc.Cluster, loc.Service, deploymentCreationTime, streamerOpts...),
+		})
+	}
+	return newECSMultiRegionStreamer(regions, opts...), nil
+}
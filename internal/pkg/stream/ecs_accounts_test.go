@@ -0,0 +1,164 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+// mockECSDescriberFactory returns the describer of each "<account ID>/<region>", or err.
+type mockECSDescriberFactory struct {
+	describers map[string]ECSServiceDescriber
+	err        error
+}
+
+func (m mockECSDescriberFactory) DescriberForAccount(accountID, region string) (ECSServiceDescriber, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.describers[accountID+"/"+region], nil
+}
+
+// countingECSDescriberFactory returns the same describer for every account and region, and counts the calls.
+type countingECSDescriberFactory struct {
+	describer ECSServiceDescriber
+	calls     int
+}
+
+func (m *countingECSDescriberFactory) DescriberForAccount(accountID, region string) (ECSServiceDescriber, error) {
+	m.calls++
+	return m.describer, nil
+}
+
+func TestNewECSMultiAccountStreamer(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	newService := func(running int64) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String("IN_PROGRESS"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate),
+				},
+			},
+		}
+	}
+	locations := []ECSServiceLocation{
+		{AccountID: "111111111111", Region: "us-west-2", Cluster: "my-cluster", Service: "my-svc"},
+		{AccountID: "222222222222", Region: "us-west-2", Cluster: "my-cluster", Service: "my-svc"},
+	}
+	t.Run("watches the service in each account", func(t *testing.T) {
+		// GIVEN
+		factory := mockECSDescriberFactory{describers: map[string]ECSServiceDescriber{
+			"111111111111/us-west-2": mockECS{out: newService(1)},
+			"222222222222/us-west-2": mockECS{out: newService(2)},
+		}}
+		streamer, err := NewECSMultiAccountStreamer(factory, locations, startDate, nil)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		ev := streamer.eventsToFlush[0]
+		require.Len(t, ev.Regions, 2)
+		require.Equal(t, "us-west-2", ev.Regions[0].Region)
+		require.Equal(t, locations[0], ev.Regions[0].Location)
+		require.Equal(t, "us-west-2", ev.Regions[1].Region)
+		require.Equal(t, locations[1], ev.Regions[1].Location)
+		require.Equal(t, 3, ev.RunningCount)
+	})
+	t.Run("reports the locations where the deployment failed", func(t *testing.T) {
+		// GIVEN
+		failed := newService(0)
+		failed.Deployments[0].RolloutState = aws.String("FAILED")
+		failed.Deployments[0].RolloutStateReason = aws.String("ECS deployment circuit breaker: tasks failed to start.")
+		factory := mockECSDescriberFactory{describers: map[string]ECSServiceDescriber{
+			"111111111111/us-west-2": mockECS{out: failed},
+			"222222222222/us-west-2": mockECS{out: failed},
+		}}
+		streamer, err := NewECSMultiAccountStreamer(factory, locations, startDate, nil)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		require.Equal(t, []string{"us-west-2"}, streamer.FailedRegions(), "the region should be reported once")
+		require.Equal(t, locations, streamer.FailedLocations())
+		require.Equal(t, "111111111111/us-west-2/my-cluster/my-svc: ECS deployment circuit breaker: tasks failed to start.; "+
+			"222222222222/us-west-2/my-cluster/my-svc: ECS deployment circuit breaker: tasks failed to start.", streamer.FailureReason())
+	})
+	t.Run("watches several services in the same account and region", func(t *testing.T) {
+		// GIVEN
+		factory := &countingECSDescriberFactory{describer: mockECS{out: newService(1)}}
+		streamer, err := NewECSMultiAccountStreamer(factory, []ECSServiceLocation{
+			{AccountID: "111111111111", Region: "us-west-2", Cluster: "my-cluster", Service: "api"},
+			{AccountID: "111111111111", Region: "us-west-2", Cluster: "my-cluster", Service: "worker"},
+		}, startDate, nil)
+		require.NoError(t, err)
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		ev := streamer.eventsToFlush[0]
+		require.Len(t, ev.Regions, 2)
+		require.Equal(t, "api", ev.Regions[0].Location.Service)
+		require.Equal(t, "worker", ev.Regions[1].Location.Service)
+		require.Equal(t, 1, factory.calls, "the describer of the account and region should be shared")
+	})
+	t.Run("applies the options of the streamers and of the aggregator", func(t *testing.T) {
+		// GIVEN
+		factory := mockECSDescriberFactory{describers: map[string]ECSServiceDescriber{
+			"111111111111/us-west-2": mockECS{out: newService(1)},
+			"222222222222/us-west-2": mockECS{out: newService(2)},
+		}}
+
+		// WHEN
+		streamer, err := NewECSMultiAccountStreamer(factory, locations, startDate,
+			[]ECSDeploymentStreamerOpt{WithStallTimeout(time.Minute)}, WithMaxConcurrentFetches(1))
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, 1, streamer.maxConcurrentFetches)
+		for _, r := range streamer.regions {
+			require.Equal(t, time.Minute, r.streamer.stallTimeout)
+		}
+	})
+	t.Run("returns a wrapped error if a describer can't be created", func(t *testing.T) {
+		// GIVEN
+		factory := mockECSDescriberFactory{err: errors.New("some error")}
+
+		// WHEN
+		_, err := NewECSMultiAccountStreamer(factory, locations, startDate, nil)
+
+		// THEN
+		require.EqualError(t, err, "create describer for account 111111111111 in region us-west-2: some error")
+	})
+	t.Run("returns an error if the service is watched twice in the same location", func(t *testing.T) {
+		// GIVEN
+		factory := mockECSDescriberFactory{describers: map[string]ECSServiceDescriber{}}
+
+		// WHEN
+		_, err := NewECSMultiAccountStreamer(factory, append(locations, locations[0]), startDate, nil)
+
+		// THEN
+		require.EqualError(t, err, "service my-svc is watched more than once in cluster my-cluster of account 111111111111 and region us-west-2")
+	})
+}
@@ -0,0 +1,185 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import "time"
+
+// ECSServiceDelta describes what changed in a description of the service since the previous description emitted,
+// so that a consumer can keep its own copy of the description up to date without receiving it whole.
+type ECSServiceDelta struct {
+	Sequence     uint64 `json:"sequence"`     // Sequence of the description, see ECSService.Sequence.
+	BaseSequence uint64 `json:"baseSequence"` // Sequence of the previous description, 0 if the delta applies to an empty description.
+
+	// Deployments replaces the deployments of the previous description, it is nil if none of them changed.
+	Deployments []ECSDeployment `json:"deployments,omitempty"`
+	// CountChanges and RolloutTransitions summarize how the deployments changed, a new deployment changes from zero counts
+	// and an empty rollout state.
+	CountChanges       []ECSCountChange       `json:"countChanges,omitempty"`
+	RolloutTransitions []ECSRolloutTransition `json:"rolloutTransitions,omitempty"`
+
+	// The fields below are reported by each description rather than accumulated, so they are passed as is.
+	LatestFailureEvents []string                 `json:"latestFailureEvents,omitempty"`
+	LatestFailures      []ECSServiceFailure      `json:"latestFailures,omitempty"`
+	Notices             []ECSNotice              `json:"notices,omitempty"`
+	Completion          *ECSDeploymentCompletion `json:"completion,omitempty"`
+	TaskTransitions     []ECSTaskTransition      `json:"taskTransitions,omitempty"`
+	Start               *ECSDeploymentStart      `json:"start,omitempty"`
+
+	// The fields below describe the service as a whole, they are passed as is even if they didn't change.
+	TaskPlacement         []ECSTaskPlacement         `json:"taskPlacement,omitempty"`
+	EventStaleness        time.Duration              `json:"eventStaleness"`
+	OtherDeploymentsCount int                        `json:"otherDeploymentsCount,omitempty"`
+	FailureCategoryCounts map[ECSFailureCategory]int `json:"failureCategoryCounts,omitempty"`
+	WaitingFor            string                     `json:"waitingFor,omitempty"`
+	CorrelationID         string                     `json:"correlationID,omitempty"`
+	Draining              []ECSDrainProgress         `json:"draining,omitempty"`
+}
+
+// ECSCountChange is a task count of a deployment that changed between two descriptions.
+type ECSCountChange struct {
+	TaskDefRevision string `json:"taskDefRevision"`
+	Count           string `json:"count"` // One of "desired", "running", "pending" or "failed".
+	From            int    `json:"from"`
+	To              int    `json:"to"`
+
+	DeploymentID string `json:"deploymentID,omitempty"`
+}
+
+// ECSRolloutTransition is a change of the rollout state of a deployment between two descriptions.
+type ECSRolloutTransition struct {
+	TaskDefRevision string `json:"taskDefRevision"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+
+	DeploymentID string `json:"deploymentID,omitempty"`
+}
+
+// DeltaSince returns the changes from the prev description to s. Deployments are matched by ID,
+// or by task definition revision if they have none.
+func (s ECSService) DeltaSince(prev ECSService) ECSServiceDelta {
+	delta := ECSServiceDelta{
+		Sequence:              s.Sequence,
+		BaseSequence:          prev.Sequence,
+		LatestFailureEvents:   s.LatestFailureEvents,
+		LatestFailures:        s.LatestFailures,
+		Notices:               s.Notices,
+		Completion:            s.Completion,
+		TaskTransitions:       s.TaskTransitions,
+		Start:                 s.Start,
+		TaskPlacement:         s.TaskPlacement,
+		EventStaleness:        s.EventStaleness,
+		OtherDeploymentsCount: s.OtherDeploymentsCount,
+		FailureCategoryCounts: s.FailureCategoryCounts,
+		WaitingFor:            s.WaitingFor,
+		CorrelationID:         s.CorrelationID,
+		Draining:              s.Draining,
+	}
+	previous := make(map[string]ECSDeployment, len(prev.Deployments))
+	for _, d := range prev.Deployments {
+		if _, ok := previous[d.deltaKey()]; !ok {
+			previous[d.deltaKey()] = d
+		}
+	}
+	changed := len(s.Deployments) != len(prev.Deployments)
+	for i, d := range s.Deployments {
+		if !changed && d != prev.Deployments[i] {
+			changed = true
+		}
+		old := previous[d.deltaKey()]
+		for _, c := range []ECSCountChange{
+			{d.TaskDefRevision, "desired", old.DesiredCount, d.DesiredCount, d.ID},
+			{d.TaskDefRevision, "running", old.RunningCount, d.RunningCount, d.ID},
+			{d.TaskDefRevision, "pending", old.PendingCount, d.PendingCount, d.ID},
+			{d.TaskDefRevision, "failed", old.FailedCount, d.FailedCount, d.ID},
+		} {
+			if c.From != c.To {
+				delta.CountChanges = append(delta.CountChanges, c)
+			}
+		}
+		if old.RolloutState != d.RolloutState {
+			delta.RolloutTransitions = append(delta.RolloutTransitions, ECSRolloutTransition{
+				TaskDefRevision: d.TaskDefRevision,
+				From:            old.RolloutState,
+				To:              d.RolloutState,
+				DeploymentID:    d.ID,
+			})
+		}
+	}
+	if changed {
+		delta.Deployments = s.Deployments
+	}
+	return delta.clone()
+}
+
+// deltaKey returns the key that matches the deployment across descriptions.
+func (d ECSDeployment) deltaKey() string {
+	if d.ID != "" {
+		return d.ID
+	}
+	return d.TaskDefRevision
+}
+
+// clone returns a deep copy of the delta so that subscribers don't share slices.
+func (d ECSServiceDelta) clone() ECSServiceDelta {
+	svc := ECSService{
+		Deployments:           d.Deployments,
+		LatestFailureEvents:   d.LatestFailureEvents,
+		LatestFailures:        d.LatestFailures,
+		Notices:               d.Notices,
+		Completion:            d.Completion,
+		TaskTransitions:       d.TaskTransitions,
+		Start:                 d.Start,
+		TaskPlacement:         d.TaskPlacement,
+		FailureCategoryCounts: d.FailureCategoryCounts,
+		Draining:              d.Draining,
+	}.clone()
+	c := d
+	c.Deployments, c.LatestFailureEvents, c.LatestFailures, c.Notices, c.Completion, c.TaskTransitions =
+		svc.Deployments, svc.LatestFailureEvents, svc.LatestFailures, svc.Notices, svc.Completion, svc.TaskTransitions
+	c.Start, c.TaskPlacement, c.FailureCategoryCounts, c.Draining = svc.Start, svc.TaskPlacement, svc.FailureCategoryCounts, svc.Draining
+	if d.CountChanges != nil {
+		c.CountChanges = make([]ECSCountChange, len(d.CountChanges))
+		copy(c.CountChanges, d.CountChanges)
+	}
+	if d.RolloutTransitions != nil {
+		c.RolloutTransitions = make([]ECSRolloutTransition, len(d.RolloutTransitions))
+		copy(c.RolloutTransitions, d.RolloutTransitions)
+	}
+	return c
+}
+
+// SubscribeDeltas returns a read-only channel that receives, instead of each description of the service,
+// the changes since the previous description. The deltas have the same sequence numbers as the descriptions received
+// by Subscribe and apply in order, a delta with a zero BaseSequence, such as the first one after Reset, applies
+// to an empty description.
+func (s *ECSDeploymentStreamer) SubscribeDeltas() <-chan ECSServiceDelta {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(chan ECSServiceDelta)
+	s.deltaSubscribers = append(s.deltaSubscribers, c)
+	return c
+}
+
+// deltas returns the deltas of the events since the last emitted description along with the subscribers to send
+// them to, and records the last event as emitted. It must be called with the lock held, once the events are sequenced.
+func (s *ECSDeploymentStreamer) deltas(events []ECSService) ([]ECSServiceDelta, []chan ECSServiceDelta) {
+	if len(s.deltaSubscribers) == 0 {
+		return nil, nil
+	}
+	deltas := make([]ECSServiceDelta, len(events))
+	for i, ev := range events {
+		deltas[i] = ev.DeltaSince(s.lastEmitted)
+		s.lastEmitted = ev.clone()
+	}
+	return deltas, s.deltaSubscribers
+}
+
+// sendDeltas sends deltas to the subscribers. It must be called without the lock held, since subscribers can be slow.
+func sendDeltas(deltas []ECSServiceDelta, subscribers []chan ECSServiceDelta) {
+	for _, delta := range deltas {
+		for _, sub := range subscribers {
+			sub <- delta.clone()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestECSService_DeltaSince(t *testing.T) {
+	primary := ECSDeployment{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1, PendingCount: 1, RolloutState: "IN_PROGRESS"}
+	active := ECSDeployment{Status: "ACTIVE", TaskDefRevision: "2", DesiredCount: 2, RunningCount: 2}
+	testCases := map[string]struct {
+		prev ECSService
+		curr ECSService
+
+		wanted ECSServiceDelta
+	}{
+		"first description": {
+			curr: ECSService{Sequence: 1, Deployments: []ECSDeployment{primary}},
+			wanted: ECSServiceDelta{
+				Sequence:    1,
+				Deployments: []ECSDeployment{primary},
+				CountChanges: []ECSCountChange{
+					{TaskDefRevision: "3", Count: "desired", From: 0, To: 2},
+					{TaskDefRevision: "3", Count: "running", From: 0, To: 1},
+					{TaskDefRevision: "3", Count: "pending", From: 0, To: 1},
+				},
+				RolloutTransitions: []ECSRolloutTransition{{TaskDefRevision: "3", From: "", To: "IN_PROGRESS"}},
+			},
+		},
+		"unchanged deployments with new failures": {
+			prev: ECSService{Sequence: 1, Deployments: []ECSDeployment{primary, active}},
+			curr: ECSService{
+				Sequence:            2,
+				Deployments:         []ECSDeployment{primary, active},
+				LatestFailureEvents: []string{"(service my-svc) was unable to place a task."},
+			},
+			wanted: ECSServiceDelta{
+				Sequence:            2,
+				BaseSequence:        1,
+				LatestFailureEvents: []string{"(service my-svc) was unable to place a task."},
+			},
+		},
+		"completed deployment": {
+			prev: ECSService{Sequence: 1, Deployments: []ECSDeployment{primary, active}},
+			curr: ECSService{
+				Sequence: 2,
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"},
+				},
+				Completion: &ECSDeploymentCompletion{Outcome: ECSDeploymentSucceeded},
+			},
+			wanted: ECSServiceDelta{
+				Sequence:     2,
+				BaseSequence: 1,
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"},
+				},
+				CountChanges: []ECSCountChange{
+					{TaskDefRevision: "3", Count: "running", From: 1, To: 2},
+					{TaskDefRevision: "3", Count: "pending", From: 1, To: 0},
+				},
+				RolloutTransitions: []ECSRolloutTransition{{TaskDefRevision: "3", From: "IN_PROGRESS", To: "COMPLETED"}},
+				Completion:         &ECSDeploymentCompletion{Outcome: ECSDeploymentSucceeded},
+			},
+		},
+		"forced deployment with the same revision": {
+			prev: ECSService{
+				Sequence: 1,
+				Deployments: []ECSDeployment{
+					{ID: "ecs-svc/2", Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"},
+				},
+			},
+			curr: ECSService{
+				Sequence: 2,
+				Deployments: []ECSDeployment{
+					{ID: "ecs-svc/1", Status: "ACTIVE", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1},
+					{ID: "ecs-svc/2", Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"},
+				},
+			},
+			wanted: ECSServiceDelta{
+				Sequence:     2,
+				BaseSequence: 1,
+				Deployments: []ECSDeployment{
+					{ID: "ecs-svc/1", Status: "ACTIVE", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1},
+					{ID: "ecs-svc/2", Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 2, RolloutState: "COMPLETED"},
+				},
+				CountChanges: []ECSCountChange{
+					{TaskDefRevision: "3", Count: "desired", From: 0, To: 2, DeploymentID: "ecs-svc/1"},
+					{TaskDefRevision: "3", Count: "running", From: 0, To: 1, DeploymentID: "ecs-svc/1"},
+				},
+			},
+		},
+		"passes the fields describing the service as a whole": {
+			prev: ECSService{Sequence: 1, Deployments: []ECSDeployment{primary}},
+			curr: ECSService{
+				Sequence:              2,
+				Deployments:           []ECSDeployment{primary},
+				TaskPlacement:         []ECSTaskPlacement{{AvailabilityZone: "us-west-2a", RunningCount: 1}},
+				EventStaleness:        time.Minute,
+				OtherDeploymentsCount: 1,
+				FailureCategoryCounts: map[ECSFailureCategory]int{ECSFailureCategoryApplication: 2},
+				WaitingFor:            "ecs-svc/1",
+				CorrelationID:         "deploy-1234",
+				Draining:              []ECSDrainProgress{{TaskDefRevision: "2", FromCount: 2, RunningCount: 1}},
+			},
+			wanted: ECSServiceDelta{
+				Sequence:              2,
+				BaseSequence:          1,
+				TaskPlacement:         []ECSTaskPlacement{{AvailabilityZone: "us-west-2a", RunningCount: 1}},
+				EventStaleness:        time.Minute,
+				OtherDeploymentsCount: 1,
+				FailureCategoryCounts: map[ECSFailureCategory]int{ECSFailureCategoryApplication: 2},
+				WaitingFor:            "ecs-svc/1",
+				CorrelationID:         "deploy-1234",
+				Draining:              []ECSDrainProgress{{TaskDefRevision: "2", FromCount: 2, RunningCount: 1}},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, tc.curr.DeltaSince(tc.prev))
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_SubscribeDeltas(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+	deltas := streamer.SubscribeDeltas()
+	inProgress := ECSService{Deployments: []ECSDeployment{{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 1, RolloutState: "IN_PROGRESS"}}}
+	streamer.eventsToFlush = []ECSService{inProgress, inProgress}
+
+	// WHEN
+	done := make(chan struct{})
+	go func() {
+		streamer.Notify()
+		streamer.Close()
+		close(done)
+	}()
+	var got []ECSServiceDelta
+	for delta := range deltas {
+		got = append(got, delta)
+	}
+	<-done
+
+	// THEN
+	require.Equal(t, []ECSServiceDelta{
+		{
+			Sequence:           1,
+			Deployments:        inProgress.Deployments,
+			CountChanges:       []ECSCountChange{{TaskDefRevision: "3", Count: "desired", From: 0, To: 1}},
+			RolloutTransitions: []ECSRolloutTransition{{TaskDefRevision: "3", From: "", To: "IN_PROGRESS"}},
+		},
+		{
+			Sequence:     2,
+			BaseSequence: 1,
+		},
+	}, got)
+}
@@ -0,0 +1,254 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+)
+
+const (
+	ecsMaxServicesPerDescribe = 10 // Maximum number of services that can be described with a single DescribeServices call.
+
+	defaultECSBatchWindow = 500 * time.Millisecond // How long to wait for other services to describe in the same batch.
+)
+
+// ECSServicesDescriber is the ECS interface needed to describe multiple services at once.
+type ECSServicesDescriber interface {
+	DescribeServices(*awsecs.DescribeServicesInput) (*awsecs.DescribeServicesOutput, error)
+}
+
+// ECSServiceBatchDescriber is an ECSServiceDescriber that can be shared by multiple ECSDeploymentStreamers.
+// Service descriptions requested for the same cluster within a short window are coalesced into
+// DescribeServices calls of up to 10 services each, reducing the number of API calls made by concurrent streamers.
+type ECSServiceBatchDescriber struct {
+	client ECSServicesDescriber
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*ecsServicesBatch // Batches that are still accepting services by cluster name.
+}
+
+// ecsServicesBatch holds the services to describe together in a cluster, and the results once done is closed.
+type ecsServicesBatch struct {
+	names []string // Names or ARNs of the services, as passed by the callers.
+	done  chan struct{}
+
+	outs []*awsecs.DescribeServicesOutput // Output of each DescribeServices call, for each chunk of up to 10 names.
+	errs []error                          // Error of each DescribeServices call.
+}
+
+// NewECSServiceBatchDescriber creates an ECSServiceBatchDescriber from an ECS client.
+func NewECSServiceBatchDescriber(client ECSServicesDescriber) *ECSServiceBatchDescriber {
+	return &ECSServiceBatchDescriber{
+		client:  client,
+		window:  defaultECSBatchWindow,
+		pending: make(map[string]*ecsServicesBatch),
+	}
+}
+
+// Service returns the description of the service, given by its name or ARN, in the cluster once the batch it belongs to
+// is described. If the service is reported as missing, the returned error matches ecs.ErrServiceNotFound.
+// If a DescribeServices call fails, only the services described by that call get the error.
+func (d *ECSServiceBatchDescriber) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	d.mu.Lock()
+	batch, ok := d.pending[clusterName]
+	if !ok {
+		batch = &ecsServicesBatch{
+			done: make(chan struct{}),
+		}
+		d.pending[clusterName] = batch
+		time.AfterFunc(d.window, func() {
+			d.describe(clusterName, batch)
+		})
+	}
+	batch.add(serviceName)
+	d.mu.Unlock()
+
+	<-batch.done
+	return batch.result(serviceName)
+}
+
+// describe stops accepting new services in the batch and describes all of them.
+func (d *ECSServiceBatchDescriber) describe(clusterName string, batch *ecsServicesBatch) {
+	d.mu.Lock()
+	delete(d.pending, clusterName) // Subsequent requests start a new batch.
+	d.mu.Unlock()
+
+	defer close(batch.done)
+	for start := 0; start < len(batch.names); start += ecsMaxServicesPerDescribe {
+		end := start + ecsMaxServicesPerDescribe
+		if end > len(batch.names) {
+			end = len(batch.names)
+		}
+		out, err := d.client.DescribeServices(&awsecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterName),
+			Services: aws.StringSlice(batch.names[start:end]),
+		})
+		if err != nil {
+			err = fmt.Errorf("describe services %s: %w", strings.Join(batch.names[start:end], ", "), err)
+		}
+		batch.outs = append(batch.outs, out)
+		batch.errs = append(batch.errs, err)
+	}
+}
+
+func (b *ecsServicesBatch) add(serviceName string) {
+	for _, name := range b.names {
+		if name == serviceName {
+			return
+		}
+	}
+	b.names = append(b.names, serviceName)
+}
+
+func (b *ecsServicesBatch) result(serviceName string) (*ecs.Service, error) {
+	for i, name := range b.names {
+		if name != serviceName {
+			continue
+		}
+		chunk := i / ecsMaxServicesPerDescribe
+		if b.errs[chunk] != nil {
+			return nil, b.errs[chunk]
+		}
+		return ecs.ServiceFromOutput(b.outs[chunk], serviceName)
+	}
+	return nil, fmt.Errorf("cannot find service %s", serviceName)
+}
+
+// ECSTTLCachingDescriber is an ECSServiceDescriber that reuses the description of a service for a time to live,
+// so that consumers polling faster than the service meaningfully changes don't each trigger an API call.
+// Concurrent requests for a service that isn't cached share a single description. Errors are shared by the concurrent
+// requests but not cached. The cached description is shared by all callers and must not be mutated.
+type ECSTTLCachingDescriber struct {
+	describer ECSServiceDescriber
+	ttl       time.Duration
+
+	now func() time.Time // Overridden in tests.
+
+	mu      sync.Mutex
+	entries map[string]*ttlCacheEntry // Cached or in-flight descriptions by cluster and service name.
+}
+
+// ttlCacheEntry holds the description of a service once done is closed.
+type ttlCacheEntry struct {
+	done chan struct{}
+
+	service   *ecs.Service
+	err       error
+	expiresAt time.Time // Zero until the description is retrieved.
+}
+
+// NewECSTTLCachingDescriber creates an ECSTTLCachingDescriber that caches the descriptions returned by describer for ttl.
+func NewECSTTLCachingDescriber(describer ECSServiceDescriber, ttl time.Duration) *ECSTTLCachingDescriber {
+	return &ECSTTLCachingDescriber{
+		describer: describer,
+		ttl:       ttl,
+		now:       time.Now,
+		entries:   make(map[string]*ttlCacheEntry),
+	}
+}
+
+// Service returns the cached description of the service in the cluster until it expires,
+// then describes the service again. Expired descriptions of any service are evicted.
+func (d *ECSTTLCachingDescriber) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	key := clusterName + "/" + serviceName
+	d.mu.Lock()
+	d.evictExpired(d.now())
+	entry, ok := d.entries[key]
+	if !ok {
+		entry = &ttlCacheEntry{
+			done: make(chan struct{}),
+		}
+		d.entries[key] = entry
+	}
+	d.mu.Unlock()
+
+	if !ok {
+		d.describe(key, entry, clusterName, serviceName)
+	}
+	<-entry.done
+	return entry.service, entry.err
+}
+
+// describe retrieves the description of the entry and caches it unless it's an error.
+func (d *ECSTTLCachingDescriber) describe(key string, entry *ttlCacheEntry, clusterName, serviceName string) {
+	defer close(entry.done)
+	service, err := d.describer.Service(clusterName, serviceName)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry.service, entry.err = service, err
+	if err != nil {
+		delete(d.entries, key) // The next request describes the service again.
+		return
+	}
+	entry.expiresAt = d.now().Add(d.ttl)
+}
+
+// evictExpired removes the descriptions that expired at now. It must be called with the lock held.
+func (d *ECSTTLCachingDescriber) evictExpired(now time.Time) {
+	for key, entry := range d.entries {
+		if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// ECSServicesRequestDescriber is the ECS interface needed to describe services with request options.
+type ECSServicesRequestDescriber interface {
+	DescribeServicesWithContext(aws.Context, *awsecs.DescribeServicesInput, ...request.Option) (*awsecs.DescribeServicesOutput, error)
+}
+
+// ECSRequestIDDescriber is an ECSServiceDescriber that records the AWS request ID of its last DescribeServices call,
+// so that the calls of a stuck deployment can be correlated with AWS-side logs when escalating to AWS support.
+// A streamer using it reports the request ID with LastRequestID and in its DebugState.
+type ECSRequestIDDescriber struct {
+	client ECSServicesRequestDescriber
+
+	mu            sync.Mutex
+	lastRequestID string
+}
+
+// NewECSRequestIDDescriber creates an ECSRequestIDDescriber from an ECS client.
+func NewECSRequestIDDescriber(client ECSServicesRequestDescriber) *ECSRequestIDDescriber {
+	return &ECSRequestIDDescriber{
+		client: client,
+	}
+}
+
+// Service describes the service in the cluster and records the ID of the request, including when it fails.
+// If the service is reported as missing, the returned error matches ecs.ErrServiceNotFound.
+func (d *ECSRequestIDDescriber) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	out, err := d.client.DescribeServicesWithContext(aws.BackgroundContext(), &awsecs.DescribeServicesInput{
+		Cluster:  aws.String(clusterName),
+		Services: aws.StringSlice([]string{serviceName}),
+	}, d.recordRequestID)
+	if err != nil {
+		return nil, fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	return ecs.ServiceFromOutput(out, serviceName)
+}
+
+// LastRequestID returns the AWS request ID of the last DescribeServices call, or an empty string before the first call.
+func (d *ECSRequestIDDescriber) LastRequestID() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lastRequestID
+}
+
+// recordRequestID is a request.Option that records the request ID once the request completes.
+func (d *ECSRequestIDDescriber) recordRequestID(r *request.Request) {
+	r.Handlers.Complete.PushBack(func(r *request.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.lastRequestID = r.RequestID
+	})
+}
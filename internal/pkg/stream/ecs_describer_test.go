@@ -0,0 +1,411 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+// mockECSServicesClient records the DescribeServices calls and describes every requested service, by name or ARN,
+// except the missing ones. The calls that include a failing service return err.
+type mockECSServicesClient struct {
+	missing map[string]bool
+	failing map[string]bool
+	err     error
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (m *mockECSServicesClient) DescribeServices(in *awsecs.DescribeServicesInput) (*awsecs.DescribeServicesOutput, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, aws.StringValueSlice(in.Services))
+	m.mu.Unlock()
+	for _, service := range aws.StringValueSlice(in.Services) {
+		if m.err != nil && (m.failing == nil || m.failing[service]) {
+			return nil, m.err
+		}
+	}
+	out := &awsecs.DescribeServicesOutput{}
+	for _, service := range aws.StringValueSlice(in.Services) {
+		name := service[strings.LastIndex(service, "/")+1:]
+		arn := fmt.Sprintf("arn:aws:ecs:us-west-2:1111:service/%s/%s", aws.StringValue(in.Cluster), name)
+		if m.missing[name] {
+			out.Failures = append(out.Failures, &awsecs.Failure{
+				Arn:    aws.String(arn),
+				Reason: aws.String("MISSING"),
+			})
+			continue
+		}
+		out.Services = append(out.Services, &awsecs.Service{
+			ServiceName: aws.String(name),
+			ServiceArn:  aws.String(arn),
+		})
+	}
+	return out, nil
+}
+
+func describeConcurrently(d *ECSServiceBatchDescriber, cluster string, services []string) ([]*ecs.Service, []error) {
+	var wg sync.WaitGroup
+	out := make([]*ecs.Service, len(services))
+	errs := make([]error, len(services))
+	for i, name := range services {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			out[i], errs[i] = d.Service(cluster, name)
+		}(i, name)
+	}
+	wg.Wait()
+	return out, errs
+}
+
+func TestECSServiceBatchDescriber_Service(t *testing.T) {
+	t.Run("coalesces concurrent descriptions of a cluster into a single call", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+
+		// WHEN
+		out, errs := describeConcurrently(d, "my-cluster", []string{"api", "web", "api"})
+
+		// THEN
+		require.Len(t, client.calls, 1)
+		require.ElementsMatch(t, []string{"api", "web"}, client.calls[0])
+		for i, name := range []string{"api", "web", "api"} {
+			require.NoError(t, errs[i])
+			require.Equal(t, name, aws.StringValue(out[i].ServiceName))
+		}
+	})
+	t.Run("describes at most 10 services per call", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+		var services []string
+		for i := 0; i < 12; i++ {
+			services = append(services, fmt.Sprintf("svc-%d", i))
+		}
+
+		// WHEN
+		_, errs := describeConcurrently(d, "my-cluster", services)
+
+		// THEN
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		require.Len(t, client.calls, 2)
+		require.Len(t, client.calls[0], 10)
+		require.Len(t, client.calls[1], 2)
+	})
+	t.Run("maps missing services to ErrServiceNotFound", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{
+			missing: map[string]bool{"web": true},
+		}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+
+		// WHEN
+		_, errs := describeConcurrently(d, "my-cluster", []string{"api", "web"})
+
+		// THEN
+		require.NoError(t, errs[0])
+		require.True(t, errors.Is(errs[1], ecs.ErrServiceNotFound))
+	})
+	t.Run("returns the results by the name or ARN passed by the caller", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{
+			missing: map[string]bool{"web": true},
+		}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+		services := []string{
+			"api",
+			"arn:aws:ecs:us-west-2:1111:service/my-cluster/api",
+			"arn:aws:ecs:us-west-2:1111:service/my-cluster/web",
+		}
+
+		// WHEN
+		out, errs := describeConcurrently(d, "my-cluster", services)
+
+		// THEN
+		require.Len(t, client.calls, 1)
+		require.NoError(t, errs[0])
+		require.Equal(t, "api", aws.StringValue(out[0].ServiceName))
+		require.NoError(t, errs[1])
+		require.Equal(t, "api", aws.StringValue(out[1].ServiceName))
+		require.True(t, errors.Is(errs[2], ecs.ErrServiceNotFound))
+	})
+	t.Run("returns the describe error only to the services of the failed call", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{
+			failing: map[string]bool{"svc-0": true},
+			err:     errors.New("some error"),
+		}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+		var services []string
+		for i := 0; i < 12; i++ {
+			services = append(services, fmt.Sprintf("svc-%d", i))
+		}
+
+		// WHEN
+		_, errs := describeConcurrently(d, "my-cluster", services)
+
+		// THEN
+		require.Len(t, client.calls, 2)
+		failed := make(map[string]bool) // Services described by the call that includes svc-0.
+		for _, call := range client.calls {
+			includesFailing := false
+			for _, name := range call {
+				includesFailing = includesFailing || name == "svc-0"
+			}
+			for _, name := range call {
+				failed[name] = includesFailing
+			}
+		}
+		for i, name := range services {
+			if failed[name] {
+				require.Error(t, errs[i], "service %s was described by the failed call", name)
+				continue
+			}
+			require.NoError(t, errs[i], "service %s was described by the successful call", name)
+		}
+	})
+	t.Run("returns the describe error to every service of the failed call", func(t *testing.T) {
+		// GIVEN
+		client := &mockECSServicesClient{
+			err: errors.New("some error"),
+		}
+		d := NewECSServiceBatchDescriber(client)
+		d.window = 50 * time.Millisecond
+
+		// WHEN
+		_, errs := describeConcurrently(d, "my-cluster", []string{"api"})
+
+		// THEN
+		require.EqualError(t, errs[0], "describe services api: some error")
+	})
+	t.Run("works unchanged with an ECSDeploymentStreamer", func(t *testing.T) {
+		// GIVEN
+		d := NewECSServiceBatchDescriber(&mockECSServicesClient{})
+		d.window = time.Millisecond
+		streamer := NewECSDeploymentStreamer(d, "my-cluster", "api", time.Now())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, streamer.eventsToFlush, 1)
+	})
+}
+
+// blockingECS counts the descriptions of services, which only return once release is closed.
+type blockingECS struct {
+	release chan struct{}
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (m *blockingECS) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+	<-m.release
+	return &ecs.Service{}, nil
+}
+
+func TestECSTTLCachingDescriber_Service(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	t.Run("reuses the description until it expires", func(t *testing.T) {
+		// GIVEN
+		first, second := &ecs.Service{ServiceName: aws.String("first")}, &ecs.Service{ServiceName: aws.String("second")}
+		m := &mockECSSequence{outs: []*ecs.Service{first, second}}
+		d := NewECSTTLCachingDescriber(m, 10*time.Second)
+		now := startDate
+		d.now = func() time.Time { return now }
+
+		// WHEN
+		var got []*ecs.Service
+		for _, at := range []time.Duration{0, 5 * time.Second, 10 * time.Second} {
+			now = startDate.Add(at)
+			out, err := d.Service("my-cluster", "my-svc")
+			require.NoError(t, err)
+			got = append(got, out)
+		}
+
+		// THEN
+		require.Equal(t, []*ecs.Service{first, first, second}, got)
+	})
+	t.Run("caches each service separately", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSSequence{outs: []*ecs.Service{{ServiceName: aws.String("first")}, {ServiceName: aws.String("second")}}}
+		d := NewECSTTLCachingDescriber(m, time.Minute)
+
+		// WHEN
+		first, err := d.Service("my-cluster", "my-svc")
+		require.NoError(t, err)
+		second, err := d.Service("my-cluster", "my-other-svc")
+		require.NoError(t, err)
+
+		// THEN
+		require.Equal(t, "first", aws.StringValue(first.ServiceName))
+		require.Equal(t, "second", aws.StringValue(second.ServiceName))
+	})
+	t.Run("evicts the expired descriptions", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{out: &ecs.Service{}}
+		d := NewECSTTLCachingDescriber(m, 10*time.Second)
+		now := startDate
+		d.now = func() time.Time { return now }
+		for i := 0; i < 5; i++ {
+			_, err := d.Service("my-cluster", fmt.Sprintf("svc-%d", i))
+			require.NoError(t, err)
+		}
+
+		// WHEN
+		now = now.Add(10 * time.Second)
+		_, err := d.Service("my-cluster", "my-svc")
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, d.entries, 1)
+	})
+	t.Run("describes the service once for concurrent requests", func(t *testing.T) {
+		// GIVEN
+		m := &blockingECS{release: make(chan struct{})}
+		d := NewECSTTLCachingDescriber(m, time.Minute)
+
+		// WHEN
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := d.Service("my-cluster", "my-svc")
+				require.NoError(t, err)
+			}()
+		}
+		time.Sleep(50 * time.Millisecond) // Let the requests pile up behind the first description.
+		close(m.release)
+		wg.Wait()
+
+		// THEN
+		require.Equal(t, 1, m.calls)
+	})
+	t.Run("does not cache errors", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{err: errors.New("some error")}
+		d := NewECSTTLCachingDescriber(m, time.Minute)
+		_, err := d.Service("my-cluster", "my-svc")
+		require.EqualError(t, err, "some error")
+		m.err, m.out = nil, &ecs.Service{ServiceName: aws.String("my-svc")}
+
+		// WHEN
+		out, err := d.Service("my-cluster", "my-svc")
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "my-svc", aws.StringValue(out.ServiceName))
+	})
+}
+
+// mockECSServicesRequestClient runs the request options of DescribeServicesWithContext on a request with the given ID.
+type mockECSServicesRequestClient struct {
+	mockECSServicesClient
+	requestID string
+}
+
+func (m *mockECSServicesRequestClient) DescribeServicesWithContext(ctx aws.Context, in *awsecs.DescribeServicesInput, opts ...request.Option) (*awsecs.DescribeServicesOutput, error) {
+	r := &request.Request{RequestID: m.requestID}
+	r.ApplyOptions(opts...)
+	out, err := m.DescribeServices(in)
+	r.Error = err
+	r.Handlers.Complete.Run(r)
+	return out, err
+}
+
+func TestECSRequestIDDescriber_Service(t *testing.T) {
+	t.Run("records the request ID of the last call", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSServicesRequestClient{requestID: "1234"}
+		d := NewECSRequestIDDescriber(m)
+		require.Empty(t, d.LastRequestID())
+
+		// WHEN
+		out, err := d.Service("my-cluster", "my-svc")
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "my-svc", aws.StringValue(out.ServiceName))
+		require.Equal(t, "1234", d.LastRequestID())
+	})
+	t.Run("records the request ID of a failed call", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSServicesRequestClient{
+			mockECSServicesClient: mockECSServicesClient{err: errors.New("some error")},
+			requestID:             "5678",
+		}
+		d := NewECSRequestIDDescriber(m)
+
+		// WHEN
+		_, err := d.Service("my-cluster", "my-svc")
+
+		// THEN
+		require.EqualError(t, err, "describe service my-svc: some error")
+		require.Equal(t, "5678", d.LastRequestID())
+	})
+	t.Run("returns an error matching ErrServiceNotFound if the service is missing", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSServicesRequestClient{
+			mockECSServicesClient: mockECSServicesClient{missing: map[string]bool{"my-svc": true}},
+		}
+		d := NewECSRequestIDDescriber(m)
+
+		// WHEN
+		_, err := d.Service("my-cluster", "my-svc")
+
+		// THEN
+		require.True(t, errors.Is(err, ecs.ErrServiceNotFound))
+	})
+	t.Run("describes the service by ARN", func(t *testing.T) {
+		// GIVEN
+		d := NewECSRequestIDDescriber(&mockECSServicesRequestClient{requestID: "1234"})
+
+		// WHEN
+		out, err := d.Service("my-cluster", "arn:aws:ecs:us-west-2:1111:service/my-cluster/my-svc")
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "my-svc", aws.StringValue(out.ServiceName))
+	})
+	t.Run("is reported by the streamer using it", func(t *testing.T) {
+		// GIVEN
+		d := NewECSRequestIDDescriber(&mockECSServicesRequestClient{requestID: "1234"})
+		streamer := NewECSDeploymentStreamer(d, "my-cluster", "my-svc", time.Now())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "1234", streamer.LastRequestID())
+		require.Equal(t, "1234", streamer.DebugState().LastRequestID)
+	})
+}
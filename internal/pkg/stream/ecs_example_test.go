@@ -0,0 +1,89 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/stream"
+)
+
+// This example streams a deployment against a custom endpoint, such as LocalStack, for hermetic integration tests.
+func ExampleNewECSDeploymentStreamer_customEndpoint() {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("test", "test", ""),
+	}))
+	describer := ecs.NewWithEndpoint(sess, "http://localhost:4566")
+
+	streamer := stream.NewECSDeploymentStreamer(describer, "my-cluster", "my-svc", time.Now())
+	events := streamer.Subscribe()
+	go func() {
+		for ev := range events {
+			for _, deployment := range ev.Deployments {
+				fmt.Printf("%s: %d/%d running\n", deployment.Status, deployment.RunningCount, deployment.DesiredCount)
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := stream.Stream(ctx, streamer); err != nil {
+		fmt.Printf("stream deployment: %v\n", err)
+	}
+}
+
+// roleDescriberFactory creates describers that assume a role of the same name in each account.
+type roleDescriberFactory struct {
+	sess     *session.Session
+	roleName string
+}
+
+func (f roleDescriberFactory) DescriberForAccount(accountID, region string) (stream.ECSServiceDescriber, error) {
+	roleARN := fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, f.roleName)
+	sess, err := session.NewSession(f.sess.Config.Copy(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: stscreds.NewCredentials(f.sess, roleARN),
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("create session for role %s: %w", roleARN, err)
+	}
+	return ecs.New(sess), nil
+}
+
+// This example streams a deployment of a service to an environment that spans several accounts,
+// assuming a role in each account to describe the service.
+func ExampleNewECSMultiAccountStreamer() {
+	factory := roleDescriberFactory{
+		sess:     session.Must(session.NewSession()),
+		roleName: "my-app-deploy-role",
+	}
+	streamer, err := stream.NewECSMultiAccountStreamer(factory, []stream.ECSServiceLocation{
+		{AccountID: "111111111111", Region: "us-west-2", Cluster: "my-cluster", Service: "my-svc"},
+		{AccountID: "222222222222", Region: "eu-west-1", Cluster: "my-cluster", Service: "my-svc"},
+	}, time.Now(), nil, stream.WithMaxConcurrentFetches(2))
+	if err != nil {
+		fmt.Printf("create streamer: %v\n", err)
+		return
+	}
+	events := streamer.Subscribe()
+	go func() {
+		for ev := range events {
+			fmt.Printf("%d/%d running\n", ev.RunningCount, ev.DesiredCount)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := stream.Stream(ctx, streamer); err != nil {
+		fmt.Printf("stream deployment: %v\n", err)
+	}
+}
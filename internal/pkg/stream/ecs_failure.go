@@ -0,0 +1,408 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ECSFailureCategory is the kind of problem reported by a failure service event.
+type ECSFailureCategory string
+
+// Categories of failure service events.
+const (
+	ECSFailureCategoryUnknown     ECSFailureCategory = "unknown"
+	ECSFailureCategoryNetworking  ECSFailureCategory = "networking"
+	ECSFailureCategoryApplication ECSFailureCategory = "application"
+
+	// ECSFailureCategoryNetworkProvisioning reports that the network interface of an awsvpc task, such as a Fargate task,
+	// could not be provisioned or attached, for example because its subnet ran out of IP addresses.
+	ECSFailureCategoryNetworkProvisioning ECSFailureCategory = "network_provisioning"
+
+	// ECSFailureCategoryMissingResource reports that a resource needed to start the task doesn't exist,
+	// such as the container image, a secret or a parameter referenced by the task definition.
+	ECSFailureCategoryMissingResource ECSFailureCategory = "missing_resource"
+
+	// ECSFailureCategorySecrets reports that the task couldn't be initialized, usually because it couldn't fetch
+	// its secrets from Secrets Manager or SSM Parameter Store due to missing permissions or a missing secret.
+	ECSFailureCategorySecrets ECSFailureCategory = "secrets"
+
+	// ECSFailureCategoryImagePull reports that the container image couldn't be pulled from its registry,
+	// see ECSServiceFailure.ImagePullReason for why.
+	ECSFailureCategoryImagePull ECSFailureCategory = "image_pull"
+
+	// ECSFailureCategoryHealthCheck reports that ECS stopped tasks that were still failing their load balancer health
+	// checks once the health check grace period of the service expired.
+	ECSFailureCategoryHealthCheck ECSFailureCategory = "health_check"
+
+	// ECSFailureCategoryIAM reports that ECS couldn't assume the task execution role or the task role, usually because
+	// the trust policy of the role doesn't allow ecs-tasks.amazonaws.com to assume it, see ECSServiceFailure.RoleARN.
+	ECSFailureCategoryIAM ECSFailureCategory = "iam"
+)
+
+// ECSServiceFailure is a failure service event along with its classification.
+type ECSServiceFailure struct {
+	Message  string             `json:"message"`
+	Category ECSFailureCategory `json:"category"`
+
+	// ExitCode is the exit code of the crashed container for application failures, nil if not reported in the message.
+	ExitCode *int `json:"exitCode,omitempty"`
+
+	// SubnetIPExhausted is true for network provisioning failures caused by a subnet without free IP addresses,
+	// which can be fixed by using a larger subnet.
+	SubnetIPExhausted bool `json:"subnetIPExhausted,omitempty"`
+
+	// ResourceShortfall is the resource that no container instance had enough of to place the task,
+	// empty if the failure isn't about a CPU or memory placement constraint.
+	ResourceShortfall ECSResourceShortfall `json:"resourceShortfall,omitempty"`
+
+	// SecretName is the ARN or name of the secret or parameter that the task couldn't fetch for secrets failures,
+	// empty if not reported in the message.
+	SecretName string `json:"secretName,omitempty"`
+
+	// Image is the reference of the image that couldn't be pulled for image pull failures, and ImagePullReason
+	// is why, both empty if not reported in the message.
+	Image           string             `json:"image,omitempty"`
+	ImagePullReason ECSImagePullReason `json:"imagePullReason,omitempty"`
+
+	// TargetGroup is the ARN or name of the target group whose health checks the task failed for health check failures,
+	// empty if not reported in the message.
+	TargetGroup string `json:"targetGroup,omitempty"`
+
+	// RoleARN is the ARN of the role that ECS couldn't assume for IAM failures, empty if not reported in the message.
+	RoleARN string `json:"roleARN,omitempty"`
+
+	// Cause is the short reason of the specific failure that most likely caused a summary failure, such as
+	// "deployment failed: tasks failed to start", for example "CannotPullContainerError", and CauseMessage is
+	// the message of that failure. Both are empty for other failures, or if no specific failure was reported shortly before.
+	Cause        string `json:"cause,omitempty"`
+	CauseMessage string `json:"causeMessage,omitempty"`
+}
+
+// ECSImagePullReason is the reason why a container image couldn't be pulled.
+type ECSImagePullReason string
+
+// Reasons of image pull failures.
+const (
+	// ECSImagePullReasonAuth reports that the registry denied the pull, for example because the task execution role
+	// isn't allowed to pull from the ECR repository.
+	ECSImagePullReasonAuth ECSImagePullReason = "auth"
+	// ECSImagePullReasonNotFound reports that the repository or the tag of the image doesn't exist.
+	ECSImagePullReasonNotFound ECSImagePullReason = "not_found"
+	// ECSImagePullReasonThrottled reports that the registry rate limited the pull, such as the Docker Hub pull rate limit.
+	ECSImagePullReasonThrottled ECSImagePullReason = "throttled"
+)
+
+// ECSResourceShortfall is a resource of the container instances that is insufficient to place a task.
+type ECSResourceShortfall string
+
+// Resources whose placement constraint can fail.
+const (
+	ECSResourceShortfallCPU    ECSResourceShortfall = "cpu"
+	ECSResourceShortfallMemory ECSResourceShortfall = "memory"
+)
+
+// ECSRolloutFailureCause is the reason why ECS failed a deployment, parsed from the deployment's rollout state reason.
+type ECSRolloutFailureCause string
+
+// Causes of a failed rollout.
+const (
+	ECSRolloutFailureCauseUnknown        ECSRolloutFailureCause = "unknown"
+	ECSRolloutFailureCauseCircuitBreaker ECSRolloutFailureCause = "circuit_breaker"
+	ECSRolloutFailureCauseAlarm          ECSRolloutFailureCause = "alarm"
+	ECSRolloutFailureCauseTimeout        ECSRolloutFailureCause = "timeout"
+)
+
+// ecsRolloutFailureCauses are evaluated in order, the first pattern that matches a rollout state reason determines its cause.
+var ecsRolloutFailureCauses = []struct {
+	cause   ECSRolloutFailureCause
+	pattern *regexp.Regexp
+}{
+	// For example: "ECS deployment timed out." or "deployment failed: timeout waiting for the service to reach steady state".
+	{ECSRolloutFailureCauseTimeout, regexp.MustCompile(`(?i)timed out|timeout`)},
+	// For example: "ECS deployment circuit breaker: tasks failed to start."
+	{ECSRolloutFailureCauseCircuitBreaker, regexp.MustCompile(`(?i)circuit breaker`)},
+	// For example: "Failed due to the following CloudWatch alarms: my-alarm."
+	{ECSRolloutFailureCauseAlarm, regexp.MustCompile(`(?i)alarm`)},
+}
+
+// parseRolloutFailureCause returns the cause of a failed rollout from its rollout state reason.
+func parseRolloutFailureCause(reason string) ECSRolloutFailureCause {
+	for _, c := range ecsRolloutFailureCauses {
+		if c.pattern.MatchString(reason) {
+			return c.cause
+		}
+	}
+	return ECSRolloutFailureCauseUnknown
+}
+
+var ecsEventFailureKeywords = []string{"fail", "unhealthy", "error", "throttle", "unable"}
+
+// ecsBroadMissingKeyword is an additional failure keyword with WithMissingKeyword. On its own, it also matches
+// benign service events, so missing resources are otherwise only reported through ecsMissingResourcePattern.
+const ecsBroadMissingKeyword = "missing"
+
+// ecsMissingResourcePattern matches failures caused by a resource that the task needs but doesn't exist.
+// For example: "(service my-svc) failed to launch a task: missing image 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc"
+// or "(service my-svc) (task 1234) stopped: secret arn:aws:secretsmanager:us-west-2:1111:secret:db is missing".
+var ecsMissingResourcePattern = regexp.MustCompile(`(?i)\bmissing (ecr |container |the )?(image|secrets?|parameters?)\b|\b(image|secrets?|parameters?)( \S+)? (is|are|was|were) missing\b`)
+
+// ecsSecretNamePattern matches the secret or parameter that a task couldn't fetch.
+// For example: "failed to fetch secret arn:aws:secretsmanager:us-west-2:1111:secret:db-AbCdEf from secrets manager"
+// or "invalid ssm parameters: /my-app/test/db-url".
+var ecsSecretNamePattern = regexp.MustCompile(`(?i)(arn:aws[\w-]*:(?:secretsmanager|ssm):\S+)|invalid ssm parameters?: ?(\S+)`)
+
+// ecsImagePattern matches the reference of an image that couldn't be pulled.
+// For example: "failed to resolve ref 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: not found",
+// "pull access denied for my-svc, repository does not exist" or "manifest for nginx:nonexistent not found".
+var ecsImagePattern = regexp.MustCompile(`(?i)failed to resolve (?:ref|reference) "?([^\s"]+?)"?:\s|pull access denied for ([^\s,]+)|manifest for (\S+) not found`)
+
+// ecsImagePullReasons are evaluated in order, the first pattern that matches an image pull failure determines its reason.
+// Docker reports a missing repository as "pull access denied [...] repository does not exist", so not found comes before auth.
+var ecsImagePullReasons = []struct {
+	reason  ECSImagePullReason
+	pattern *regexp.Regexp
+}{
+	// For example: "toomanyrequests: You have reached your pull rate limit." or "429 Too Many Requests".
+	{ECSImagePullReasonThrottled, regexp.MustCompile(`(?i)toomanyrequests|too many requests|rate limit|\b429\b`)},
+	// For example: "my-svc:abc: not found" or "repository does not exist".
+	{ECSImagePullReasonNotFound, regexp.MustCompile(`(?i)not found|does not exist|manifest unknown|\b404\b`)},
+	// For example: "denied: User is not authorized to perform: ecr:BatchGetImage" or "401 Unauthorized".
+	{ECSImagePullReasonAuth, regexp.MustCompile(`(?i)denied|unauthorized|not authorized|no basic auth credentials|\b40[13]\b`)},
+}
+
+// ecsSpotInterruptionPattern matches service events about Spot tasks stopped to reclaim capacity, which aren't failures.
+// For example: "(service my-svc) has stopped 1 running tasks: (task 1234). Reason: Your Spot Task was interrupted."
+// or "(service my-svc) is rebalancing capacity: (task 1234) received a spot interruption warning".
+var ecsSpotInterruptionPattern = regexp.MustCompile(`(?i)spot (task )?(was )?interrupt|capacity rebalanc|rebalancing capacity`)
+
+// ecsHealthCheckGracePattern matches service events about unhealthy tasks that are still within the health check grace
+// period, which aren't failures.
+// For example: "(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason Request timed out),
+// but (task 1234) is within the health check grace period".
+var ecsHealthCheckGracePattern = regexp.MustCompile(`(?i)(with)?in (the|its) health ?check grace period`)
+
+// ecsTargetGroupPattern matches the target group that reported a failed health check.
+// For example: "Task failed ELB health checks in (target-group arn:aws:elasticloadbalancing:us-west-2:1111:targetgroup/my-tg/1234)"
+// or "Task failed ELB health checks in target-group my-tg".
+var ecsTargetGroupPattern = regexp.MustCompile(`(?i)\(target-group ([^)\s]+)\)|target-group ([\w:/.-]*\w)`)
+
+// ecsRoleARNPattern matches the ARN of the role that ECS couldn't assume, without a trailing quote or period.
+// For example: "ECS was unable to assume the role 'arn:aws:iam::1111:role/my-app-test-my-svc-ExecutionRole' that was provided".
+var ecsRoleARNPattern = regexp.MustCompile(`arn:aws[\w-]*:iam::\d*:role/[\w+=,.@/-]*[\w+=,@-]`)
+
+// ecsInconsistentStartPattern matches the service event that ECS emits when tasks keep failing to start, which usually
+// precedes the deployment circuit breaker rolling the deployment back.
+// For example: "(service my-svc) is unable to consistently start tasks successfully. For more information,
+// see the Troubleshooting section of the Amazon ECS Developer Guide."
+var ecsInconsistentStartPattern = regexp.MustCompile(`(?i)unable to consistently start tasks successfully`)
+
+// ecsSummaryFailurePattern matches failure service events that report that the deployment failed without saying why,
+// the cause being reported by a separate service event about the stopped tasks.
+// For example: "(service my-svc) (deployment ecs-svc/1234) deployment failed: tasks failed to start."
+var ecsSummaryFailurePattern = regexp.MustCompile(`(?i)(deployment failed|circuit breaker):? ?tasks failed to start`)
+
+// ecsFailureCausePattern matches the name of the error reported by a failure, which is its most concise reason.
+// For example: "CannotPullContainerError" or "ResourceInitializationError".
+var ecsFailureCausePattern = regexp.MustCompile(`\b([A-Z]\w*(?:Error|Exception))\b`)
+
+// ecsSubnetIPExhaustedPattern matches network provisioning failures caused by a subnet without free IP addresses.
+// For example: "(service my-svc) was unable to place a task because the subnet subnet-1234 has insufficient free addresses".
+var ecsSubnetIPExhaustedPattern = regexp.MustCompile(`(?i)insufficient ?free ?addresses|not have enough free addresses|no (more |available )?(free )?ip addresses`)
+
+// ecsResourceShortfallPattern matches placement failures caused by container instances without enough CPU or memory.
+// For example: "(service my-svc) was unable to place a task because no container instance met all of its requirements.
+// The closest matching (container-instance 1234) has insufficient memory available." or "Reasons: ["RESOURCE:CPU"]".
+var ecsResourceShortfallPattern = regexp.MustCompile(`(?i)resource:(cpu|memory)\b|insufficient (cpu|memory)\b`)
+
+var ecsExitCodePattern = regexp.MustCompile(`(?i)exit code:? ?(\d+)`)
+
+// ecsFailureClassifiers are evaluated in order, the first pattern that matches a message determines its category.
+// If set, detail parses additional information about the failure from the message.
+var ecsFailureClassifiers = []struct {
+	category ECSFailureCategory
+	pattern  *regexp.Regexp
+	detail   func(msg string, failure *ECSServiceFailure)
+}{
+	// For example: "(service my-svc) was unable to place a task. Reason: unable to attach network interface to (task 1234)."
+	// or "(service my-svc) failed to launch a task with (error ENI provisioning failed: insufficient free addresses)".
+	{ECSFailureCategoryNetworkProvisioning, regexp.MustCompile(`(?i)(attach|provision)\w* (the )?(elastic )?(network interface|eni)|eni (attachment|provisioning)|` + ecsSubnetIPExhaustedPattern.String()), parseSubnetIPExhausted},
+	// For example: "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)"
+	// or "(service my-svc) was unable to deregister targets in (target-group 1234)".
+	{ECSFailureCategoryNetworking, regexp.MustCompile(`(?i)(fail(ed)?|unable) to (de)?register targets`), nil},
+	// For example: "(service my-svc) service discovery instance registration failed for (task 1234)"
+	// or "(service my-svc) failed to register (instance 1234) in (service discovery service srv-1234)".
+	{ECSFailureCategoryNetworking, regexp.MustCompile(`(?i)service discovery.*registration.*fail|(fail(ed)?|unable) to register.*service discovery`), nil},
+	// For example: "(service my-svc) failed to launch a task with (error ECS was unable to assume the role
+	// 'arn:aws:iam::1111:role/my-exec-role' that was provided for this task. Please verify that the role being passed
+	// has the proper trust relationship and permissions and that your IAM user has permissions to pass this role.)".
+	{ECSFailureCategoryIAM, regexp.MustCompile(`(?i)unable to assume (the )?(task (execution )?)?role|not authorized to (perform: )?sts:AssumeRole`), parseRoleARN},
+	// For example: "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 5 time(s):
+	// failed to resolve ref 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: not found".
+	{ECSFailureCategoryImagePull, regexp.MustCompile(`(?i)CannotPullContainerError`), parseImagePull},
+	// For example: "(service my-svc) (task 1234) stopped: ResourceInitializationError: unable to pull secrets or registry auth:
+	// execution resource retrieval failed: unable to retrieve secrets from ssm: invalid ssm parameters: /my-app/test/db-url".
+	{ECSFailureCategorySecrets, regexp.MustCompile(`(?i)ResourceInitializationError|unable to (pull|retrieve) secrets?|invalid ssm parameters?`), parseSecretName},
+	// For example: "(service my-svc) failed to launch a task: missing image my-svc:abc", see ecsMissingResourcePattern.
+	{ECSFailureCategoryMissingResource, ecsMissingResourcePattern, nil},
+	// For example: "(service my-svc) (task 1234) stopped: Task failed ELB health checks in (target-group 1234)."
+	// or "(service my-svc) health check grace period expired, stopping unhealthy (task 1234)".
+	{ECSFailureCategoryHealthCheck, regexp.MustCompile(`(?i)failed (elb|load balancer) health ?checks?|health ?check grace period (has )?expired`), parseTargetGroup},
+	// For example: "(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)"
+	// or "(service my-svc) task 1234 stopped with error: CannotStartContainerError".
+	{ECSFailureCategoryApplication, regexp.MustCompile(`(?i)essential container.* exited|task.*stopped.*(error|exit code)`), parseExitCode},
+}
+
+// parseFailureServiceEvent returns the classified failure if the service event message reports a failure.
+// Messages that are not classified are still failures if they contain one of the keywords.
+func parseFailureServiceEvent(msg string, keywords []string) (ECSServiceFailure, bool) {
+	for _, classifier := range ecsFailureClassifiers {
+		if !classifier.pattern.MatchString(msg) {
+			continue
+		}
+		failure := ECSServiceFailure{
+			Message:  msg,
+			Category: classifier.category,
+		}
+		if classifier.detail != nil {
+			classifier.detail(msg, &failure)
+		}
+		parseResourceShortfall(msg, &failure)
+		return failure, true
+	}
+	if !isFailureServiceEvent(msg, keywords) {
+		return ECSServiceFailure{}, false
+	}
+	failure := ECSServiceFailure{
+		Message:  msg,
+		Category: ECSFailureCategoryUnknown,
+	}
+	parseResourceShortfall(msg, &failure)
+	return failure, true
+}
+
+// isSpotInterruptionServiceEvent returns true if the service event message reports a Spot interruption.
+func isSpotInterruptionServiceEvent(msg string) bool {
+	return ecsSpotInterruptionPattern.MatchString(msg)
+}
+
+// isHealthCheckGraceServiceEvent returns true if the service event message reports unhealthy tasks within the health
+// check grace period.
+func isHealthCheckGraceServiceEvent(msg string) bool {
+	return ecsHealthCheckGracePattern.MatchString(msg)
+}
+
+// isInconsistentStartServiceEvent returns true if the service event message reports that tasks can't consistently start.
+func isInconsistentStartServiceEvent(msg string) bool {
+	return ecsInconsistentStartPattern.MatchString(msg)
+}
+
+// isSummaryFailureServiceEvent returns true if the service event message reports that the deployment failed
+// without its cause.
+func isSummaryFailureServiceEvent(msg string) bool {
+	return ecsSummaryFailurePattern.MatchString(msg)
+}
+
+// failureCause returns the short reason of a specific failure: the name of the error it reports, the exit code of
+// the container for application failures, or its category otherwise.
+func failureCause(failure ECSServiceFailure) string {
+	if match := ecsFailureCausePattern.FindStringSubmatch(failure.Message); match != nil {
+		return match[1]
+	}
+	if failure.ExitCode != nil {
+		return fmt.Sprintf("exit code %d", *failure.ExitCode)
+	}
+	return string(failure.Category)
+}
+
+func isFailureServiceEvent(msg string, keywords []string) bool {
+	for _, kw := range keywords {
+		if strings.Contains(msg, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTargetGroup sets the target group of the failure if its message names one.
+func parseTargetGroup(msg string, failure *ECSServiceFailure) {
+	match := ecsTargetGroupPattern.FindStringSubmatch(msg)
+	if match == nil {
+		return
+	}
+	failure.TargetGroup = match[1]
+	if failure.TargetGroup == "" {
+		failure.TargetGroup = match[2]
+	}
+}
+
+// parseRoleARN sets the role that ECS couldn't assume if the message names it.
+func parseRoleARN(msg string, failure *ECSServiceFailure) {
+	failure.RoleARN = ecsRoleARNPattern.FindString(msg)
+}
+
+// parseSubnetIPExhausted flags the failure if its message reports a subnet without free IP addresses.
+func parseSubnetIPExhausted(msg string, failure *ECSServiceFailure) {
+	failure.SubnetIPExhausted = ecsSubnetIPExhaustedPattern.MatchString(msg)
+}
+
+// parseResourceShortfall sets the resource that is short if the message reports a CPU or memory placement constraint failure.
+func parseResourceShortfall(msg string, failure *ECSServiceFailure) {
+	match := ecsResourceShortfallPattern.FindStringSubmatch(msg)
+	if match == nil {
+		return
+	}
+	resource := match[1]
+	if resource == "" {
+		resource = match[2]
+	}
+	failure.ResourceShortfall = ECSResourceShortfall(strings.ToLower(resource))
+}
+
+// parseImagePull sets the image that couldn't be pulled and the reason why if they're present in the message.
+func parseImagePull(msg string, failure *ECSServiceFailure) {
+	if match := ecsImagePattern.FindStringSubmatch(msg); match != nil {
+		for _, image := range match[1:] {
+			if image != "" {
+				failure.Image = image
+				break
+			}
+		}
+	}
+	for _, r := range ecsImagePullReasons {
+		if r.pattern.MatchString(msg) {
+			failure.ImagePullReason = r.reason
+			return
+		}
+	}
+}
+
+// parseSecretName sets the secret or parameter that the task couldn't fetch if it's present in the message.
+func parseSecretName(msg string, failure *ECSServiceFailure) {
+	match := ecsSecretNamePattern.FindStringSubmatch(msg)
+	if match == nil {
+		return
+	}
+	name := match[1]
+	if name == "" {
+		name = match[2]
+	}
+	failure.SecretName = strings.TrimRight(name, ".,;)")
+}
+
+// parseExitCode sets the exit code of the failure if it's present in the message.
+func parseExitCode(msg string, failure *ECSServiceFailure) {
+	match := ecsExitCodePattern.FindStringSubmatch(msg)
+	if match == nil {
+		return
+	}
+	code, err := strconv.Atoi(match[1])
+	if err != nil {
+		return
+	}
+	failure.ExitCode = &code
+}
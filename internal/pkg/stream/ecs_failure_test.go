@@ -0,0 +1,434 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFailureServiceEvent(t *testing.T) {
+	testCases := map[string]struct {
+		msg      string
+		keywords []string // Defaults to ecsEventFailureKeywords.
+
+		wantedCategory  ECSFailureCategory
+		wantedFailure   bool
+		wantedExitCode  *int
+		wantedSubnetIP  bool
+		wantedShortfall ECSResourceShortfall
+		wantedSecret    string
+		wantedImage     string
+		wantedPull      ECSImagePullReason
+		wantedTarget    string
+		wantedRole      string
+	}{
+		"target group registration failure": {
+			msg:            "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)",
+			wantedCategory: ECSFailureCategoryNetworking,
+			wantedFailure:  true,
+		},
+		"target group deregistration failure": {
+			msg:            "(service my-svc) was unable to deregister targets in (target-group 1234).",
+			wantedCategory: ECSFailureCategoryNetworking,
+			wantedFailure:  true,
+		},
+		"service discovery registration failure": {
+			msg:            "(service my-svc) service discovery instance registration failed for (task 1234).",
+			wantedCategory: ECSFailureCategoryNetworking,
+			wantedFailure:  true,
+		},
+		"service discovery registration failure without a failure keyword": {
+			msg:            "(service my-svc) Failed to register (instance 1234) in (service discovery service srv-1234).",
+			wantedCategory: ECSFailureCategoryNetworking,
+			wantedFailure:  true,
+		},
+		"task execution role that ECS can't assume": {
+			msg:            "(service my-svc) failed to launch a task with (error ECS was unable to assume the role 'arn:aws:iam::1111:role/my-app-test-my-svc-ExecutionRole' that was provided for this task. Please verify that the role being passed has the proper trust relationship and permissions and that your IAM user has permissions to pass this role.).",
+			wantedCategory: ECSFailureCategoryIAM,
+			wantedFailure:  true,
+			wantedRole:     "arn:aws:iam::1111:role/my-app-test-my-svc-ExecutionRole",
+		},
+		"task role with a path that ECS can't assume": {
+			msg:            "(service my-svc) (task 1234) stopped: ECS was unable to assume the task role arn:aws:iam::1111:role/service-role/my-task-role.",
+			wantedCategory: ECSFailureCategoryIAM,
+			wantedFailure:  true,
+			wantedRole:     "arn:aws:iam::1111:role/service-role/my-task-role",
+		},
+		"role that ECS is not authorized to assume in another partition": {
+			msg:            "(service my-svc) failed to launch a task: ecs-tasks.amazonaws.com is not authorized to perform: sts:AssumeRole on resource: arn:aws-cn:iam::1111:role/my-role",
+			wantedCategory: ECSFailureCategoryIAM,
+			wantedFailure:  true,
+			wantedRole:     "arn:aws-cn:iam::1111:role/my-role",
+		},
+		"role that ECS can't assume without an ARN": {
+			msg:            "(service my-svc) was unable to place a task because ECS was unable to assume the role.",
+			wantedCategory: ECSFailureCategoryIAM,
+			wantedFailure:  true,
+		},
+		"task stopped after failing elb health checks": {
+			msg:            "(service my-svc) (task 1234) stopped: Task failed ELB health checks in (target-group arn:aws:elasticloadbalancing:us-west-2:1111:targetgroup/my-tg/1234).",
+			wantedCategory: ECSFailureCategoryHealthCheck,
+			wantedFailure:  true,
+			wantedTarget:   "arn:aws:elasticloadbalancing:us-west-2:1111:targetgroup/my-tg/1234",
+		},
+		"task failed elb health checks in a target group named without parentheses": {
+			msg:            "(service my-svc) (task 1234) stopped: Task failed ELB health checks in target-group my-tg.",
+			wantedCategory: ECSFailureCategoryHealthCheck,
+			wantedFailure:  true,
+			wantedTarget:   "my-tg",
+		},
+		"task failed load balancer health checks without a target group": {
+			msg:            "(service my-svc) (task 1234) stopped: Task failed load balancer health checks.",
+			wantedCategory: ECSFailureCategoryHealthCheck,
+			wantedFailure:  true,
+		},
+		"health check grace period expired": {
+			msg:            "(service my-svc) health check grace period expired, stopping unhealthy (task 1234).",
+			wantedCategory: ECSFailureCategoryHealthCheck,
+			wantedFailure:  true,
+		},
+		"essential container exited with an exit code": {
+			msg:            "(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 137)",
+			wantedCategory: ECSFailureCategoryApplication,
+			wantedFailure:  true,
+			wantedExitCode: aws.Int(137),
+		},
+		"essential container exited without an exit code": {
+			msg:            "(service my-svc) Essential container in task exited.",
+			wantedCategory: ECSFailureCategoryApplication,
+			wantedFailure:  true,
+		},
+		"task stopped with an error": {
+			msg:            "(service my-svc) task 1234 stopped with error: CannotStartContainerError",
+			wantedCategory: ECSFailureCategoryApplication,
+			wantedFailure:  true,
+		},
+		"network interface attachment failure": {
+			msg:            "(service my-svc) was unable to place a task. Reason: unable to attach network interface to (task 1234).",
+			wantedCategory: ECSFailureCategoryNetworkProvisioning,
+			wantedFailure:  true,
+		},
+		"eni provisioning failure": {
+			msg:            "(service my-svc) failed to launch a task with (error ENI provisioning failed).",
+			wantedCategory: ECSFailureCategoryNetworkProvisioning,
+			wantedFailure:  true,
+		},
+		"subnet without free ip addresses": {
+			msg:            "(service my-svc) was unable to place a task because the subnet subnet-1234 has insufficient free addresses to place the task.",
+			wantedCategory: ECSFailureCategoryNetworkProvisioning,
+			wantedFailure:  true,
+			wantedSubnetIP: true,
+		},
+		"subnet ip exhaustion error code": {
+			msg:            "(service my-svc) failed to launch a task with (error InsufficientFreeAddressesInSubnet).",
+			wantedCategory: ECSFailureCategoryNetworkProvisioning,
+			wantedFailure:  true,
+			wantedSubnetIP: true,
+		},
+		"missing image": {
+			msg:            "(service my-svc) failed to launch a task: missing image 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc.",
+			wantedCategory: ECSFailureCategoryMissingResource,
+			wantedFailure:  true,
+		},
+		"missing secret": {
+			msg:            "(service my-svc) (task 1234) stopped: secret arn:aws:secretsmanager:us-west-2:1111:secret:db is missing.",
+			wantedCategory: ECSFailureCategoryMissingResource,
+			wantedFailure:  true,
+		},
+		"missing parameters": {
+			msg:            "(service my-svc) was unable to start a task: missing parameters /my-app/test/db-url.",
+			wantedCategory: ECSFailureCategoryMissingResource,
+			wantedFailure:  true,
+		},
+		"benign missing occurrence": {
+			msg: "(service my-svc) has started 1 tasks: (task 1234). Placing the task in the missing availability zone us-west-2c.",
+		},
+		"benign missing occurrence with the broad missing keyword": {
+			msg:            "(service my-svc) has started 1 tasks: (task 1234). Placing the task in the missing availability zone us-west-2c.",
+			keywords:       append(ecsEventFailureKeywords[:len(ecsEventFailureKeywords):len(ecsEventFailureKeywords)], ecsBroadMissingKeyword),
+			wantedCategory: ECSFailureCategoryUnknown,
+			wantedFailure:  true,
+		},
+		"successful target registration": {
+			msg: "(service my-svc) registered 1 targets in (target-group 1234)",
+		},
+		"image not found": {
+			msg: "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 5 time(s): " +
+				"failed to resolve ref 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: not found",
+			wantedCategory: ECSFailureCategoryImagePull,
+			wantedFailure:  true,
+			wantedImage:    "1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc",
+			wantedPull:     ECSImagePullReasonNotFound,
+		},
+		"repository does not exist": {
+			msg: "(service my-svc) (task 1234) stopped: CannotPullContainerError: Error response from daemon: " +
+				"pull access denied for my-svc, repository does not exist or may require 'docker login'",
+			wantedCategory: ECSFailureCategoryImagePull,
+			wantedFailure:  true,
+			wantedImage:    "my-svc",
+			wantedPull:     ECSImagePullReasonNotFound,
+		},
+		"image pull access denied": {
+			msg: "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 5 time(s): " +
+				"failed to resolve ref 1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc: pulling from host 1111.dkr.ecr.us-west-2.amazonaws.com " +
+				"failed with status code [manifests abc]: 403 Forbidden",
+			wantedCategory: ECSFailureCategoryImagePull,
+			wantedFailure:  true,
+			wantedImage:    "1111.dkr.ecr.us-west-2.amazonaws.com/my-svc:abc",
+			wantedPull:     ECSImagePullReasonAuth,
+		},
+		"image pull unauthorized": {
+			msg: "(service my-svc) (task 1234) stopped: CannotPullContainerError: Error response from daemon: " +
+				"Get https://1111.dkr.ecr.us-west-2.amazonaws.com/v2/my-svc/manifests/abc: no basic auth credentials",
+			wantedCategory: ECSFailureCategoryImagePull,
+			wantedFailure:  true,
+			wantedPull:     ECSImagePullReasonAuth,
+		},
+		"image pull throttled": {
+			msg: "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 1 time(s): " +
+				"failed to resolve ref docker.io/library/nginx:latest: unexpected status from HEAD request to " +
+				"https://registry-1.docker.io/v2/library/nginx/manifests/latest: 429 Too Many Requests",
+			wantedCategory: ECSFailureCategoryImagePull,
+			wantedFailure:  true,
+			wantedImage:    "docker.io/library/nginx:latest",
+			wantedPull:     ECSImagePullReasonThrottled,
+		},
+		"secrets manager access denied": {
+			msg: "(service my-svc) (task 1234) stopped: ResourceInitializationError: unable to pull secrets or registry auth: " +
+				"execution resource retrieval failed: unable to retrieve secret from asm: service call has been retried 1 time(s): " +
+				"failed to fetch secret arn:aws:secretsmanager:us-west-2:1111:secret:db-AbCdEf from secrets manager: " +
+				"AccessDeniedException: User is not authorized to perform: secretsmanager:GetSecretValue.",
+			wantedCategory: ECSFailureCategorySecrets,
+			wantedFailure:  true,
+			wantedSecret:   "arn:aws:secretsmanager:us-west-2:1111:secret:db-AbCdEf",
+		},
+		"invalid ssm parameter": {
+			msg: "(service my-svc) (task 1234) stopped: ResourceInitializationError: unable to pull secrets or registry auth: " +
+				"execution resource retrieval failed: unable to retrieve secrets from ssm: invalid ssm parameters: /my-app/test/db-url.",
+			wantedCategory: ECSFailureCategorySecrets,
+			wantedFailure:  true,
+			wantedSecret:   "/my-app/test/db-url",
+		},
+		"ssm parameter access denied": {
+			msg: "(service my-svc) (task 1234) stopped: ResourceInitializationError: unable to pull secrets or registry auth: " +
+				"execution resource retrieval failed: unable to retrieve secrets from ssm: AccessDeniedException: " +
+				"not authorized to perform: ssm:GetParameters on resource: arn:aws:ssm:us-west-2:1111:parameter/my-app/test/db-url",
+			wantedCategory: ECSFailureCategorySecrets,
+			wantedFailure:  true,
+			wantedSecret:   "arn:aws:ssm:us-west-2:1111:parameter/my-app/test/db-url",
+		},
+		"resource initialization error without a secret": {
+			msg:            "(service my-svc) (task 1234) stopped: ResourceInitializationError: failed to validate logger args: signal: killed",
+			wantedCategory: ECSFailureCategorySecrets,
+			wantedFailure:  true,
+		},
+		"insufficient memory": {
+			msg: "(service my-svc) was unable to place a task because no container instance met all of its requirements. " +
+				"The closest matching (container-instance 1234) has insufficient memory available.",
+			wantedCategory:  ECSFailureCategoryUnknown,
+			wantedFailure:   true,
+			wantedShortfall: ECSResourceShortfallMemory,
+		},
+		"insufficient cpu": {
+			msg: "(service my-svc) was unable to place a task because no container instance met all of its requirements. " +
+				"The closest matching (container-instance 1234) has insufficient CPU units available.",
+			wantedCategory:  ECSFailureCategoryUnknown,
+			wantedFailure:   true,
+			wantedShortfall: ECSResourceShortfallCPU,
+		},
+		"memory placement constraint": {
+			msg:             `(service my-svc) was unable to place a task. Reasons: ["RESOURCE:MEMORY"].`,
+			wantedCategory:  ECSFailureCategoryUnknown,
+			wantedFailure:   true,
+			wantedShortfall: ECSResourceShortfallMemory,
+		},
+		"cpu placement constraint": {
+			msg:             `(service my-svc) was unable to place a task. Reasons: ["RESOURCE:CPU"].`,
+			wantedCategory:  ECSFailureCategoryUnknown,
+			wantedFailure:   true,
+			wantedShortfall: ECSResourceShortfallCPU,
+		},
+		"unclassified failure": {
+			msg:            "(service my-svc) was unable to place a task.",
+			wantedCategory: ECSFailureCategoryUnknown,
+			wantedFailure:  true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			keywords := tc.keywords
+			if keywords == nil {
+				keywords = ecsEventFailureKeywords
+			}
+
+			// WHEN
+			failure, ok := parseFailureServiceEvent(tc.msg, keywords)
+
+			// THEN
+			require.Equal(t, tc.wantedFailure, ok)
+			if !tc.wantedFailure {
+				return
+			}
+			require.Equal(t, tc.msg, failure.Message)
+			require.Equal(t, tc.wantedCategory, failure.Category)
+			require.Equal(t, tc.wantedExitCode, failure.ExitCode)
+			require.Equal(t, tc.wantedSubnetIP, failure.SubnetIPExhausted)
+			require.Equal(t, tc.wantedShortfall, failure.ResourceShortfall)
+			require.Equal(t, tc.wantedSecret, failure.SecretName)
+			require.Equal(t, tc.wantedImage, failure.Image)
+			require.Equal(t, tc.wantedPull, failure.ImagePullReason)
+			require.Equal(t, tc.wantedTarget, failure.TargetGroup)
+			require.Equal(t, tc.wantedRole, failure.RoleARN)
+		})
+	}
+}
+
+func TestIsSpotInterruptionServiceEvent(t *testing.T) {
+	testCases := map[string]struct {
+		msg string
+
+		wanted bool
+	}{
+		"fargate spot interruption": {
+			msg:    "(service my-svc) has stopped 1 running tasks: (task 1234). Reason: Your Spot Task was interrupted.",
+			wanted: true,
+		},
+		"ec2 spot interruption": {
+			msg:    "(service my-svc) has stopped 2 running tasks due to a Spot interruption of (container-instance 1234).",
+			wanted: true,
+		},
+		"capacity rebalancing": {
+			msg:    "(service my-svc) is rebalancing capacity: (task 1234) received a rebalance recommendation.",
+			wanted: true,
+		},
+		"stopped task": {
+			msg: "(service my-svc) has stopped 1 running tasks: (task 1234).",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, isSpotInterruptionServiceEvent(tc.msg))
+		})
+	}
+}
+
+func TestIsHealthCheckGraceServiceEvent(t *testing.T) {
+	testCases := map[string]struct {
+		msg string
+
+		wanted bool
+	}{
+		"unhealthy target within the grace period": {
+			msg:    "(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason Request timed out), but (task 1234) is within the health check grace period.",
+			wanted: true,
+		},
+		"task in its grace period": {
+			msg:    "(service my-svc) (task 1234) is in its healthcheck grace period.",
+			wanted: true,
+		},
+		"grace period expired": {
+			msg: "(service my-svc) health check grace period expired, stopping unhealthy (task 1234).",
+		},
+		"unhealthy target": {
+			msg: "(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason Health checks failed).",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, isHealthCheckGraceServiceEvent(tc.msg))
+		})
+	}
+}
+
+func TestIsInconsistentStartServiceEvent(t *testing.T) {
+	testCases := map[string]struct {
+		msg string
+
+		wanted bool
+	}{
+		"unable to consistently start tasks": {
+			msg:    "(service my-svc) is unable to consistently start tasks successfully. For more information, see the Troubleshooting section of the Amazon ECS Developer Guide.",
+			wanted: true,
+		},
+		"unable to place a task": {
+			msg: "(service my-svc) was unable to place a task.",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, isInconsistentStartServiceEvent(tc.msg))
+		})
+	}
+}
+
+func TestFailureCause(t *testing.T) {
+	exitCode := 137
+	testCases := map[string]struct {
+		failure ECSServiceFailure
+
+		wanted string
+	}{
+		"name of the error": {
+			failure: ECSServiceFailure{
+				Message:  "(service my-svc) (task 1234) stopped: ResourceInitializationError: unable to pull secrets or registry auth",
+				Category: ECSFailureCategorySecrets,
+			},
+			wanted: "ResourceInitializationError",
+		},
+		"exit code of the container": {
+			failure: ECSServiceFailure{
+				Message:  "(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 137)",
+				Category: ECSFailureCategoryApplication,
+				ExitCode: &exitCode,
+			},
+			wanted: "exit code 137",
+		},
+		"category of the failure": {
+			failure: ECSServiceFailure{
+				Message:  "(service my-svc) (task 1234) stopped: Task failed ELB health checks in (target-group 1234).",
+				Category: ECSFailureCategoryHealthCheck,
+			},
+			wanted: "health_check",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, failureCause(tc.failure))
+		})
+	}
+}
+
+func TestParseRolloutFailureCause(t *testing.T) {
+	testCases := map[string]struct {
+		reason string
+
+		wanted ECSRolloutFailureCause
+	}{
+		"deployment timeout": {
+			reason: "ECS deployment timed out.",
+			wanted: ECSRolloutFailureCauseTimeout,
+		},
+		"circuit breaker": {
+			reason: "ECS deployment circuit breaker: tasks failed to start.",
+			wanted: ECSRolloutFailureCauseCircuitBreaker,
+		},
+		"alarm": {
+			reason: "Failed due to the following CloudWatch alarms: my-alarm.",
+			wanted: ECSRolloutFailureCauseAlarm,
+		},
+		"unknown reason": {
+			reason: "ECS deployment failed.",
+			wanted: ECSRolloutFailureCauseUnknown,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, parseRolloutFailureCause(tc.reason))
+		})
+	}
+}
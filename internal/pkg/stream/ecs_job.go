@@ -0,0 +1,221 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+)
+
+// ECSJobTasksDescriber is the interface to find and describe the tasks invoked for a job.
+type ECSJobTasksDescriber interface {
+	RunningTasksInFamily(cluster, family string) ([]*ecs.Task, error)
+	StoppedTasksInFamily(cluster, family string) ([]*ecs.Task, error)
+	DescribeTasks(cluster string, taskARNs []string) ([]*ecs.Task, error)
+}
+
+// ECSJobTask is a description of the task invoked for a job.
+type ECSJobTask struct {
+	TaskARN       string    `json:"taskARN"`
+	LastStatus    string    `json:"lastStatus"`
+	StartedBy     string    `json:"startedBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	StartedAt     time.Time `json:"startedAt"`
+	StoppedAt     time.Time `json:"stoppedAt"`
+	StoppedReason string    `json:"stoppedReason,omitempty"`
+
+	// ExitCode is the first non-zero exit code of the task's containers, or 0 if they all exited successfully.
+	// It is nil until a container of the stopped task reports an exit code.
+	ExitCode *int `json:"exitCode,omitempty"`
+}
+
+// ECSJobStreamer is a Streamer for the most recent task invoked for a job, for example by the job's EventBridge rule.
+// The streamer first discovers the latest task in the job's task definition family created since the invocation time,
+// then watches that task until it is STOPPED.
+type ECSJobStreamer struct {
+	client       ECSJobTasksDescriber
+	cluster      string
+	family       string
+	invokedAfter time.Time
+
+	// Optional configuration.
+	startedByPrefix string
+
+	now func() time.Time // Overridden in tests.
+
+	mu            sync.Mutex // Guards the state below.
+	subscribers   []chan ECSJobTask
+	done          chan struct{}
+	isDone        bool
+	closed        bool
+	taskARN       string // ARN of the invoked task once discovered.
+	eventsToFlush []ECSJobTask
+}
+
+// ECSJobStreamerOpt is an option to configure an ECSJobStreamer.
+type ECSJobStreamerOpt func(*ECSJobStreamer)
+
+// WithJobTaskStartedBy only considers the tasks whose StartedBy starts with prefix as invocations of the job.
+// For example, tasks started by an EventBridge rule named "my-rule" have StartedBy set to "events-rule/my-rule".
+func WithJobTaskStartedBy(prefix string) ECSJobStreamerOpt {
+	return func(s *ECSJobStreamer) {
+		s.startedByPrefix = prefix
+	}
+}
+
+// NewECSJobStreamer creates an ECSJobStreamer for the tasks of the task definition family in the cluster
+// created at or after invokedAfter.
+func NewECSJobStreamer(ecs ECSJobTasksDescriber, cluster, family string, invokedAfter time.Time, opts ...ECSJobStreamerOpt) *ECSJobStreamer {
+	s := &ECSJobStreamer{
+		client:       ecs,
+		cluster:      cluster,
+		family:       family,
+		invokedAfter: invokedAfter,
+		done:         make(chan struct{}),
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Subscribe returns a read-only channel that will receive descriptions of the job's task.
+func (s *ECSJobStreamer) Subscribe() <-chan ECSJobTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(chan ECSJobTask)
+	s.subscribers = append(s.subscribers, c)
+	return c
+}
+
+// Fetch discovers the task invoked for the job if it isn't known yet, and stores its latest description.
+// Nothing is stored until the job is invoked.
+func (s *ECSJobStreamer) Fetch() (next time.Time, err error) {
+	s.mu.Lock()
+	taskARN := s.taskARN
+	s.mu.Unlock()
+
+	var task *ecs.Task
+	if taskARN == "" {
+		task, err = s.latestTask()
+	} else {
+		task, err = s.describeTask(taskARN)
+	}
+	if err != nil {
+		return next, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next = s.now().Add(streamerFetchIntervalDuration)
+	if task == nil {
+		return next, nil
+	}
+	ev := newECSJobTask(task)
+	s.taskARN = ev.TaskARN
+	s.eventsToFlush = append(s.eventsToFlush, ev)
+	if ev.LastStatus == awsecs.DesiredStatusStopped && !s.isDone {
+		s.isDone = true
+		close(s.done)
+	}
+	return next, nil
+}
+
+// Notify flushes all new task descriptions to subscribers.
+func (s *ECSJobStreamer) Notify() {
+	s.mu.Lock()
+	events, subscribers := s.eventsToFlush, s.subscribers
+	s.eventsToFlush = nil
+	s.mu.Unlock()
+
+	for _, event := range events {
+		for _, sub := range subscribers {
+			sub <- event
+		}
+	}
+}
+
+// Close closes all subscribed channels notifying them that no more events will be sent.
+func (s *ECSJobStreamer) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for _, sub := range s.subscribers {
+		close(sub)
+	}
+	s.closed = true
+}
+
+// Done returns a channel that's closed when the job's task is stopped.
+func (s *ECSJobStreamer) Done() <-chan struct{} {
+	return s.done
+}
+
+// latestTask returns the most recently created task invoked for the job, or nil if there is none yet.
+func (s *ECSJobStreamer) latestTask() (*ecs.Task, error) {
+	running, err := s.client.RunningTasksInFamily(s.cluster, s.family)
+	if err != nil {
+		return nil, fmt.Errorf("list running tasks in family %s: %w", s.family, err)
+	}
+	stopped, err := s.client.StoppedTasksInFamily(s.cluster, s.family)
+	if err != nil {
+		return nil, fmt.Errorf("list stopped tasks in family %s: %w", s.family, err)
+	}
+	var latest *ecs.Task
+	for _, task := range append(running, stopped...) {
+		createdAt := aws.TimeValue(task.CreatedAt)
+		if createdAt.Before(s.invokedAfter) {
+			continue
+		}
+		if !strings.HasPrefix(aws.StringValue(task.StartedBy), s.startedByPrefix) {
+			continue
+		}
+		if latest == nil || createdAt.After(aws.TimeValue(latest.CreatedAt)) {
+			latest = task
+		}
+	}
+	return latest, nil
+}
+
+func (s *ECSJobStreamer) describeTask(taskARN string) (*ecs.Task, error) {
+	tasks, err := s.client.DescribeTasks(s.cluster, []string{taskARN})
+	if err != nil {
+		return nil, fmt.Errorf("describe job task %s: %w", taskARN, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("cannot find job task %s", taskARN)
+	}
+	return tasks[0], nil
+}
+
+func newECSJobTask(task *ecs.Task) ECSJobTask {
+	ev := ECSJobTask{
+		TaskARN:       aws.StringValue(task.TaskArn),
+		LastStatus:    aws.StringValue(task.LastStatus),
+		StartedBy:     aws.StringValue(task.StartedBy),
+		CreatedAt:     aws.TimeValue(task.CreatedAt),
+		StartedAt:     aws.TimeValue(task.StartedAt),
+		StoppedAt:     aws.TimeValue(task.StoppedAt),
+		StoppedReason: aws.StringValue(task.StoppedReason),
+	}
+	for _, container := range task.Containers {
+		if container.ExitCode == nil {
+			continue
+		}
+		code := int(aws.Int64Value(container.ExitCode))
+		if ev.ExitCode == nil || (*ev.ExitCode == 0 && code != 0) {
+			ev.ExitCode = &code
+		}
+	}
+	return ev
+}
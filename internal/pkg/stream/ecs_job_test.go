@@ -0,0 +1,135 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+type mockECSJobTasks struct {
+	running   []*ecs.Task
+	stopped   []*ecs.Task
+	described []*ecs.Task
+	err       error
+}
+
+func (m *mockECSJobTasks) RunningTasksInFamily(cluster, family string) ([]*ecs.Task, error) {
+	return m.running, m.err
+}
+
+func (m *mockECSJobTasks) StoppedTasksInFamily(cluster, family string) ([]*ecs.Task, error) {
+	return m.stopped, m.err
+}
+
+func (m *mockECSJobTasks) DescribeTasks(cluster string, taskARNs []string) ([]*ecs.Task, error) {
+	return m.described, m.err
+}
+
+func TestECSJobStreamer_Fetch(t *testing.T) {
+	invokedAt := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	jobTask := func(arn, startedBy, status string, createdAt time.Time) *ecs.Task {
+		return &ecs.Task{
+			TaskArn:    aws.String(arn),
+			StartedBy:  aws.String(startedBy),
+			LastStatus: aws.String(status),
+			CreatedAt:  aws.Time(createdAt),
+		}
+	}
+	t.Run("returns a wrapped error if tasks cannot be listed", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSJobStreamer(&mockECSJobTasks{err: errors.New("some error")}, "my-cluster", "my-job", invokedAt)
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.EqualError(t, err, "list running tasks in family my-job: some error")
+	})
+	t.Run("stores nothing until the job is invoked", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSJobTasks{
+			stopped: []*ecs.Task{jobTask("previous", "events-rule/my-rule", "STOPPED", invokedAt.Add(-time.Hour))},
+		}
+		streamer := NewECSJobStreamer(m, "my-cluster", "my-job", invokedAt)
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Empty(t, streamer.eventsToFlush)
+	})
+	t.Run("discovers the latest task started by the job's rule", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSJobTasks{
+			running: []*ecs.Task{
+				jobTask("latest", "events-rule/my-rule", "RUNNING", invokedAt.Add(2*time.Minute)),
+				jobTask("other", "ecs-svc/1234", "RUNNING", invokedAt.Add(3*time.Minute)),
+			},
+			stopped: []*ecs.Task{jobTask("older", "events-rule/my-rule", "STOPPED", invokedAt.Add(time.Minute))},
+		}
+		streamer := NewECSJobStreamer(m, "my-cluster", "my-job", invokedAt, WithJobTaskStartedBy("events-rule/my-rule"))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, streamer.eventsToFlush, 1)
+		require.Equal(t, "latest", streamer.eventsToFlush[0].TaskARN)
+		require.Equal(t, "latest", streamer.taskARN)
+	})
+	t.Run("streams the discovered task until it stops with its exit code", func(t *testing.T) {
+		// GIVEN
+		stopped := jobTask("latest", "events-rule/my-rule", "STOPPED", invokedAt.Add(2*time.Minute))
+		stopped.StoppedReason = aws.String("Essential container in task exited")
+		stopped.Containers = []*awsecs.Container{
+			{ExitCode: aws.Int64(0)},
+			{ExitCode: aws.Int64(137)},
+		}
+		m := &mockECSJobTasks{
+			running: []*ecs.Task{jobTask("latest", "events-rule/my-rule", "RUNNING", invokedAt.Add(2*time.Minute))},
+		}
+		streamer := NewECSJobStreamer(m, "my-cluster", "my-job", invokedAt)
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		m.running, m.described = nil, []*ecs.Task{stopped}
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		require.Len(t, streamer.eventsToFlush, 2)
+		require.Nil(t, streamer.eventsToFlush[0].ExitCode)
+		last := streamer.eventsToFlush[1]
+		require.Equal(t, "STOPPED", last.LastStatus)
+		require.Equal(t, "Essential container in task exited", last.StoppedReason)
+		require.Equal(t, 137, *last.ExitCode)
+	})
+}
+
+func TestECSJobStreamer_Notify(t *testing.T) {
+	// GIVEN
+	streamer := NewECSJobStreamer(&mockECSJobTasks{}, "my-cluster", "my-job", time.Now())
+	sub := streamer.Subscribe()
+	streamer.eventsToFlush = []ECSJobTask{{TaskARN: "latest"}}
+
+	// WHEN
+	go streamer.Notify()
+
+	// THEN
+	require.Equal(t, ECSJobTask{TaskARN: "latest"}, <-sub)
+	streamer.Close()
+	_, ok := <-sub
+	require.False(t, ok)
+}
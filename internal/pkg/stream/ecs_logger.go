@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+// ECSEventLogger is the interface to write structured log records about a deployment.
+// The arguments following a message are alternating keys and values.
+// It is satisfied by a *slog.Logger, so that applications using log/slog can pass their logger as is.
+type ECSEventLogger interface {
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// WithEventLogger writes a structured record to logger for each meaningful snapshot of the service, new failure,
+// notice and for the completion of the deployment. Transient errors retried by Fetch are logged as warnings,
+// while failed deployments and errors terminating the stream are logged as errors.
+// Unlike WithEventWriter, the records are meant to be consumed by a structured logging pipeline rather than a human.
+func WithEventLogger(logger ECSEventLogger) ECSDeploymentStreamerOpt {
+	return func(s *ECSDeploymentStreamer) {
+		s.logger = logger
+	}
+}
+
+// logEvent writes the records of a snapshot that is meaningful since the previous one.
+func (s *ECSDeploymentStreamer) logEvent(cluster string, ev ECSService, failureReason string, category ECSFailureCategory) {
+	attrs := []interface{}{"cluster", cluster, "service", s.service}
+	if s.correlationID != "" {
+		attrs = append(attrs, "correlationID", s.correlationID)
+	}
+	n := len(attrs) // Number of attributes shared by all the records.
+	if primary, ok := ev.Primary(); ok {
+		attrs = append(attrs,
+			"revision", primary.TaskDefRevision,
+			"desiredCount", primary.DesiredCount,
+			"runningCount", primary.RunningCount,
+			"pendingCount", primary.PendingCount,
+			"failedCount", primary.FailedCount,
+			"rolloutState", primary.RolloutState)
+	}
+	s.logger.Info("service deployment progressed", attrs...)
+	for _, failure := range ev.LatestFailures {
+		s.logger.Warn("service task failed", append(attrs[:n:n], "category", failure.Category, "message", failure.Message)...)
+	}
+	for _, notice := range ev.Notices {
+		log := s.logger.Info
+		if notice.Severity == ECSNoticeWarning {
+			log = s.logger.Warn
+		}
+		log("service deployment notice", append(attrs[:n:n], "kind", notice.Kind, "message", notice.Message)...)
+	}
+	if ev.Completion == nil {
+		return
+	}
+	completed := append(attrs[:n:n], "outcome", ev.Completion.Outcome, "elapsed", ev.Completion.Elapsed)
+	if ev.Completion.Outcome.isSuccessful() {
+		s.logger.Info("service deployment completed", completed...)
+		return
+	}
+	if category == "" {
+		category = ECSFailureCategoryUnknown
+	}
+	s.logger.Error("service deployment completed", append(completed, "reason", failureReason, "category", category)...)
+}
+
+// logFetchError writes a record for an error encountered while fetching the service description.
+func (s *ECSDeploymentStreamer) logFetchError(err error, retried bool) {
+	if s.logger == nil {
+		return
+	}
+	attrs := []interface{}{"service", s.service, "error", err}
+	if s.correlationID != "" {
+		attrs = append(attrs, "correlationID", s.correlationID)
+	}
+	if retried {
+		s.logger.Warn("retry fetching service description", attrs...)
+		return
+	}
+	s.logger.Error("fetch service description", attrs...)
+}
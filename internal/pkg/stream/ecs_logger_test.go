@@ -0,0 +1,155 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+type logRecord struct {
+	level string
+	msg   string
+	args  []interface{}
+}
+
+type mockECSEventLogger struct {
+	records []logRecord
+}
+
+func (m *mockECSEventLogger) Info(msg string, args ...interface{}) {
+	m.records = append(m.records, logRecord{"INFO", msg, args})
+}
+
+func (m *mockECSEventLogger) Warn(msg string, args ...interface{}) {
+	m.records = append(m.records, logRecord{"WARN", msg, args})
+}
+
+func (m *mockECSEventLogger) Error(msg string, args ...interface{}) {
+	m.records = append(m.records, logRecord{"ERROR", msg, args})
+}
+
+func TestECSDeploymentStreamer_FetchEventLogger(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	const placeFailure = "(service my-svc) was unable to place a task because no container instance met all of its requirements."
+	service := func(running int64, rolloutState string, events ...string) *ecs.Service {
+		out := &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:       aws.Int64(2),
+					RunningCount:       aws.Int64(running),
+					Status:             aws.String("PRIMARY"),
+					RolloutState:       aws.String(rolloutState),
+					RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+					TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+		for _, msg := range events {
+			out.Events = append(out.Events, &awsecs.ServiceEvent{
+				Id:        aws.String(msg),
+				Message:   aws.String(msg),
+				CreatedAt: aws.Time(startDate.Add(time.Minute)),
+			})
+		}
+		return out
+	}
+	attrs := []interface{}{"cluster", "my-cluster", "service", "my-svc"}
+	progress := func(running int, rolloutState string) []interface{} {
+		return append(attrs[:4:4], "revision", "2", "desiredCount", 2, "runningCount", running,
+			"pendingCount", 0, "failedCount", 0, "rolloutState", rolloutState)
+	}
+	t.Run("logs meaningful snapshots, failures and the completion of the deployment", func(t *testing.T) {
+		// GIVEN
+		logger := &mockECSEventLogger{}
+		m := &mockECSSequence{outs: []*ecs.Service{
+			service(1, "IN_PROGRESS", placeFailure),
+			service(1, "IN_PROGRESS", placeFailure),
+			service(0, "FAILED", placeFailure),
+		}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithEventLogger(logger))
+		streamer.now = func() time.Time { return startDate.Add(5 * time.Minute) }
+
+		// WHEN
+		for i := 0; i < 3; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// THEN
+		require.Equal(t, []logRecord{
+			{"INFO", "service deployment progressed", progress(1, "IN_PROGRESS")},
+			{"WARN", "service task failed", append(attrs[:4:4], "category", ECSFailureCategoryUnknown, "message", placeFailure)},
+			{"INFO", "service deployment progressed", progress(0, "FAILED")},
+			{"ERROR", "service deployment completed", append(attrs[:4:4], "outcome", ECSDeploymentFailed, "elapsed", 5*time.Minute,
+				"reason", "ECS deployment circuit breaker: tasks failed to start.", "category", ECSFailureCategoryUnknown)},
+		}, logger.records, "the unchanged second snapshot should not be logged")
+	})
+	t.Run("logs the category of the latest classified failure with the completion", func(t *testing.T) {
+		// GIVEN
+		const registerFailure = "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)"
+		logger := &mockECSEventLogger{}
+		m := &mockECS{out: service(0, "FAILED", registerFailure)}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithEventLogger(logger))
+		streamer.now = func() time.Time { return startDate.Add(5 * time.Minute) }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, logRecord{"ERROR", "service deployment completed", append(attrs[:4:4], "outcome", ECSDeploymentFailed,
+			"elapsed", 5*time.Minute, "reason", registerFailure, "category", ECSFailureCategoryNetworking)}, logger.records[len(logger.records)-1])
+	})
+	t.Run("logs transient errors as warnings and errors terminating the stream as errors", func(t *testing.T) {
+		// GIVEN
+		logger := &mockECSEventLogger{}
+		m := &mockECS{err: awserr.New("ThrottlingException", "Rate exceeded", nil)}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithEventLogger(logger))
+
+		// WHEN
+		_, retried := streamer.Fetch()
+		m.err = errors.New("some error")
+		_, fatal := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, retried)
+		require.Error(t, fatal)
+		require.Len(t, logger.records, 2)
+		require.Equal(t, "WARN", logger.records[0].level)
+		require.Equal(t, "ERROR", logger.records[1].level)
+		require.Equal(t, []interface{}{"service", "my-svc", "error", fatal}, logger.records[1].args)
+	})
+	t.Run("stamps the records and descriptions WithCorrelationID", func(t *testing.T) {
+		// GIVEN
+		logger := &mockECSEventLogger{}
+		m := &mockECS{out: service(1, "IN_PROGRESS", placeFailure)}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithEventLogger(logger), WithCorrelationID("deploy-1234"))
+		streamer.now = func() time.Time { return startDate.Add(5 * time.Minute) }
+		sub := streamer.Subscribe()
+
+		// WHEN
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		got := notifyAndCollect(streamer, sub)
+
+		// THEN
+		correlated := []interface{}{"cluster", "my-cluster", "service", "my-svc", "correlationID", "deploy-1234"}
+		require.Equal(t, []logRecord{
+			{"INFO", "service deployment progressed", append(correlated[:6:6], "revision", "2", "desiredCount", 2, "runningCount", 1,
+				"pendingCount", 0, "failedCount", 0, "rolloutState", "IN_PROGRESS")},
+			{"WARN", "service task failed", append(correlated[:6:6], "category", ECSFailureCategoryUnknown, "message", placeFailure)},
+		}, logger.records)
+		require.Len(t, got, 1)
+		require.Equal(t, "deploy-1234", got[0].CorrelationID)
+		require.Contains(t, FormatECSServiceJSON(got[0]), `"correlationID":"deploy-1234"`)
+	})
+}
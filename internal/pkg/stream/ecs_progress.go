@@ -0,0 +1,75 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProgressUpdater is the interface of a progress bar that can be driven by an ECSProgressAdapter.
+type ProgressUpdater interface {
+	SetTotal(total int64)
+	Increment(n int64)
+	SetLabel(label string)
+}
+
+// ECSProgressAdapter translates the snapshots of an ECS service into progress bar updates.
+// The total of the bar is the desired count of the primary deployment and it is incremented with its running count.
+type ECSProgressAdapter struct {
+	bar ProgressUpdater
+
+	total   int64
+	current int64
+	label   string
+}
+
+// NewECSProgressAdapter creates an ECSProgressAdapter that updates bar.
+func NewECSProgressAdapter(bar ProgressUpdater) *ECSProgressAdapter {
+	return &ECSProgressAdapter{
+		bar: bar,
+	}
+}
+
+// Run updates the progress bar with every snapshot received from events until the channel is closed.
+func (a *ECSProgressAdapter) Run(events <-chan ECSService) {
+	for ev := range events {
+		a.update(ev)
+	}
+}
+
+// update moves the progress bar to the running count of the primary deployment and refreshes its label.
+// The bar is incremented by a negative amount if the running count drops.
+func (a *ECSProgressAdapter) update(ev ECSService) {
+	primary, ok := ev.Primary()
+	if ok {
+		if total := int64(primary.DesiredCount); total != a.total {
+			a.total = total
+			a.bar.SetTotal(total)
+		}
+		if current := int64(primary.RunningCount); current != a.current {
+			a.bar.Increment(current - a.current)
+			a.current = current
+		}
+	}
+	if label := progressLabel(ev, primary, ok); label != a.label {
+		a.label = label
+		a.bar.SetLabel(label)
+	}
+}
+
+// progressLabel describes the phase of the deployment, or its latest failure.
+func progressLabel(ev ECSService, primary ECSDeployment, hasPrimary bool) string {
+	if ev.Completion != nil {
+		return fmt.Sprintf("deployment %s", strings.ToLower(string(ev.Completion.Outcome)))
+	}
+	if len(ev.LatestFailureEvents) > 0 { // Failure events are sorted from newest to oldest.
+		return ev.LatestFailureEvents[0]
+	}
+	if !hasPrimary {
+		return "waiting for the deployment to start"
+	}
+	return fmt.Sprintf("revision %s: %d/%d running, %d pending",
+		primary.TaskDefRevision, primary.RunningCount, primary.DesiredCount, primary.PendingCount)
+}
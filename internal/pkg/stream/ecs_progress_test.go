@@ -0,0 +1,104 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockProgressUpdater records the updates made to a progress bar.
+type mockProgressUpdater struct {
+	calls []string
+}
+
+func (m *mockProgressUpdater) SetTotal(total int64) {
+	m.calls = append(m.calls, fmt.Sprintf("total %d", total))
+}
+
+func (m *mockProgressUpdater) Increment(n int64) {
+	m.calls = append(m.calls, fmt.Sprintf("increment %d", n))
+}
+
+func (m *mockProgressUpdater) SetLabel(label string) {
+	m.calls = append(m.calls, fmt.Sprintf("label %s", label))
+}
+
+func TestECSProgressAdapter_Run(t *testing.T) {
+	// GIVEN
+	bar := &mockProgressUpdater{}
+	adapter := NewECSProgressAdapter(bar)
+	primary := func(running, pending int) ECSDeployment {
+		return ECSDeployment{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: running, PendingCount: pending}
+	}
+	events := make(chan ECSService, 5)
+	events <- ECSService{}
+	events <- ECSService{Deployments: []ECSDeployment{primary(1, 1)}}
+	events <- ECSService{
+		Deployments:         []ECSDeployment{primary(0, 1)},
+		LatestFailureEvents: []string{"(service my-svc) failed to launch a task."},
+	}
+	events <- ECSService{Deployments: []ECSDeployment{primary(1, 1)}}
+	events <- ECSService{
+		Deployments: []ECSDeployment{primary(2, 0)},
+		Completion:  &ECSDeploymentCompletion{Outcome: ECSDeploymentSucceeded},
+	}
+	close(events)
+
+	// WHEN
+	adapter.Run(events)
+
+	// THEN
+	require.Equal(t, []string{
+		"label waiting for the deployment to start",
+		"total 2",
+		"increment 1",
+		"label revision 3: 1/2 running, 1 pending",
+		"increment -1",
+		"label (service my-svc) failed to launch a task.",
+		"increment 1",
+		"label revision 3: 1/2 running, 1 pending",
+		"increment 1",
+		"label deployment succeeded",
+	}, bar.calls)
+}
+
+func TestProgressLabel(t *testing.T) {
+	primary := ECSDeployment{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1, PendingCount: 1}
+	testCases := map[string]struct {
+		ev ECSService
+
+		wanted string
+	}{
+		"latest of several failures": {
+			ev: ECSService{
+				Deployments: []ECSDeployment{primary},
+				LatestFailureEvents: []string{
+					"(service my-svc) (task 5678) stopped: Essential container in task exited (exit code: 1)",
+					"(service my-svc) failed to launch a task.",
+				},
+			},
+			wanted: "(service my-svc) (task 5678) stopped: Essential container in task exited (exit code: 1)",
+		},
+		"counts of the primary deployment": {
+			ev:     ECSService{Deployments: []ECSDeployment{primary}},
+			wanted: "revision 3: 1/2 running, 1 pending",
+		},
+		"completion": {
+			ev: ECSService{
+				LatestFailureEvents: []string{"(service my-svc) failed to launch a task."},
+				Completion:          &ECSDeploymentCompletion{Outcome: ECSDeploymentFailed},
+			},
+			wanted: "deployment failed",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			p, ok := tc.ev.Primary()
+			require.Equal(t, tc.wanted, progressLabel(tc.ev, p, ok))
+		})
+	}
+}
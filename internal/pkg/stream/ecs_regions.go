@@ -0,0 +1,478 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultECSMaxConcurrentFetches = 5 // Maximum number of regions fetched at once if not overridden.
+
+// ECSRegionalService is the latest description of a service in one of the regions it is deployed to.
+type ECSRegionalService struct {
+	Region  string     `json:"region"`
+	Service ECSService `json:"service"`
+
+	// Location is where the service is watched. Its account ID is only set with NewECSMultiAccountStreamer.
+	Location ECSServiceLocation `json:"location"`
+
+	// Outcome of the deployment in the region, empty while it is in progress.
+	Outcome ECSDeploymentOutcome `json:"outcome,omitempty"`
+	// FailureReason explains why the deployment failed in the region, see ECSDeploymentStreamer.FailureReason.
+	FailureReason string `json:"failureReason,omitempty"`
+	// FetchError is the error that stopped the streamer from fetching the service in the region, in which case
+	// the region counts as failed while the other regions keep being streamed.
+	FetchError string `json:"fetchError,omitempty"`
+
+	// Progress is the fraction of the desired tasks of the primary deployment that are running, between 0 and 1.
+	// It is 1 once the deployment succeeded or had no changes, and 0 if the primary deployment has no desired tasks yet.
+	Progress float64 `json:"progress"`
+}
+
+// ECSMultiRegionCompletion describes how a deployment across multiple regions ended.
+type ECSMultiRegionCompletion struct {
+	// Outcome is ECSDeploymentSucceeded if the deployment succeeded or had no changes in every region,
+	// and ECSDeploymentFailed otherwise.
+	Outcome       ECSDeploymentOutcome `json:"outcome"`
+	FailedRegions []string             `json:"failedRegions,omitempty"` // Distinct regions with a failed location.
+
+	// FailedLocations are the locations where the deployment did not succeed, such as the services of an environment
+	// watched with NewECSMultiAccountStreamer that failed.
+	FailedLocations []ECSServiceLocation `json:"failedLocations,omitempty"`
+}
+
+// ECSServiceProgress is the progress of a service across the regions it is deployed to.
+type ECSServiceProgress struct {
+	Service string `json:"service"`
+
+	// DesiredCount and RunningCount are the sums of the counts of the primary deployments of the service across regions.
+	DesiredCount int `json:"desiredCount"`
+	RunningCount int `json:"runningCount"`
+
+	// Progress is the average progress of the service in each region weighted by its desired count, between 0 and 1.
+	Progress float64 `json:"progress"`
+}
+
+// ECSMultiRegionService is a combined description of the same logical service deployed to multiple regions.
+type ECSMultiRegionService struct {
+	Regions []ECSRegionalService `json:"regions"` // Sorted by region name, or by location with NewECSMultiAccountStreamer.
+
+	// DesiredCount and RunningCount are the sums of the counts of the primary deployments across regions.
+	DesiredCount int `json:"desiredCount"`
+	RunningCount int `json:"runningCount"`
+
+	// Progress is the overall progress of the deployment between 0 and 1, the average of the progress of each service
+	// weighted by its desired count so that a service with 50 tasks counts more than one with 2. Services without desired
+	// tasks don't weigh in, and if no service has desired tasks, it is the fraction of the regions that succeeded.
+	Progress float64 `json:"progress"`
+	// Services is the progress of each service across regions, sorted by service name. It breaks down Progress for
+	// a deployment of several services, such as the services of an environment watched with NewECSMultiAccountStreamer.
+	Services []ECSServiceProgress `json:"services"`
+
+	Completion *ECSMultiRegionCompletion `json:"completion,omitempty"` // Only set on the last description, once every region is done.
+}
+
+// ecsRegionStreamer is the streamer of the service in a region.
+type ecsRegionStreamer struct {
+	name     string // Name of the region, or of the location with NewECSMultiAccountStreamer.
+	location ECSServiceLocation
+	streamer *ECSDeploymentStreamer
+}
+
+// ECSMultiRegionStreamer is a Streamer for a service deployed to multiple regions at once, for example an
+// active/active service, that reports the combined progress of the per-region ECSDeploymentStreamers.
+// The streamer is done once the deployment completed in every region, whether it succeeded or failed.
+type ECSMultiRegionStreamer struct {
+	regions []ecsRegionStreamer
+
+	maxConcurrentFetches int // Maximum number of regions fetched at once, unbounded if zero.
+
+	now func() time.Time // Overridden in tests.
+
+	mu            sync.Mutex // Guards the state below.
+	subscribers   []chan ECSMultiRegionService
+	done          chan struct{}
+	isDone        bool
+	closed        bool
+	eventsToFlush []ECSMultiRegionService
+	failedRegions []string
+
+	failedLocations []ECSServiceLocation
+	fetchErrs       []error // Error that stopped the fetches of each region, in the order of regions.
+}
+
+// ECSMultiRegionStreamerOpt is an option to configure an ECSMultiRegionStreamer.
+type ECSMultiRegionStreamerOpt func(*ECSMultiRegionStreamer)
+
+// WithMaxConcurrentFetches fetches the service in at most n regions at once, so that a deployment to many regions
+// doesn't describe all of them in the same instant. A zero n fetches every region at once. By default, at most 5
+// regions are fetched at once. The limit complements an ECSServiceBatchDescriber, which combines the descriptions
+// of the services of the same cluster.
+func WithMaxConcurrentFetches(n int) ECSMultiRegionStreamerOpt {
+	return func(s *ECSMultiRegionStreamer) {
+		s.maxConcurrentFetches = n
+	}
+}
+
+// NewECSMultiRegionStreamer creates an ECSMultiRegionStreamer from the streamers of the service by region name.
+func NewECSMultiRegionStreamer(streamers map[string]*ECSDeploymentStreamer, opts ...ECSMultiRegionStreamerOpt) *ECSMultiRegionStreamer {
+	regions := make([]ecsRegionStreamer, 0, len(streamers))
+	for region, streamer := range streamers {
+		regions = append(regions, ecsRegionStreamer{
+			name:     region,
+			location: ECSServiceLocation{Region: region, Cluster: streamer.Cluster(), Service: streamer.Service()},
+			streamer: streamer,
+		})
+	}
+	return newECSMultiRegionStreamer(regions, opts...)
+}
+
+// newECSMultiRegionStreamer creates an ECSMultiRegionStreamer from the streamers of each region, sorted by name.
+func newECSMultiRegionStreamer(regions []ecsRegionStreamer, opts ...ECSMultiRegionStreamerOpt) *ECSMultiRegionStreamer {
+	s := &ECSMultiRegionStreamer{
+		regions:              regions,
+		fetchErrs:            make([]error, len(regions)),
+		maxConcurrentFetches: defaultECSMaxConcurrentFetches,
+		done:                 make(chan struct{}),
+		now:                  time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	sort.Slice(s.regions, func(i, j int) bool {
+		return s.regions[i].name < s.regions[j].name
+	})
+	return s
+}
+
+// Subscribe returns a read-only channel that will receive the combined descriptions of the service.
+// Subscribers of the per-region streamers keep receiving their descriptions as well.
+func (s *ECSMultiRegionStreamer) Subscribe() <-chan ECSMultiRegionService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := make(chan ECSMultiRegionService)
+	s.subscribers = append(s.subscribers, c)
+	return c
+}
+
+// Fetch fetches the service concurrently in each region where the deployment is still in progress, and stores
+// the combined description of the service. If the service can't be fetched in a region, the region is no longer
+// fetched and counts as failed, while the other regions keep being streamed. Returns the earliest time the next Fetch
+// should be attempted for a region.
+func (s *ECSMultiRegionStreamer) Fetch() (next time.Time, err error) {
+	nexts, errs := s.fetchRegions()
+	s.mu.Lock()
+	for i := range s.regions {
+		if errs[i] != nil {
+			s.fetchErrs[i] = errs[i]
+		}
+	}
+	fetchErrs := append([]error(nil), s.fetchErrs...)
+	s.mu.Unlock()
+	for i := range s.regions {
+		if nexts[i].IsZero() {
+			continue
+		}
+		if next.IsZero() || nexts[i].Before(next) {
+			next = nexts[i]
+		}
+	}
+
+	var ev ECSMultiRegionService
+	var failed []string
+	var failedLocations []ECSServiceLocation
+	allDone := true
+	for i, r := range s.regions {
+		snapshot, _ := r.streamer.latestSnapshot()
+		location := r.location
+		location.Cluster = r.streamer.Cluster() // The cluster can change WithClusterResolver.
+		regional := ECSRegionalService{
+			Region:        location.Region,
+			Service:       snapshot,
+			Location:      location,
+			Outcome:       r.streamer.Outcome(),
+			FailureReason: r.streamer.FailureReason(),
+		}
+		if fetchErrs[i] != nil {
+			regional.FetchError = fetchErrs[i].Error()
+		}
+		if primary, ok := snapshot.Primary(); ok {
+			ev.DesiredCount += primary.DesiredCount
+			ev.RunningCount += primary.RunningCount
+		}
+		regional.Progress = regionalProgress(regional)
+		switch {
+		case regional.FetchError == "" && regional.Outcome == "":
+			allDone = false
+		case regional.FetchError == "" && regional.Outcome.isSuccessful():
+		default:
+			failedLocations = append(failedLocations, location)
+			if !containsString(failed, location.Region) {
+				failed = append(failed, location.Region)
+			}
+		}
+		ev.Regions = append(ev.Regions, regional)
+	}
+	ev.Services = servicesProgress(ev.Regions)
+	ev.Progress = weightedProgress(ev.Services, ev.Regions)
+
+	s.mu.Lock()
+	justDone := allDone && !s.isDone
+	if justDone {
+		s.isDone = true
+		s.failedRegions = failed
+		s.failedLocations = failedLocations
+		ev.Completion = &ECSMultiRegionCompletion{
+			Outcome:         ECSDeploymentSucceeded,
+			FailedRegions:   failed,
+			FailedLocations: failedLocations,
+		}
+		if len(failed) > 0 {
+			ev.Completion.Outcome = ECSDeploymentFailed
+		}
+	}
+	s.eventsToFlush = append(s.eventsToFlush, ev)
+	s.mu.Unlock()
+
+	if justDone {
+		close(s.done)
+	}
+	if next.IsZero() {
+		next = s.now().Add(streamerFetchIntervalDuration)
+	}
+	return next, nil
+}
+
+// fetchRegions fetches the service in each region where the deployment is still in progress, with at most
+// maxConcurrentFetches fetches in flight, and returns the results of each region in the order of s.regions.
+func (s *ECSMultiRegionStreamer) fetchRegions() ([]time.Time, []error) {
+	nexts := make([]time.Time, len(s.regions))
+	errs := make([]error, len(s.regions))
+	var inFlight chan struct{}
+	if s.maxConcurrentFetches > 0 {
+		inFlight = make(chan struct{}, s.maxConcurrentFetches)
+	}
+	var wg sync.WaitGroup
+	for i, r := range s.regions {
+		if isClosed(r.streamer.Done()) || s.fetchErrs[i] != nil {
+			continue
+		}
+		if inFlight != nil {
+			inFlight <- struct{}{}
+		}
+		wg.Add(1)
+		go func(i int, streamer *ECSDeploymentStreamer) {
+			defer wg.Done()
+			nexts[i], errs[i] = streamer.Fetch()
+			if inFlight != nil {
+				<-inFlight
+			}
+		}(i, r.streamer)
+	}
+	wg.Wait()
+	return nexts, errs
+}
+
+// Notify flushes the new descriptions of each region to the subscribers of the per-region streamers,
+// then flushes the new combined descriptions to the streamer's subscribers.
+func (s *ECSMultiRegionStreamer) Notify() {
+	for _, r := range s.regions {
+		r.streamer.Notify()
+	}
+
+	s.mu.Lock()
+	events, subscribers := s.eventsToFlush, s.subscribers
+	s.eventsToFlush = nil
+	s.mu.Unlock()
+
+	for _, event := range events {
+		for _, sub := range subscribers {
+			sub <- event.clone()
+		}
+	}
+}
+
+// Close closes all subscribed channels, including the ones of the per-region streamers.
+func (s *ECSMultiRegionStreamer) Close() {
+	for _, r := range s.regions {
+		r.streamer.Close()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for _, sub := range s.subscribers {
+		close(sub)
+	}
+	s.closed = true
+}
+
+// Done returns a channel that's closed once the deployment completed in every region.
+func (s *ECSMultiRegionStreamer) Done() <-chan struct{} {
+	return s.done
+}
+
+// FailedRegions returns the regions where the deployment did not succeed once the streamer is done.
+func (s *ECSMultiRegionStreamer) FailedRegions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.failedRegions...)
+}
+
+// FailedLocations returns the locations where the deployment did not succeed once the streamer is done.
+func (s *ECSMultiRegionStreamer) FailedLocations() []ECSServiceLocation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ECSServiceLocation(nil), s.failedLocations...)
+}
+
+// FailureReason returns the reason of the failure in each failed region once the streamer is done,
+// for example "us-west-2: deployment failed to stabilize", and an empty string if the deployment succeeded everywhere.
+// With NewECSMultiAccountStreamer, each reason is prefixed by the location instead, such as
+// "111111111111/us-west-2/my-cluster/my-svc: deployment failed to stabilize".
+func (s *ECSMultiRegionStreamer) FailureReason() string {
+	s.mu.Lock()
+	isDone := s.isDone
+	fetchErrs := append([]error(nil), s.fetchErrs...)
+	s.mu.Unlock()
+	if !isDone {
+		return ""
+	}
+	var reasons []string
+	for i, r := range s.regions {
+		if fetchErrs[i] != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", r.name, fetchErrs[i]))
+			continue
+		}
+		if outcome := r.streamer.Outcome(); outcome.isSuccessful() {
+			continue
+		}
+		reason := r.streamer.FailureReason()
+		if reason == "" {
+			reason = strings.ToLower(string(r.streamer.Outcome()))
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", r.name, reason))
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// containsString returns true if s is one of the values.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// regionalProgress returns the fraction of the desired tasks of the primary deployment running in the region.
+func regionalProgress(r ECSRegionalService) float64 {
+	if r.Outcome == ECSDeploymentSucceeded || r.Outcome == ECSDeploymentNoChanges {
+		return 1
+	}
+	primary, ok := r.Service.Primary()
+	if !ok || primary.DesiredCount <= 0 {
+		return 0
+	}
+	if primary.RunningCount >= primary.DesiredCount {
+		return 1
+	}
+	return float64(primary.RunningCount) / float64(primary.DesiredCount)
+}
+
+// servicesProgress returns the progress of each service across the regions, sorted by service name.
+// The progress of a service is the average progress of the service in each region weighted by its desired count.
+func servicesProgress(regions []ECSRegionalService) []ECSServiceProgress {
+	var services []ECSServiceProgress
+	index := make(map[string]int)
+	weighted := make(map[string]float64) // Sum of the progress of each region multiplied by its desired count, by service.
+	for _, r := range regions {
+		i, ok := index[r.Location.Service]
+		if !ok {
+			i = len(services)
+			index[r.Location.Service] = i
+			services = append(services, ECSServiceProgress{Service: r.Location.Service})
+		}
+		primary, ok := r.Service.Primary()
+		if !ok {
+			continue
+		}
+		services[i].DesiredCount += primary.DesiredCount
+		services[i].RunningCount += primary.RunningCount
+		weighted[r.Location.Service] += float64(primary.DesiredCount) * r.Progress
+	}
+	for i, svc := range services {
+		if svc.DesiredCount > 0 {
+			services[i].Progress = weighted[svc.Service] / float64(svc.DesiredCount)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Service < services[j].Service
+	})
+	return services
+}
+
+// weightedProgress returns the average progress of the services weighted by their desired count, or the fraction
+// of the regions that succeeded if no service has desired tasks.
+func weightedProgress(services []ECSServiceProgress, regions []ECSRegionalService) float64 {
+	var progress, weights float64
+	for _, svc := range services {
+		if svc.DesiredCount <= 0 {
+			continue
+		}
+		weight := float64(svc.DesiredCount)
+		progress += weight * svc.Progress
+		weights += weight
+	}
+	if weights > 0 {
+		return progress / weights
+	}
+	if len(regions) == 0 {
+		return 0
+	}
+	var succeeded int
+	for _, r := range regions {
+		if r.Outcome == ECSDeploymentSucceeded || r.Outcome == ECSDeploymentNoChanges {
+			succeeded++
+		}
+	}
+	return float64(succeeded) / float64(len(regions))
+}
+
+// clone returns a deep copy of the combined description so that subscribers don't share slices.
+func (s ECSMultiRegionService) clone() ECSMultiRegionService {
+	c := s
+	if s.Regions != nil {
+		c.Regions = make([]ECSRegionalService, len(s.Regions))
+		for i, r := range s.Regions {
+			c.Regions[i] = r
+			c.Regions[i].Service = r.Service.clone()
+		}
+	}
+	c.Services = append([]ECSServiceProgress(nil), s.Services...)
+	if s.Completion != nil {
+		completion := *s.Completion
+		completion.FailedRegions = append([]string(nil), s.Completion.FailedRegions...)
+		completion.FailedLocations = append([]ECSServiceLocation(nil), s.Completion.FailedLocations...)
+		c.Completion = &completion
+	}
+	return c
+}
+
+// isClosed returns true if the channel is closed, without blocking.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
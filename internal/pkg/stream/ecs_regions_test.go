@@ -0,0 +1,334 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECSMultiRegionStreamer_Fetch(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	newService := func(running int64, rolloutState string) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String(rolloutState),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate),
+				},
+			},
+		}
+	}
+	t.Run("keeps streaming the other regions if a region can't be fetched", func(t *testing.T) {
+		// GIVEN
+		west, east := &mockECS{out: newService(1, "IN_PROGRESS")}, &mockECS{err: errors.New("some error")}
+		streamer := NewECSMultiRegionStreamer(map[string]*ECSDeploymentStreamer{
+			"us-west-2": NewECSDeploymentStreamer(west, "my-cluster", "my-svc", startDate),
+			"eu-west-1": NewECSDeploymentStreamer(east, "my-cluster", "my-svc", startDate),
+		})
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		ev := streamer.eventsToFlush[0]
+		require.Equal(t, "eu-west-1", ev.Regions[0].Region)
+		require.Equal(t, "fetch service description: some error", ev.Regions[0].FetchError)
+		require.Equal(t, 1, ev.Regions[1].Service.Deployments[0].RunningCount)
+		require.Nil(t, ev.Completion)
+
+		// WHEN
+		east.out, east.err = newService(2, "COMPLETED"), nil
+		west.out = newService(2, "COMPLETED")
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		require.Equal(t, &ECSMultiRegionCompletion{
+			Outcome:         ECSDeploymentFailed,
+			FailedRegions:   []string{"eu-west-1"},
+			FailedLocations: []ECSServiceLocation{{Region: "eu-west-1", Cluster: "my-cluster", Service: "my-svc"}},
+		}, streamer.eventsToFlush[1].Completion, "the region should no longer be fetched once it errored")
+		require.Equal(t, "eu-west-1: fetch service description: some error", streamer.FailureReason())
+	})
+	t.Run("combines the progress of each region until they all complete", func(t *testing.T) {
+		// GIVEN
+		west, east := &mockECS{out: newService(2, "IN_PROGRESS")}, &mockECS{out: newService(1, "IN_PROGRESS")}
+		streamer := NewECSMultiRegionStreamer(map[string]*ECSDeploymentStreamer{
+			"us-west-2": NewECSDeploymentStreamer(west, "my-cluster", "my-svc", startDate),
+			"us-east-1": NewECSDeploymentStreamer(east, "my-cluster", "my-svc", startDate),
+		})
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, streamer.eventsToFlush, 1)
+		ev := streamer.eventsToFlush[0]
+		require.Equal(t, 4, ev.DesiredCount)
+		require.Equal(t, 3, ev.RunningCount)
+		require.Equal(t, "us-east-1", ev.Regions[0].Region)
+		require.Empty(t, ev.Regions[0].Outcome)
+		require.Equal(t, "us-west-2", ev.Regions[1].Region)
+		require.Equal(t, ECSDeploymentSucceeded, ev.Regions[1].Outcome)
+		require.Equal(t, 0.75, ev.Progress)
+		require.Equal(t, []ECSServiceProgress{{Service: "my-svc", DesiredCount: 4, RunningCount: 3, Progress: 0.75}}, ev.Services)
+		require.Nil(t, ev.Completion)
+		require.False(t, isClosed(streamer.Done()))
+
+		// WHEN
+		east.out = newService(2, "COMPLETED")
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		ev = streamer.eventsToFlush[1]
+		require.Equal(t, 4, ev.RunningCount)
+		require.Equal(t, &ECSMultiRegionCompletion{Outcome: ECSDeploymentSucceeded}, ev.Completion)
+		require.Empty(t, streamer.FailedRegions())
+		require.Empty(t, streamer.FailureReason())
+	})
+	t.Run("reports the regions where the deployment failed", func(t *testing.T) {
+		// GIVEN
+		failed := newService(0, "FAILED")
+		failed.Deployments[0].RolloutStateReason = aws.String("ECS deployment circuit breaker: tasks failed to start.")
+		streamer := NewECSMultiRegionStreamer(map[string]*ECSDeploymentStreamer{
+			"us-west-2": NewECSDeploymentStreamer(&mockECS{out: newService(2, "COMPLETED")}, "my-cluster", "my-svc", startDate),
+			"eu-west-1": NewECSDeploymentStreamer(&mockECS{out: failed}, "my-cluster", "my-svc", startDate),
+		})
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		ev := streamer.eventsToFlush[0]
+		require.Equal(t, &ECSMultiRegionCompletion{
+			Outcome:         ECSDeploymentFailed,
+			FailedRegions:   []string{"eu-west-1"},
+			FailedLocations: []ECSServiceLocation{{Region: "eu-west-1", Cluster: "my-cluster", Service: "my-svc"}},
+		}, ev.Completion)
+		require.Equal(t, ECSDeploymentFailed, ev.Regions[0].Outcome)
+		require.Equal(t, "ECS deployment circuit breaker: tasks failed to start.", ev.Regions[0].FailureReason)
+		require.Equal(t, []string{"eu-west-1"}, streamer.FailedRegions())
+		require.Equal(t, "eu-west-1: ECS deployment circuit breaker: tasks failed to start.", streamer.FailureReason())
+	})
+}
+
+// mockECSInFlight records the highest number of concurrent calls to Service.
+type mockECSInFlight struct {
+	out *ecs.Service
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	calls       int
+}
+
+func (m *mockECSInFlight) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	m.mu.Lock()
+	m.calls++
+	m.inFlight++
+	if m.inFlight > m.maxInFlight {
+		m.maxInFlight = m.inFlight
+	}
+	m.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+	return m.out, nil
+}
+
+func TestECSMultiRegionStreamer_FetchMaxConcurrentFetches(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	regions := []string{"us-west-2", "us-west-1", "us-east-1", "us-east-2", "eu-west-1", "eu-central-1"}
+	testCases := map[string]struct {
+		opts []ECSMultiRegionStreamerOpt
+
+		wantedMaxInFlight int
+	}{
+		"caps the in-flight fetches with the option": {
+			opts:              []ECSMultiRegionStreamerOpt{WithMaxConcurrentFetches(2)},
+			wantedMaxInFlight: 2,
+		},
+		"caps the in-flight fetches by default": {
+			wantedMaxInFlight: defaultECSMaxConcurrentFetches,
+		},
+		"fetches every region at once without a limit": {
+			opts:              []ECSMultiRegionStreamerOpt{WithMaxConcurrentFetches(0)},
+			wantedMaxInFlight: len(regions),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECSInFlight{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{
+						{
+							DesiredCount:   aws.Int64(2),
+							RunningCount:   aws.Int64(1),
+							Status:         aws.String("PRIMARY"),
+							TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+						},
+					},
+				},
+			}
+			streamers := make(map[string]*ECSDeploymentStreamer)
+			for _, region := range regions {
+				streamers[region] = NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+			}
+			streamer := NewECSMultiRegionStreamer(streamers, tc.opts...)
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, len(regions), m.calls)
+			require.LessOrEqual(t, m.maxInFlight, tc.wantedMaxInFlight)
+			require.Len(t, streamer.eventsToFlush[0].Regions, len(regions))
+		})
+	}
+}
+
+// newRegionalService returns the description of the service in a region with the counts of its primary deployment.
+func newRegionalService(service string, desired, running int, outcome ECSDeploymentOutcome) ECSRegionalService {
+	r := ECSRegionalService{
+		Service: ECSService{
+			Deployments: []ECSDeployment{{Status: "PRIMARY", DesiredCount: desired, RunningCount: running}},
+		},
+		Location: ECSServiceLocation{Service: service},
+		Outcome:  outcome,
+	}
+	r.Progress = regionalProgress(r)
+	return r
+}
+
+func TestServicesProgress(t *testing.T) {
+	// GIVEN
+	regions := []ECSRegionalService{
+		newRegionalService("worker", 2, 0, ""),
+		newRegionalService("api", 10, 10, ECSDeploymentSucceeded),
+		newRegionalService("api", 30, 15, ""),
+		newRegionalService("worker", 0, 0, ""),
+	}
+
+	// WHEN
+	services := servicesProgress(regions)
+
+	// THEN
+	require.Equal(t, []ECSServiceProgress{
+		{Service: "api", DesiredCount: 40, RunningCount: 25, Progress: (10*1.0 + 30*0.5) / 40},
+		{Service: "worker", DesiredCount: 2, RunningCount: 0, Progress: 0},
+	}, services)
+}
+
+func TestWeightedProgress(t *testing.T) {
+	regional := func(desired, running int, outcome ECSDeploymentOutcome) ECSRegionalService {
+		return newRegionalService("my-svc", desired, running, outcome)
+	}
+	testCases := map[string]struct {
+		regions []ECSRegionalService
+
+		wanted float64
+	}{
+		"no regions": {
+			wanted: 0,
+		},
+		"weighs each service by its desired count": {
+			regions: []ECSRegionalService{
+				newRegionalService("api", 50, 40, ""),  // 80% of 50 tasks.
+				newRegionalService("worker", 2, 0, ""), // 0% of 2 tasks.
+				newRegionalService("cron", 0, 0, ""),   // No desired tasks.
+			},
+			wanted: 40.0 / 52.0,
+		},
+		"weighs each region by its desired count": {
+			regions: []ECSRegionalService{
+				regional(50, 40, ""), // 80% of 50 tasks.
+				regional(2, 0, ""),   // 0% of 2 tasks.
+			},
+			wanted: 40.0 / 52.0,
+		},
+		"counts a succeeded region as fully deployed": {
+			regions: []ECSRegionalService{
+				regional(4, 3, ECSDeploymentSucceeded),
+				regional(4, 1, ""),
+			},
+			wanted: (4*1.0 + 4*0.25) / 8,
+		},
+		"caps the progress of a region with more running than desired tasks": {
+			regions: []ECSRegionalService{
+				regional(2, 4, ""),
+				regional(2, 0, ""),
+			},
+			wanted: 0.5,
+		},
+		"ignores the regions without desired tasks": {
+			regions: []ECSRegionalService{
+				regional(0, 0, ""),
+				regional(10, 5, ""),
+			},
+			wanted: 0.5,
+		},
+		"falls back to the fraction of succeeded regions without any desired task": {
+			regions: []ECSRegionalService{
+				regional(0, 0, ECSDeploymentNoChanges),
+				regional(0, 0, ""),
+				regional(0, 0, ECSDeploymentFailed),
+				{}, // Not fetched yet.
+			},
+			wanted: 0.25,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.InDelta(t, tc.wanted, weightedProgress(servicesProgress(tc.regions), tc.regions), 1e-9)
+		})
+	}
+}
+
+func TestECSMultiRegionStreamer_Notify(t *testing.T) {
+	// GIVEN
+	regional := NewECSDeploymentStreamer(&mockECS{}, "my-cluster", "my-svc", time.Now())
+	regionalSub := regional.Subscribe()
+	streamer := NewECSMultiRegionStreamer(map[string]*ECSDeploymentStreamer{
+		"us-west-2": regional,
+	})
+	sub := streamer.Subscribe()
+	regional.eventsToFlush = []ECSService{{Deployments: []ECSDeployment{{Status: "PRIMARY"}}}}
+	streamer.eventsToFlush = []ECSMultiRegionService{{RunningCount: 1}}
+
+	// WHEN
+	go streamer.Notify()
+
+	// THEN
+	require.Equal(t, ECSService{Deployments: []ECSDeployment{{Status: "PRIMARY"}}, Sequence: 1}, <-regionalSub)
+	require.Equal(t, ECSMultiRegionService{RunningCount: 1}, <-sub)
+	streamer.Close()
+	_, ok := <-sub
+	require.False(t, ok)
+	_, ok = <-regionalSub
+	require.False(t, ok)
+}
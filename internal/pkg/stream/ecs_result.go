@@ -0,0 +1,90 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import "time"
+
+const ecsMaxResultFailureMessages = 100 // Maximum number of failure messages retained for Result.
+
+// ECSDeploymentResult summarizes how a deployment ended, for example for a CI script to serialize it
+// and map it to an exit code.
+type ECSDeploymentResult struct {
+	Outcome ECSDeploymentOutcome `json:"outcome"`
+	// Succeeded is true if the outcome is ECSDeploymentSucceeded or ECSDeploymentNoChanges.
+	Succeeded bool `json:"succeeded"`
+
+	// Reason explains why the deployment failed, see ECSDeploymentStreamer.FailureReason. It is empty if it succeeded.
+	Reason string `json:"reason,omitempty"`
+	// Category is the category of the failure service event used as Reason, or ECSFailureCategoryUnknown if the reason
+	// doesn't come from a classified service event. It is empty if the deployment didn't fail.
+	Category ECSFailureCategory `json:"category,omitempty"`
+	// RolloutFailureCause is why ECS failed the rollout, see ECSDeploymentStreamer.RolloutFailureCause.
+	RolloutFailureCause ECSRolloutFailureCause `json:"rolloutFailureCause,omitempty"`
+
+	// Duration is how long the deployment took from its creation time until the streamer observed its outcome.
+	Duration time.Duration `json:"duration"`
+
+	// TaskDefRevision and the counts are the ones of the primary deployment in the last snapshot of the service.
+	TaskDefRevision string `json:"taskDefRevision,omitempty"`
+	DesiredCount    int    `json:"desiredCount"`
+	RunningCount    int    `json:"runningCount"`
+	PendingCount    int    `json:"pendingCount"`
+	FailedCount     int    `json:"failedCount"`
+
+	// FailureMessages are the messages of the failure events reported while watching the deployment, oldest first.
+	// At most the 100 most recent messages are kept.
+	FailureMessages []string `json:"failureMessages,omitempty"`
+	// StoppedReasons are the distinct reasons why the tasks of the failed deployment stopped, most recent first,
+	// see ECSDeploymentStreamer.StoppedTaskReasons.
+	StoppedReasons []string `json:"stoppedReasons,omitempty"`
+}
+
+// Result returns the summary of the deployment once the streamer is done, and false while it is still in progress.
+func (s *ECSDeploymentStreamer) Result() (ECSDeploymentResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.outcome == "" {
+		return ECSDeploymentResult{}, false
+	}
+	result := ECSDeploymentResult{
+		Outcome:             s.outcome,
+		Succeeded:           s.outcome.isSuccessful(),
+		RolloutFailureCause: s.rolloutCause,
+		Duration:            s.completedAt.Sub(s.deploymentCreationTime),
+	}
+	if s.outcome == ECSDeploymentFailed || s.outcome == ECSDeploymentSuperseded {
+		result.Reason = s.failureReason
+		result.Category = s.failureCategory
+		if result.Category == "" {
+			result.Category = ECSFailureCategoryUnknown
+		}
+	}
+	if primary, ok := s.latest.Primary(); ok {
+		result.TaskDefRevision = primary.TaskDefRevision
+		result.DesiredCount = primary.DesiredCount
+		result.RunningCount = primary.RunningCount
+		result.PendingCount = primary.PendingCount
+		result.FailedCount = primary.FailedCount
+	}
+	if len(s.failureMsgs) > 0 {
+		result.FailureMessages = make([]string, len(s.failureMsgs))
+		copy(result.FailureMessages, s.failureMsgs)
+	}
+	if len(s.stoppedReasons) > 0 {
+		result.StoppedReasons = make([]string, len(s.stoppedReasons))
+		copy(result.StoppedReasons, s.stoppedReasons)
+	}
+	return result, true
+}
+
+// recordFailureMessages retains the messages of new failures, sorted from newest to oldest like the service events
+// they were parsed from, dropping the oldest messages beyond the limit. It must be called with the lock held.
+func (s *ECSDeploymentStreamer) recordFailureMessages(msgs []string) {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		s.failureMsgs = append(s.failureMsgs, msgs[i])
+	}
+	if extra := len(s.failureMsgs) - ecsMaxResultFailureMessages; extra > 0 {
+		s.failureMsgs = append([]string(nil), s.failureMsgs[extra:]...)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECSDeploymentStreamer_Result(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	pullFailure := "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 5 time(s): failed to resolve ref my-svc:abc: not found"
+	placeFailure := "(service my-svc) was unable to place a task."
+	newService := func(running int64, rolloutState string, events ...*awsecs.ServiceEvent) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					FailedTasks:    aws.Int64(2 - running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String(rolloutState),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+			Events: events,
+		}
+	}
+	event := func(id, msg string, at time.Duration) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(msg),
+			CreatedAt: aws.Time(startDate.Add(at)),
+		}
+	}
+	testCases := map[string]struct {
+		outs []*ecs.Service
+
+		wanted ECSDeploymentResult
+	}{
+		"summarizes a successful deployment": {
+			outs: []*ecs.Service{
+				newService(1, "IN_PROGRESS"),
+				newService(2, "COMPLETED"),
+			},
+			wanted: ECSDeploymentResult{
+				Outcome:         ECSDeploymentSucceeded,
+				Succeeded:       true,
+				Duration:        2 * time.Minute,
+				TaskDefRevision: "2",
+				DesiredCount:    2,
+				RunningCount:    2,
+			},
+		},
+		"summarizes a failed deployment with its failures": {
+			outs: []*ecs.Service{
+				newService(1, "IN_PROGRESS", event("1", placeFailure, time.Minute)),
+				newService(0, "FAILED",
+					event("3", pullFailure, 90*time.Second),
+					event("2", placeFailure, 80*time.Second),
+					event("1", placeFailure, time.Minute)),
+			},
+			wanted: ECSDeploymentResult{
+				Outcome:             ECSDeploymentFailed,
+				Reason:              pullFailure,
+				Category:            ECSFailureCategoryImagePull,
+				RolloutFailureCause: ECSRolloutFailureCauseUnknown,
+				Duration:            2 * time.Minute,
+				TaskDefRevision:     "2",
+				DesiredCount:        2,
+				FailedCount:         2,
+				FailureMessages:     []string{placeFailure, placeFailure, pullFailure},
+			},
+		},
+		"does not categorize a reason that is not a classified failure event": {
+			outs: []*ecs.Service{
+				newService(1, "IN_PROGRESS", event("1", pullFailure, time.Minute)),
+				func() *ecs.Service {
+					out := newService(0, "FAILED", event("1", pullFailure, time.Minute))
+					out.Deployments[0].RolloutStateReason = aws.String("ECS deployment timed out.")
+					return out
+				}(),
+			},
+			wanted: ECSDeploymentResult{
+				Outcome:             ECSDeploymentFailed,
+				Reason:              ecsDeploymentTimedOutReason,
+				Category:            ECSFailureCategoryUnknown,
+				RolloutFailureCause: ECSRolloutFailureCauseTimeout,
+				Duration:            2 * time.Minute,
+				TaskDefRevision:     "2",
+				DesiredCount:        2,
+				FailedCount:         2,
+				FailureMessages:     []string{pullFailure},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: tc.outs}, "my-cluster", "my-svc", startDate)
+			now := startDate.Add(time.Minute)
+			streamer.now = func() time.Time { return now }
+
+			// WHEN
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			_, inProgress := streamer.Result()
+			now = startDate.Add(2 * time.Minute)
+			_, err = streamer.Fetch()
+			require.NoError(t, err)
+			result, done := streamer.Result()
+
+			// THEN
+			require.False(t, inProgress, "there should be no result while the deployment is in progress")
+			require.True(t, done)
+			require.Equal(t, tc.wanted, result)
+		})
+	}
+}
+
+func TestECSDeploymentResult_JSON(t *testing.T) {
+	// GIVEN
+	result := ECSDeploymentResult{
+		Outcome:         ECSDeploymentFailed,
+		Reason:          "deployment failed",
+		Category:        ECSFailureCategoryApplication,
+		Duration:        time.Second,
+		TaskDefRevision: "3",
+		DesiredCount:    2,
+		FailedCount:     1,
+		FailureMessages: []string{"(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)"},
+	}
+
+	// WHEN
+	data, err := json.Marshal(result)
+
+	// THEN
+	require.NoError(t, err)
+	require.JSONEq(t, fmt.Sprintf(`{
+		"outcome": "FAILED",
+		"succeeded": false,
+		"reason": "deployment failed",
+		"category": "application",
+		"duration": %d,
+		"taskDefRevision": "3",
+		"desiredCount": 2,
+		"runningCount": 0,
+		"pendingCount": 0,
+		"failedCount": 1,
+		"failureMessages": ["(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)"]
+	}`, time.Second), string(data))
+}
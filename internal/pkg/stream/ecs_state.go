@@ -0,0 +1,85 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const ecsStateVersion = 1
+
+// ecsStreamerState is the state of an ECSDeploymentStreamer persisted by MarshalState.
+type ecsStreamerState struct {
+	Version     int        `json:"version"`
+	Cluster     string     `json:"cluster"`
+	Service     string     `json:"service"`
+	LastEventAt time.Time  `json:"lastEventAt"`
+	EventIDs    []string   `json:"eventIDs"`
+	Latest      ECSService `json:"latest"`
+	Sequence    uint64     `json:"sequence"`
+}
+
+// MarshalState serializes the state that the streamer needs to not report the same service events again,
+// so that a restarted watcher can resume with UnmarshalState. It includes the creation time of the most recent
+// service event seen, the IDs of the events seen that were created at that time, and the last snapshot.
+// Events created before the most recent one are skipped on resume regardless, so their IDs are not kept.
+func (s *ECSDeploymentStreamer) MarshalState() ([]byte, error) {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.lastEventIDs))
+	for id := range s.lastEventIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	lastEventAt := s.lastEventAt
+	if s.resumedAt.After(lastEventAt) {
+		lastEventAt = s.resumedAt
+	}
+	state := ecsStreamerState{
+		Version:     ecsStateVersion,
+		Cluster:     s.cluster,
+		Service:     s.service,
+		LastEventAt: lastEventAt,
+		EventIDs:    ids,
+		Latest:      s.latest.clone(),
+		Sequence:    s.sequence,
+	}
+	s.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("marshal streamer state: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalState restores the state serialized by MarshalState, so that the service events seen before are not
+// reported again. It should be called before the first Fetch, on a streamer watching the same cluster and service.
+func (s *ECSDeploymentStreamer) UnmarshalState(data []byte) error {
+	var state ecsStreamerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("unmarshal streamer state: %w", err)
+	}
+	if state.Version != ecsStateVersion {
+		return fmt.Errorf("unsupported streamer state version %d", state.Version)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.Cluster != s.cluster || state.Service != s.service {
+		return fmt.Errorf("streamer state of service %s in cluster %s can't be restored for service %s in cluster %s",
+			state.Service, state.Cluster, s.service, s.cluster)
+	}
+	if state.LastEventAt.After(s.lastEventAt) {
+		s.lastEventAt, s.lastEventIDs = state.LastEventAt, nil
+	}
+	for _, id := range state.EventIDs {
+		s.markEventSeen(id, state.LastEventAt)
+	}
+	s.resumedAt = state.LastEventAt
+	s.latest = state.Latest
+	s.sequence = state.Sequence
+	return nil
+}
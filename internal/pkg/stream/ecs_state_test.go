@@ -0,0 +1,146 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestECSDeploymentStreamer_MarshalState(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	failureEvent := func(id string, createdAt time.Time) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(fmt.Sprintf("(service my-svc) failed to launch a task %s.", id)),
+			CreatedAt: aws.Time(createdAt),
+		}
+	}
+	deployments := []*awsecs.Deployment{
+		{
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(1),
+			Status:         aws.String("PRIMARY"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+		},
+	}
+	t.Run("doesn't report the events seen before the state was saved", func(t *testing.T) {
+		// GIVEN
+		before := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{
+			Deployments: deployments,
+			Events: []*awsecs.ServiceEvent{
+				failureEvent("2", startDate.Add(2*time.Minute)),
+				failureEvent("1", startDate.Add(time.Minute)),
+			},
+		}}, "my-cluster", "my-svc", startDate)
+		before.now = func() time.Time { return startDate.Add(2 * time.Minute) }
+		_, err := before.Fetch()
+		require.NoError(t, err)
+		before.Notify()
+
+		// WHEN
+		data, err := before.MarshalState()
+		require.NoError(t, err)
+		after := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{
+			Deployments: deployments,
+			Events: []*awsecs.ServiceEvent{
+				failureEvent("4", startDate.Add(3*time.Minute)),
+				failureEvent("3", startDate.Add(2*time.Minute)), // Created at the same time as the last event seen.
+				failureEvent("2", startDate.Add(2*time.Minute)),
+				failureEvent("1", startDate.Add(time.Minute)),
+			},
+		}}, "my-cluster", "my-svc", startDate)
+		after.now = func() time.Time { return startDate.Add(3 * time.Minute) }
+		require.NoError(t, after.UnmarshalState(data))
+		restored, _ := after.latestSnapshot()
+		_, err = after.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, before.latest, restored, "the last snapshot should be restored")
+		require.Equal(t, []string{
+			"(service my-svc) failed to launch a task 4.",
+			"(service my-svc) failed to launch a task 3.",
+		}, after.eventsToFlush[0].LatestFailureEvents)
+		sub := after.Subscribe()
+		got := notifyAndCollect(after, sub)
+		require.Equal(t, uint64(2), got[0].Sequence, "the sequence should continue from the saved state")
+	})
+	t.Run("only keeps the IDs of the events created at the same time as the most recent one", func(t *testing.T) {
+		// GIVEN
+		latestAt := startDate.Add(time.Hour)
+		events := []*awsecs.ServiceEvent{
+			failureEvent("z2", latestAt),
+			failureEvent("z1", latestAt),
+		}
+		for i := 0; i < 1500; i++ {
+			events = append(events, failureEvent(fmt.Sprintf("a%d", i), latestAt.Add(-time.Duration(i+1)*time.Second)))
+		}
+		before := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{
+			Deployments: deployments,
+			Events:      events,
+		}}, "my-cluster", "my-svc", startDate)
+		before.now = func() time.Time { return latestAt }
+		_, err := before.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		data, err := before.MarshalState()
+		require.NoError(t, err)
+		after := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{
+			Deployments: deployments,
+			Events:      append([]*awsecs.ServiceEvent{failureEvent("z3", latestAt)}, events...),
+		}}, "my-cluster", "my-svc", startDate)
+		after.now = func() time.Time { return latestAt.Add(time.Minute) }
+		require.NoError(t, after.UnmarshalState(data))
+		_, err = after.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		var state ecsStreamerState
+		require.NoError(t, json.Unmarshal(data, &state))
+		require.Equal(t, []string{"z1", "z2"}, state.EventIDs)
+		require.Equal(t, []string{"(service my-svc) failed to launch a task z3."}, after.eventsToFlush[0].LatestFailureEvents)
+	})
+}
+
+func TestECSDeploymentStreamer_UnmarshalState(t *testing.T) {
+	testCases := map[string]struct {
+		data string
+
+		wantedErr string
+	}{
+		"invalid state": {
+			data:      "not json",
+			wantedErr: "unmarshal streamer state: invalid character 'o' in literal null (expecting 'u')",
+		},
+		"unsupported version": {
+			data:      `{"version":2,"cluster":"my-cluster","service":"my-svc"}`,
+			wantedErr: "unsupported streamer state version 2",
+		},
+		"state of another service": {
+			data:      `{"version":1,"cluster":"my-cluster","service":"other-svc"}`,
+			wantedErr: "streamer state of service other-svc in cluster my-cluster can't be restored for service my-svc in cluster my-cluster",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+
+			// WHEN
+			err := streamer.UnmarshalState([]byte(tc.data))
+
+			// THEN
+			require.EqualError(t, err, tc.wantedErr)
+		})
+	}
+}
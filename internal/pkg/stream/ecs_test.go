@@ -4,11 +4,15 @@
 package stream
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	awsecs "github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
 	"github.com/stretchr/testify/require"
@@ -23,6 +27,64 @@ func (m mockECS) Service(clusterName, serviceName string) (*ecs.Service, error)
 	return m.out, m.err
 }
 
+type mockECSTasks struct {
+	out []*ecs.Task
+	err error
+}
+
+func (m mockECSTasks) ServiceTasks(clusterName, serviceName string) ([]*ecs.Task, error) {
+	return m.out, m.err
+}
+
+func TestParseECSRolloutState(t *testing.T) {
+	testCases := map[string]struct {
+		raw string
+
+		wanted         ECSRolloutState
+		wantedTerminal bool
+		wantedFailed   bool
+	}{
+		"in progress": {
+			raw:    "IN_PROGRESS",
+			wanted: ECSRolloutStateInProgress,
+		},
+		"completed": {
+			raw:            "COMPLETED",
+			wanted:         ECSRolloutStateCompleted,
+			wantedTerminal: true,
+		},
+		"failed": {
+			raw:            "FAILED",
+			wanted:         ECSRolloutStateFailed,
+			wantedTerminal: true,
+			wantedFailed:   true,
+		},
+		"empty": {
+			wanted: ECSRolloutStateUnknown,
+		},
+		"unknown": {
+			raw:    "ROLLING_BACK",
+			wanted: ECSRolloutStateUnknown,
+		},
+		"differs in case": {
+			raw:    "completed",
+			wanted: ECSRolloutStateUnknown,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// WHEN
+			state := ParseECSRolloutState(tc.raw)
+
+			// THEN
+			require.Equal(t, tc.wanted, state)
+			require.Equal(t, tc.wantedTerminal, state.IsTerminal())
+			require.Equal(t, tc.wantedFailed, state.IsFailed())
+			require.Equal(t, tc.wanted, ECSDeployment{RolloutState: tc.raw}.ParsedRolloutState())
+		})
+	}
+}
+
 func TestECSDeploymentStreamer_Subscribe(t *testing.T) {
 	// GIVEN
 	streamer := &ECSDeploymentStreamer{}
@@ -35,6 +97,15 @@ func TestECSDeploymentStreamer_Subscribe(t *testing.T) {
 	require.Equal(t, 2, len(streamer.subscribers), "expected number of subscribers to match")
 }
 
+func TestECSDeploymentStreamer_ClusterAndService(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+
+	// THEN
+	require.Equal(t, "my-cluster", streamer.Cluster())
+	require.Equal(t, "my-svc", streamer.Service())
+}
+
 func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 	t.Run("returns a wrapped error on describe service call failure", func(t *testing.T) {
 		// GIVEN
@@ -49,6 +120,22 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 		// THEN
 		require.EqualError(t, err, "fetch service description: some error")
 	})
+	t.Run("returns an error that matches ErrServiceNotFound if the service is missing", func(t *testing.T) {
+		// GIVEN
+		m := mockECS{
+			err: &ecs.ErrServiceFailure{
+				Service: "my-svc",
+				Reason:  "MISSING",
+			},
+		}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, ecs.ErrServiceNotFound))
+	})
 	t.Run("stores events until deployment is done", func(t *testing.T) {
 		// GIVEN
 		m := mockECS{
@@ -75,7 +162,11 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 				},
 			},
 		}
-		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+		startDate := time.Now()
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time {
+			return startDate
+		}
 
 		// WHEN
 		_, err := streamer.Fetch()
@@ -105,6 +196,21 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 					},
 				},
 				LatestFailureEvents: nil,
+				Notices: []ECSNotice{
+					{
+						Kind:     ECSNoticeAlreadyCompleted,
+						Severity: ECSNoticeInfo,
+						Message:  "the deployment had already completed when the watch started, there is no progress to report",
+					},
+				},
+				Completion: &ECSDeploymentCompletion{
+					Outcome:     ECSDeploymentSucceeded,
+					StartedAt:   startDate,
+					CompletedAt: startDate,
+
+					MinRunningCount: 10,
+					MaxRunningCount: 10,
+				},
 			},
 		}, streamer.eventsToFlush)
 		_, isOpen := <-streamer.Done()
@@ -168,6 +274,9 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 			},
 		}
 		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time {
+			return startDate.Add(3 * time.Minute)
+		}
 
 		// WHEN
 		_, err := streamer.Fetch()
@@ -176,6 +285,11 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, []ECSService{
 			{
+				EventStaleness: 2 * time.Minute,
+				FailureCategoryCounts: map[ECSFailureCategory]int{
+					ECSFailureCategoryNetworking: 1,
+					ECSFailureCategoryUnknown:    5,
+				},
 				LatestFailureEvents: []string{
 					"(service my-svc) failed to register targets in (target-group 1234) with (error some-error)",
 					"(service my-svc) failed to launch a task with (error some-error).",
@@ -184,6 +298,32 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 					"(service my-svc) was unable to place a task.",
 					"(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason some-error).",
 				},
+				LatestFailures: []ECSServiceFailure{
+					{
+						Message:  "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)",
+						Category: ECSFailureCategoryNetworking,
+					},
+					{
+						Message:  "(service my-svc) failed to launch a task with (error some-error).",
+						Category: ECSFailureCategoryUnknown,
+					},
+					{
+						Message:  "(service my-svc) (task 1234) failed container health checks.",
+						Category: ECSFailureCategoryUnknown,
+					},
+					{
+						Message:  "(service my-svc) (deployment 123) deployment failed: some-error.",
+						Category: ECSFailureCategoryUnknown,
+					},
+					{
+						Message:  "(service my-svc) was unable to place a task.",
+						Category: ECSFailureCategoryUnknown,
+					},
+					{
+						Message:  "(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason some-error).",
+						Category: ECSFailureCategoryUnknown,
+					},
+				},
 			},
 		}, streamer.eventsToFlush)
 	})
@@ -240,47 +380,4402 @@ func TestECSDeploymentStreamer_Fetch(t *testing.T) {
 	})
 }
 
-func TestECSDeploymentStreamer_Notify(t *testing.T) {
-	// GIVEN
-	wantedEvents := []ECSService{
-		{
-			Deployments: []ECSDeployment{
-				{
-					Status: "PRIMARY",
-				},
-				{
-					Status: "ACTIVE",
+func TestECSDeploymentStreamer_Start(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	primary := &awsecs.Deployment{
+		DesiredCount:   aws.Int64(2),
+		RunningCount:   aws.Int64(1),
+		Status:         aws.String("PRIMARY"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+	}
+	controller := func(controllerType string) *awsecs.DeploymentController {
+		return &awsecs.DeploymentController{Type: aws.String(controllerType)}
+	}
+	testCases := map[string]struct {
+		mock ECSServiceDescriber
+		opts []ECSDeploymentStreamerOpt
+
+		wantedErr       string
+		wantedNotFound  bool
+		wantedWatchable bool
+	}{
+		"service is missing": {
+			mock: mockECS{
+				err: &ecs.ErrServiceFailure{Service: "my-svc", Reason: "MISSING"},
+			},
+			wantedNotFound: true,
+		},
+		"transient errors are not retried": {
+			mock:      mockECS{err: awserr.New("ThrottlingException", "Rate exceeded", nil)},
+			wantedErr: "fetch service description: ThrottlingException: Rate exceeded",
+		},
+		"service deployed by CodeDeploy": {
+			mock: mockECS{out: &ecs.Service{
+				DeploymentController: controller("CODE_DEPLOY"),
+				Deployments:          []*awsecs.Deployment{primary},
+			}},
+			wantedErr: "service is not watchable: my-svc uses the CODE_DEPLOY deployment controller, watch its task sets WithTaskSets",
+		},
+		"task sets of a service deployed by ECS": {
+			mock: mockECS{out: &ecs.Service{
+				DeploymentController: controller("ECS"),
+				Deployments:          []*awsecs.Deployment{primary},
+			}},
+			opts:      []ECSDeploymentStreamerOpt{WithTaskSets()},
+			wantedErr: "service is not watchable: my-svc uses the ECS deployment controller, which has no task sets",
+		},
+		"service without a primary deployment": {
+			mock:      mockECS{out: &ecs.Service{}},
+			wantedErr: "service is not watchable: my-svc has no PRIMARY deployment",
+		},
+		"external service without a primary task set": {
+			mock: mockECS{out: &ecs.Service{
+				DeploymentController: controller("EXTERNAL"),
+				TaskSets:             []*awsecs.TaskSet{{Status: aws.String("ACTIVE")}},
+			}},
+			opts:      []ECSDeploymentStreamerOpt{WithTaskSets()},
+			wantedErr: "service is not watchable: my-svc has no PRIMARY task set",
+		},
+		"watchable service": {
+			mock: mockECS{out: &ecs.Service{
+				Deployments: []*awsecs.Deployment{primary},
+			}},
+			wantedWatchable: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(tc.mock, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			// WHEN
+			err := streamer.Start(context.Background())
+
+			// THEN
+			switch {
+			case tc.wantedWatchable:
+				require.NoError(t, err)
+				require.Len(t, streamer.eventsToFlush, 1, "the first fetch should be stored")
+			case tc.wantedNotFound:
+				require.True(t, errors.Is(err, ecs.ErrServiceNotFound))
+			default:
+				require.EqualError(t, err, tc.wantedErr)
+				require.Empty(t, streamer.eventsToFlush)
+			}
+		})
+	}
+	t.Run("returns the error of a canceled context without fetching", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{out: &ecs.Service{}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// WHEN
+		err := streamer.Start(ctx)
+
+		// THEN
+		require.True(t, errors.Is(err, context.Canceled))
+		require.Zero(t, m.calls)
+	})
+	t.Run("returns the error of the context canceled while describing the service", func(t *testing.T) {
+		// GIVEN
+		m := slowECS{release: make(chan struct{})}
+		defer close(m.release)
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		// WHEN
+		err := streamer.Start(ctx)
+
+		// THEN
+		require.True(t, errors.Is(err, context.DeadlineExceeded))
+		require.Empty(t, streamer.eventsToFlush)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchRetry(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	throttleErr := awserr.New("ThrottlingException", "Rate exceeded", nil)
+	t.Run("retries transient errors without emitting an event", func(t *testing.T) {
+		// GIVEN
+		var retried []error
+		streamer := NewECSDeploymentStreamer(mockECS{err: throttleErr}, "my-cluster", "my-svc", startDate, WithOnFetchError(func(err error) {
+			retried = append(retried, err)
+		}))
+		streamer.now = func() time.Time { return startDate }
+
+		// WHEN
+		next, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, startDate.Add(streamerFetchIntervalDuration), next)
+		require.Empty(t, streamer.eventsToFlush)
+		require.Len(t, retried, 1)
+		require.True(t, errors.Is(retried[0], throttleErr))
+	})
+	t.Run("returns errors that are not transient without calling the callback", func(t *testing.T) {
+		// GIVEN
+		called := false
+		streamer := NewECSDeploymentStreamer(mockECS{err: errors.New("some error")}, "my-cluster", "my-svc", startDate, WithOnFetchError(func(err error) {
+			called = true
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.EqualError(t, err, "fetch service description: some error")
+		require.False(t, called)
+	})
+	t.Run("gives up after too many consecutive transient errors", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{err: throttleErr}, "my-cluster", "my-svc", startDate)
+		for i := 0; i < defaultMaxECSFetchRetries; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, throttleErr))
+	})
+	t.Run("resets the retry count on a successful fetch", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{err: throttleErr}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		for i := 0; i < defaultMaxECSFetchRetries; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+		m.err, m.out = nil, &ecs.Service{}
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		m.err = throttleErr
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+	})
+	t.Run("classifies the errors WithIsRetryable", func(t *testing.T) {
+		// GIVEN
+		customErr := errors.New("some custom error")
+		var classified, retried []error
+		isRetryable := func(err error) bool {
+			classified = append(classified, err)
+			return errors.Is(err, customErr)
+		}
+		m := &mockECSErrors{errs: []error{customErr, throttleErr}, out: &ecs.Service{}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithIsRetryable(isRetryable), WithOnFetchError(func(err error) {
+			retried = append(retried, err)
+		}))
+
+		// WHEN
+		_, errCustom := streamer.Fetch()
+		_, errThrottle := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, errCustom, "the custom error should be retried")
+		require.True(t, errors.Is(errThrottle, throttleErr), "throttling should be fatal for the custom classifier")
+		require.Len(t, classified, 2)
+		require.Len(t, retried, 1)
+		require.True(t, errors.Is(retried[0], customErr))
+	})
+}
+
+// mockECSErrors returns each of the errors in order, and then the service description.
+type mockECSErrors struct {
+	errs  []error
+	out   *ecs.Service
+	calls int
+}
+
+func (m *mockECSErrors) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	m.calls++
+	if m.calls <= len(m.errs) {
+		return nil, m.errs[m.calls-1]
+	}
+	return m.out, nil
+}
+
+func TestECSDeploymentStreamer_FetchAccessDenied(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	deniedErr := awserr.New("AccessDeniedException", "User is not authorized to perform: ecs:DescribeServices", nil)
+	t.Run("retries AccessDenied errors until the permissions propagate", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{
+			errs: []error{deniedErr, deniedErr, deniedErr},
+			out: &ecs.Service{
+				Deployments: []*awsecs.Deployment{
+					{
+						DesiredCount:   aws.Int64(1),
+						RunningCount:   aws.Int64(0),
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+					},
 				},
 			},
+		}
+		var retried []error
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithOnFetchError(func(err error) {
+			retried = append(retried, err)
+		}))
+		now := startDate
+		streamer.now = func() time.Time { return now }
+
+		// WHEN
+		for i := 0; i < len(m.errs); i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			now = now.Add(streamerFetchIntervalDuration)
+		}
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, retried, 3)
+		require.True(t, errors.Is(retried[0], deniedErr))
+		require.Len(t, streamer.eventsToFlush, 1)
+	})
+	t.Run("returns AccessDenied errors once the grace period elapsed", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{errs: []error{deniedErr, deniedErr}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithAccessDeniedGracePeriod(time.Minute))
+		now := startDate
+		streamer.now = func() time.Time { return now }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		now = now.Add(time.Minute)
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, deniedErr))
+	})
+	t.Run("returns AccessDenied errors right away without a grace period", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{errs: []error{deniedErr}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithAccessDeniedGracePeriod(0))
+		streamer.now = func() time.Time { return startDate }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, deniedErr))
+	})
+}
+
+func TestECSDeploymentStreamer_FetchExpiredCredentials(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	expiredErr := awserr.New("ExpiredTokenException", "The security token included in the request is expired", nil)
+	out := &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				DesiredCount:   aws.Int64(1),
+				RunningCount:   aws.Int64(0),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+			},
 		},
 	}
-	sub := make(chan ECSService, 2)
-	streamer := &ECSDeploymentStreamer{
-		subscribers:   []chan ECSService{sub},
-		eventsToFlush: wantedEvents,
+	t.Run("refreshes the credentials and retries once they expired", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{errs: []error{expiredErr}, out: out}
+		var refreshed int
+		var retried []error
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+			WithCredentialsRefresher(func() error {
+				refreshed++
+				return nil
+			}),
+			WithOnFetchError(func(err error) {
+				retried = append(retried, err)
+			}))
+		streamer.now = func() time.Time { return startDate }
+
+		// WHEN
+		next, errExpired := streamer.Fetch()
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, errExpired)
+		require.Equal(t, startDate.Add(streamerFetchIntervalDuration), next)
+		require.NoError(t, err)
+		require.Equal(t, 1, refreshed)
+		require.Len(t, retried, 1)
+		require.True(t, errors.Is(retried[0], expiredErr))
+		require.Len(t, streamer.eventsToFlush, 1)
+		require.Zero(t, streamer.expiredRetries, "a successful fetch should reset the count")
+	})
+	t.Run("gives up after too many consecutive expired credentials errors", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{err: expiredErr}, "my-cluster", "my-svc", startDate)
+		for i := 0; i < defaultMaxECSExpiredCredsRetry; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, expiredErr))
+	})
+	t.Run("returns the error of the refresher", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSErrors{errs: []error{expiredErr}, out: out}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithCredentialsRefresher(func() error {
+			return errors.New("assume role: some error")
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.EqualError(t, err, "refresh expired credentials: assume role: some error")
+	})
+}
+
+// mockECSClusters describes the services of the clusters, reporting services outside of them as missing.
+type mockECSClusters map[string]*ecs.Service
+
+func (m mockECSClusters) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	if out, ok := m[clusterName]; ok {
+		return out, nil
 	}
+	return nil, &ecs.ErrServiceFailure{
+		Service: serviceName,
+		Reason:  "MISSING",
+	}
+}
 
-	// WHEN
-	streamer.Notify()
-	close(sub) // Close the channel to stop expecting to receive new events.
+// mockECSDeletedCluster reports the cluster as not found, and describes the services of the other clusters.
+type mockECSDeletedCluster struct {
+	cluster  string
+	clusters mockECSClusters
+}
 
-	// THEN
-	var actualEvents []ECSService
-	for event := range sub {
-		actualEvents = append(actualEvents, event)
+func (m mockECSDeletedCluster) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	if clusterName == m.cluster {
+		return nil, fmt.Errorf("describe service %s: %w", serviceName, awserr.New(awsecs.ErrCodeClusterNotFoundException, "Cluster not found.", nil))
 	}
-	require.ElementsMatch(t, wantedEvents, actualEvents)
+	return m.clusters.Service(clusterName, serviceName)
 }
 
-func TestECSDeploymentStreamer_Close(t *testing.T) {
-	// GIVEN
-	streamer := &ECSDeploymentStreamer{}
-	c := streamer.Subscribe()
+func TestECSDeploymentStreamer_FetchClusterResolver(t *testing.T) {
+	clusters := mockECSClusters{
+		"new-cluster": &ecs.Service{},
+	}
+	t.Run("errors if the service is not found without a resolver", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(clusters, "old-cluster", "my-svc", time.Now())
 
-	// WHEN
-	streamer.Close()
+		// WHEN
+		_, err := streamer.Fetch()
 
-	// THEN
-	_, isOpen := <-c
-	require.False(t, isOpen, "expected subscribed channels to be closed")
+		// THEN
+		require.True(t, errors.Is(err, ecs.ErrServiceNotFound))
+	})
+	t.Run("continues streaming in the resolved cluster", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(clusters, "old-cluster", "my-svc", time.Now(), WithClusterResolver(func(service string) (string, error) {
+			return "new-cluster", nil
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "new-cluster", streamer.Cluster())
+		require.Equal(t, []ECSNotice{
+			{
+				Kind:     ECSNoticeClusterChanged,
+				Severity: ECSNoticeInfo,
+				Message:  "service my-svc is no longer in cluster old-cluster, watching it in cluster new-cluster",
+			},
+		}, streamer.eventsToFlush[0].Notices)
+	})
+	t.Run("continues streaming in the resolved cluster if the cluster is not found", func(t *testing.T) {
+		// GIVEN
+		m := mockECSDeletedCluster{cluster: "old-cluster", clusters: clusters}
+		streamer := NewECSDeploymentStreamer(m, "old-cluster", "my-svc", time.Now(), WithClusterResolver(func(service string) (string, error) {
+			return "new-cluster", nil
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, "new-cluster", streamer.Cluster())
+	})
+	t.Run("stops if the resolver errors", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(clusters, "old-cluster", "my-svc", time.Now(), WithClusterResolver(func(service string) (string, error) {
+			return "", errors.New("some error")
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.EqualError(t, err, "resolve cluster of service my-svc: some error")
+	})
+	t.Run("errors if the service is not found in the resolved cluster either", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(clusters, "old-cluster", "my-svc", time.Now(), WithClusterResolver(func(service string) (string, error) {
+			return "other-cluster", nil
+		}))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.True(t, errors.Is(err, ecs.ErrServiceNotFound))
+	})
+}
+
+// slowECS blocks describing the service until release is closed.
+type slowECS struct {
+	release chan struct{}
+}
+
+func (m slowECS) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	<-m.release
+	return &ecs.Service{}, nil
+}
+
+func TestECSDeploymentStreamer_FetchTimeout(t *testing.T) {
+	// GIVEN
+	m := slowECS{release: make(chan struct{})}
+	defer close(m.release)
+	var retried []error
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(),
+		WithFetchTimeout(10*time.Millisecond),
+		WithOnFetchError(func(err error) {
+			retried = append(retried, err)
+		}))
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err, "timeouts should be retried")
+	require.Empty(t, streamer.eventsToFlush)
+	require.Len(t, retried, 1)
+	require.EqualError(t, retried[0], "fetch service description: describe service timed out after 10ms")
+}
+
+func TestECSDeploymentStreamer_FetchRevisionChange(t *testing.T) {
+	// GIVEN
+	primary := &awsecs.Deployment{
+		DesiredCount:   aws.Int64(2),
+		RunningCount:   aws.Int64(0),
+		Status:         aws.String("PRIMARY"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+	}
+	m := mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{primary},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+
+	// WHEN
+	_, err := streamer.Fetch()
+	require.NoError(t, err)
+	primary.TaskDefinition = aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3")
+	_, err = streamer.Fetch()
+	require.NoError(t, err)
+	_, err = streamer.Fetch()
+	require.NoError(t, err)
+
+	// THEN
+	require.Len(t, streamer.eventsToFlush, 3)
+	require.Nil(t, streamer.eventsToFlush[0].Notices, "no warning expected while the revision is unchanged")
+	require.Equal(t, []ECSNotice{
+		{
+			Kind:     ECSNoticeRevisionChanged,
+			Severity: ECSNoticeWarning,
+			Message:  "primary deployment changed from revision 2 to 3, another deployment may have started concurrently",
+		},
+	}, streamer.eventsToFlush[1].Notices)
+	require.Nil(t, streamer.eventsToFlush[2].Notices, "the warning should only be emitted once per change")
+}
+
+func TestECSDeploymentStreamer_FetchExpectedRevision(t *testing.T) {
+	deployment := func(status, revision string, running int64, rolloutState string) *awsecs.Deployment {
+		return &awsecs.Deployment{
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(running),
+			Status:         aws.String(status),
+			RolloutState:   aws.String(rolloutState),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:" + revision),
+		}
+	}
+	testCases := map[string]struct {
+		deployments []*awsecs.Deployment
+		opts        []ECSDeploymentStreamerOpt
+
+		wantedOutcome ECSDeploymentOutcome
+		wantedReason  string
+	}{
+		"succeeds if the primary deployment is on the expected revision": {
+			deployments:   []*awsecs.Deployment{deployment("PRIMARY", "3", 2, "COMPLETED")},
+			opts:          []ECSDeploymentStreamerOpt{WithExpectedRevision("3")},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"fails if the primary deployment diverges from the expected revision": {
+			deployments:   []*awsecs.Deployment{deployment("PRIMARY", "3", 2, "COMPLETED")},
+			opts:          []ECSDeploymentStreamerOpt{WithExpectedRevision("2")},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "primary deployment is on revision 3 instead of the expected revision 2, another deployment may have overwritten it",
+		},
+		"keeps waiting while a deployment of the service is still on the expected revision": {
+			deployments: []*awsecs.Deployment{
+				deployment("PRIMARY", "3", 1, "IN_PROGRESS"),
+				deployment("ACTIVE", "2", 2, "COMPLETED"),
+			},
+			opts: []ECSDeploymentStreamerOpt{WithExpectedRevision("2")},
+		},
+		"fails once the deployment on another revision completes": {
+			deployments: []*awsecs.Deployment{
+				deployment("PRIMARY", "3", 2, "IN_PROGRESS"),
+				deployment("ACTIVE", "2", 1, "COMPLETED"),
+			},
+			opts:          []ECSDeploymentStreamerOpt{WithExpectedRevision("2")},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "primary deployment is on revision 3 instead of the expected revision 2, another deployment may have overwritten it",
+		},
+		"succeeds on any revision by default": {
+			deployments:   []*awsecs.Deployment{deployment("PRIMARY", "3", 2, "COMPLETED")},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: tc.deployments,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), tc.opts...)
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedReason, streamer.FailureReason())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchDeploymentID(t *testing.T) {
+	deployment := func(id, status string, running int64, rolloutState string) *awsecs.Deployment {
+		return &awsecs.Deployment{
+			Id:             aws.String(id),
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(running),
+			Status:         aws.String(status),
+			RolloutState:   aws.String(rolloutState),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+		}
+	}
+	testCases := map[string]struct {
+		deployments []*awsecs.Deployment
+
+		wantedOutcome ECSDeploymentOutcome
+		wantedReason  string
+	}{
+		"waits while the watched deployment is in progress": {
+			deployments: []*awsecs.Deployment{
+				deployment("ecs-svc/1", "PRIMARY", 1, "IN_PROGRESS"),
+			},
+		},
+		"succeeds once the watched deployment reaches its running target": {
+			deployments: []*awsecs.Deployment{
+				deployment("ecs-svc/1", "PRIMARY", 2, "COMPLETED"),
+			},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"fails if the rollout of the watched deployment fails": {
+			deployments: []*awsecs.Deployment{
+				deployment("ecs-svc/0", "PRIMARY", 2, "IN_PROGRESS"),
+				deployment("ecs-svc/1", "ACTIVE", 0, "FAILED"),
+			},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "deployment failed",
+		},
+		"is superseded once another deployment is primary": {
+			deployments: []*awsecs.Deployment{
+				deployment("ecs-svc/2", "PRIMARY", 2, "COMPLETED"),
+				deployment("ecs-svc/1", "ACTIVE", 2, "IN_PROGRESS"),
+			},
+			wantedOutcome: ECSDeploymentSuperseded,
+			wantedReason:  "deployment ecs-svc/1 was superseded by deployment ecs-svc/2",
+		},
+		"is superseded if the watched deployment is gone": {
+			deployments: []*awsecs.Deployment{
+				deployment("ecs-svc/2", "PRIMARY", 1, "IN_PROGRESS"),
+			},
+			wantedOutcome: ECSDeploymentSuperseded,
+			wantedReason:  "deployment ecs-svc/1 is no longer a deployment of the service",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: tc.deployments,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), WithDeploymentID("ecs-svc/1"))
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedReason, streamer.FailureReason())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_ObservedRevisions(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	primary := &awsecs.Deployment{
+		DesiredCount: aws.Int64(2),
+		RunningCount: aws.Int64(0),
+		Status:       aws.String("PRIMARY"),
+	}
+	streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}}, "my-cluster", "my-svc", startDate)
+	for i, revision := range []string{"2", "2", "3", "2"} {
+		now := startDate.Add(time.Duration(i) * time.Minute)
+		streamer.now = func() time.Time { return now }
+		primary.TaskDefinition = aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:" + revision)
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+	}
+
+	// WHEN
+	revisions := streamer.ObservedRevisions()
+
+	// THEN
+	require.Equal(t, []ECSObservedRevision{
+		{
+			Revision:  "2",
+			FirstSeen: startDate,
+			LastSeen:  startDate.Add(3 * time.Minute),
+		},
+		{
+			Revision:  "3",
+			FirstSeen: startDate.Add(2 * time.Minute),
+			LastSeen:  startDate.Add(2 * time.Minute),
+		},
+	}, revisions)
+}
+
+func TestECSDeploymentStreamer_FetchSerialRollout(t *testing.T) {
+	newService := func(maxPercent int64) *ecs.Service {
+		return &ecs.Service{
+			DeploymentConfiguration: &awsecs.DeploymentConfiguration{
+				MaximumPercent: aws.Int64(maxPercent),
+			},
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(0),
+					PendingCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+				},
+				{
+					DesiredCount:   aws.Int64(1),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("ACTIVE"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+	}
+	t.Run("explains a serial rollout once", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService(100)}, "my-cluster", "my-svc", time.Now())
+
+		// WHEN
+		for i := 0; i < 2; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// THEN
+		require.Equal(t, []ECSNotice{
+			{
+				Kind:     ECSNoticeSerialRollout,
+				Severity: ECSNoticeInfo,
+				Message:  "the deployment configuration allows at most 100% of the desired tasks to run, so old tasks must stop before new ones start and the rollout happens in steps",
+			},
+		}, streamer.eventsToFlush[0].Notices)
+		require.Nil(t, streamer.eventsToFlush[1].Notices)
+	})
+	t.Run("does not emit a notice if new tasks can start alongside old ones", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService(200)}, "my-cluster", "my-svc", time.Now())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Nil(t, streamer.eventsToFlush[0].Notices)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchDesiredCountChange(t *testing.T) {
+	newService := func(id string, desired int64) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					Id:             aws.String(id),
+					DesiredCount:   aws.Int64(desired),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+				},
+			},
+		}
+	}
+	testCases := map[string]struct {
+		services []*ecs.Service
+
+		wantedNotices []ECSNotice
+	}{
+		"explains that auto scaling raised the desired count mid-deploy": {
+			services: []*ecs.Service{newService("ecs-svc/1", 3), newService("ecs-svc/1", 5)},
+			wantedNotices: []ECSNotice{
+				{
+					Kind:     ECSNoticeDesiredCountChanged,
+					Severity: ECSNoticeInfo,
+					Message:  "desired count raised from 3 to 5 outside of the deployment, likely by service auto scaling",
+				},
+			},
+		},
+		"explains that auto scaling lowered the desired count mid-deploy": {
+			services: []*ecs.Service{newService("ecs-svc/1", 5), newService("ecs-svc/1", 3)},
+			wantedNotices: []ECSNotice{
+				{
+					Kind:     ECSNoticeDesiredCountChanged,
+					Severity: ECSNoticeInfo,
+					Message:  "desired count lowered from 5 to 3 outside of the deployment, likely by service auto scaling",
+				},
+			},
+		},
+		"does not emit a notice if the desired count changes with a new deployment": {
+			services: []*ecs.Service{newService("ecs-svc/1", 3), newService("ecs-svc/2", 5)},
+		},
+		"does not emit a notice if the desired count is unchanged": {
+			services: []*ecs.Service{newService("ecs-svc/1", 3), newService("ecs-svc/1", 3)},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECS{out: tc.services[0]}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			m.out = tc.services[1]
+
+			// WHEN
+			_, err = streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Nil(t, streamer.eventsToFlush[0].Notices)
+			require.Equal(t, tc.wantedNotices, streamer.eventsToFlush[1].Notices)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_PhaseLog(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	type fetch struct {
+		at           time.Duration // Time since startDate.
+		rolloutState string
+		running      int64
+	}
+	fetches := []fetch{
+		{at: 0, rolloutState: "IN_PROGRESS", running: 0},
+		{at: time.Minute, rolloutState: "IN_PROGRESS", running: 1},
+		{at: 2 * time.Minute, rolloutState: "IN_PROGRESS", running: 1},
+		{at: 3 * time.Minute, rolloutState: "COMPLETED", running: 2},
+	}
+	testCases := map[string]struct {
+		opts []ECSDeploymentStreamerOpt
+
+		wantedPhases []ECSRolloutPhase
+	}{
+		"coalesces consecutive rollout states into phases": {
+			opts: []ECSDeploymentStreamerOpt{WithPhaseLog()},
+			wantedPhases: []ECSRolloutPhase{
+				{State: "IN_PROGRESS", StartedAt: startDate, EndedAt: startDate.Add(3 * time.Minute)},
+				{State: "COMPLETED", StartedAt: startDate.Add(3 * time.Minute), EndedAt: startDate.Add(3 * time.Minute)},
+			},
+		},
+		"does not record phases by default": {
+			wantedPhases: []ECSRolloutPhase{},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			primary := &awsecs.Deployment{
+				DesiredCount:   aws.Int64(2),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			}
+			m := &mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{primary},
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+
+			// WHEN
+			for _, f := range fetches {
+				now := startDate.Add(f.at)
+				streamer.now = func() time.Time { return now }
+				primary.RolloutState, primary.RunningCount = aws.String(f.rolloutState), aws.Int64(f.running)
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			require.Equal(t, tc.wantedPhases, streamer.PhaseLog())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchEventsUnavailable(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	newService := func(failed int64, events ...*awsecs.ServiceEvent) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(0),
+					FailedTasks:    aws.Int64(failed),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+				},
+			},
+			Events: events,
+		}
+	}
+	event := &awsecs.ServiceEvent{
+		Id:        aws.String("1"),
+		Message:   aws.String("(service my-svc) failed to launch a task."),
+		CreatedAt: aws.Time(startDate.Add(time.Minute)),
+	}
+	warning := ECSNotice{
+		Kind:     ECSNoticeEventsUnavailable,
+		Severity: ECSNoticeWarning,
+		Message:  "2 tasks failed but the service has no events, failure details may be unavailable due to restricted permissions",
+	}
+	testCases := map[string]struct {
+		services []*ecs.Service
+
+		wantedNotices [][]ECSNotice // Notices of each snapshot.
+	}{
+		"warns once after several fetches with failed tasks but no events": {
+			services:      []*ecs.Service{newService(1), newService(2), newService(2), newService(2)},
+			wantedNotices: [][]ECSNotice{nil, nil, {warning}, nil},
+		},
+		"does not warn if the service has events": {
+			services:      []*ecs.Service{newService(1, event), newService(2, event), newService(2, event)},
+			wantedNotices: [][]ECSNotice{nil, nil, nil},
+		},
+		"does not warn if no task failed": {
+			services:      []*ecs.Service{newService(0), newService(0), newService(0)},
+			wantedNotices: [][]ECSNotice{nil, nil, nil},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: tc.services}, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			// WHEN
+			for range tc.services {
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			var notices [][]ECSNotice
+			for _, ev := range streamer.eventsToFlush {
+				notices = append(notices, ev.Notices)
+			}
+			require.Equal(t, tc.wantedNotices, notices)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchTaskSets(t *testing.T) {
+	taskSet := func(stability string) *awsecs.TaskSet {
+		return &awsecs.TaskSet{
+			Status:               aws.String("PRIMARY"),
+			TaskDefinition:       aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:4"),
+			ComputedDesiredCount: aws.Int64(2),
+			RunningCount:         aws.Int64(1),
+			PendingCount:         aws.Int64(1),
+			StabilityStatus:      aws.String(stability),
+		}
+	}
+	t.Run("keeps streaming while the primary task set is stabilizing", func(t *testing.T) {
+		// GIVEN
+		m := mockECS{
+			out: &ecs.Service{
+				Deployments: []*awsecs.Deployment{
+					{
+						Status:       aws.String("PRIMARY"),
+						DesiredCount: aws.Int64(0),
+						RunningCount: aws.Int64(0),
+					},
+				},
+				TaskSets: []*awsecs.TaskSet{taskSet(awsecs.StabilityStatusStabilizing)},
+			},
+		}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), WithTaskSets())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, []ECSDeployment{
+			{
+				Status:          "PRIMARY",
+				TaskDefRevision: "4",
+				DesiredCount:    2,
+				RunningCount:    1,
+				PendingCount:    1,
+				StabilityStatus: "STABILIZING",
+			},
+		}, streamer.eventsToFlush[0].Deployments)
+		select {
+		case <-streamer.Done():
+			require.Fail(t, "the deployments of the service should be ignored")
+		default:
+		}
+	})
+	t.Run("is done once the primary task set reaches a steady state", func(t *testing.T) {
+		// GIVEN
+		m := mockECS{
+			out: &ecs.Service{
+				TaskSets: []*awsecs.TaskSet{taskSet(awsecs.StabilityStatusSteadyState)},
+			},
+		}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), WithTaskSets())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		require.Equal(t, ECSDeploymentSucceeded, streamer.Outcome())
+	})
+}
+
+func TestECSDeploymentStreamer_FetchFailuresSincePrimary(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	event := func(id string, at time.Duration) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(fmt.Sprintf("(service my-svc) failed to register targets in (target-group %s)", id)),
+			CreatedAt: aws.Time(startDate.Add(at)),
+		}
+	}
+	primary := func(revision string, createdAt time.Duration, rolloutState string) *awsecs.Deployment {
+		return &awsecs.Deployment{
+			DesiredCount:       aws.Int64(2),
+			RunningCount:       aws.Int64(0),
+			Status:             aws.String("PRIMARY"),
+			RolloutState:       aws.String(rolloutState),
+			RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+			TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:" + revision),
+			CreatedAt:          aws.Time(startDate.Add(createdAt)),
+		}
+	}
+	// The revision 3 is deployed over the revision 2 mid-watch, after the failure event 2 of the revision 2.
+	before := &ecs.Service{
+		Deployments: []*awsecs.Deployment{primary("2", 0, "IN_PROGRESS")},
+		Events:      []*awsecs.ServiceEvent{event("1", time.Minute)},
+	}
+	after := &ecs.Service{
+		Deployments: []*awsecs.Deployment{primary("3", 5*time.Minute, "FAILED")},
+		Events: []*awsecs.ServiceEvent{
+			{
+				Id:        aws.String("3"),
+				Message:   aws.String("(service my-svc) was unable to place a task."),
+				CreatedAt: aws.Time(startDate.Add(6 * time.Minute)),
+			},
+			event("2", 2*time.Minute),
+			event("1", time.Minute),
+		},
+	}
+	testCases := map[string]struct {
+		opts []ECSDeploymentStreamerOpt
+
+		wantedFailures []string
+		wantedReason   string
+	}{
+		"ignores the failures of the previous revision": {
+			opts: []ECSDeploymentStreamerOpt{WithFailuresSincePrimary()},
+			wantedFailures: []string{
+				"(service my-svc) was unable to place a task.",
+			},
+			wantedReason: "ECS deployment circuit breaker: tasks failed to start.",
+		},
+		"reports all the failures since the deployment creation time by default": {
+			wantedFailures: []string{
+				"(service my-svc) was unable to place a task.",
+				"(service my-svc) failed to register targets in (target-group 2)",
+			},
+			wantedReason: "(service my-svc) failed to register targets in (target-group 2)",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECSSequence{outs: []*ecs.Service{before, after}}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+
+			// WHEN
+			_, err = streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, []string{"(service my-svc) failed to register targets in (target-group 1)"}, streamer.eventsToFlush[0].LatestFailureEvents)
+			require.Equal(t, tc.wantedFailures, streamer.eventsToFlush[1].LatestFailureEvents)
+			require.Equal(t, tc.wantedReason, streamer.FailureReason())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchMaxInitialEventAge(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	now := startDate.Add(time.Hour)
+	failureEvent := func(id string, createdAt time.Time) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(fmt.Sprintf("(service my-svc) failed to launch a task %s.", id)),
+			CreatedAt: aws.Time(createdAt),
+		}
+	}
+	m := &mockECS{
+		out: &ecs.Service{
+			Events: []*awsecs.ServiceEvent{
+				failureEvent("2", now.Add(-time.Minute)),
+				failureEvent("1", now.Add(-30*time.Minute)),
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithMaxInitialEventAge(10*time.Minute))
+	streamer.now = func() time.Time { return now }
+
+	// WHEN
+	_, err := streamer.Fetch()
+	require.NoError(t, err)
+	m.out.Events = append([]*awsecs.ServiceEvent{failureEvent("3", now)}, m.out.Events...)
+	_, err = streamer.Fetch()
+	require.NoError(t, err)
+
+	// THEN
+	require.Equal(t, []string{"(service my-svc) failed to launch a task 2."}, streamer.eventsToFlush[0].LatestFailureEvents)
+	require.Equal(t, []string{"(service my-svc) failed to launch a task 3."}, streamer.eventsToFlush[1].LatestFailureEvents)
+}
+
+func TestECSDeploymentStreamer_FetchMaxEventLookback(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	now := startDate.Add(30 * 24 * time.Hour)
+	failureEvent := func(id string, createdAt time.Time) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(fmt.Sprintf("(service my-svc) failed to launch a task %s.", id)),
+			CreatedAt: aws.Time(createdAt),
+		}
+	}
+	newService := func() *ecs.Service {
+		return &ecs.Service{
+			Events: []*awsecs.ServiceEvent{
+				failureEvent("2", now.Add(-time.Hour)),
+				failureEvent("1", startDate.Add(time.Hour)),
+			},
+		}
+	}
+	t.Run("clamps a very old deployment creation time and warns", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService()}, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time { return now }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, []string{"(service my-svc) failed to launch a task 2."}, streamer.eventsToFlush[0].LatestFailureEvents)
+		require.Equal(t, []ECSNotice{
+			{
+				Kind:     ECSNoticeEventLookbackClamped,
+				Severity: ECSNoticeWarning,
+				Message:  "the deployment creation time 2020-11-23T18:00:00Z is more than 24h0m0s ago, ignoring the service events created before 2020-12-22T18:00:00Z",
+			},
+		}, streamer.eventsToFlush[0].Notices)
+	})
+	t.Run("doesn't bound the lookback WithMaxEventLookback zero", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService()}, "my-cluster", "my-svc", startDate, WithMaxEventLookback(0))
+		streamer.now = func() time.Time { return now }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"(service my-svc) failed to launch a task 2.",
+			"(service my-svc) failed to launch a task 1.",
+		}, streamer.eventsToFlush[0].LatestFailureEvents)
+		require.Empty(t, streamer.eventsToFlush[0].Notices)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchSpotInterruption(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	msg := "(service my-svc) has stopped 1 running tasks: (task 1234). Reason: Your Spot Task was interrupted due to an error."
+	m := mockECS{
+		out: &ecs.Service{
+			Events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String(msg),
+					CreatedAt: aws.Time(startDate.Add(time.Minute)),
+				},
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err)
+	require.Nil(t, streamer.eventsToFlush[0].LatestFailureEvents, "spot interruptions are not failures")
+	require.Equal(t, []ECSNotice{
+		{
+			Kind:     ECSNoticeSpotInterruption,
+			Severity: ECSNoticeInfo,
+			Message:  msg,
+		},
+	}, streamer.eventsToFlush[0].Notices)
+}
+
+func TestECSDeploymentStreamer_FetchDrainProgress(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(newRunning, oldRunning int64) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					Id:             aws.String("ecs-svc/2"),
+					DesiredCount:   aws.Int64(5),
+					RunningCount:   aws.Int64(newRunning),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+				{
+					Id:             aws.String("ecs-svc/1"),
+					DesiredCount:   aws.Int64(oldRunning),
+					RunningCount:   aws.Int64(oldRunning),
+					Status:         aws.String("ACTIVE"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+				},
+			},
+		}
+	}
+	outs := []*ecs.Service{service(1, 4), service(3, 2)}
+	t.Run("reports the drain progress of the previous deployment", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: outs}, "my-cluster", "my-svc", startDate, WithDrainProgress())
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+		// WHEN
+		for range outs {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// THEN
+		require.Equal(t, []ECSDrainProgress{{TaskDefRevision: "1", FromCount: 4, RunningCount: 4}}, streamer.eventsToFlush[0].Draining)
+		require.Equal(t, []ECSDrainProgress{{TaskDefRevision: "1", FromCount: 4, RunningCount: 2}}, streamer.eventsToFlush[1].Draining)
+	})
+	t.Run("doesn't report the drain progress by default", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: outs}, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Nil(t, streamer.eventsToFlush[0].Draining)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchLikelyToFail(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	msg := "(service my-svc) is unable to consistently start tasks successfully. For more information, see the Troubleshooting section of the Amazon ECS Developer Guide."
+	m := mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(0),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String("IN_PROGRESS"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+			Events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String(msg),
+					CreatedAt: aws.Time(startDate.Add(time.Minute)),
+				},
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err)
+	ev := streamer.eventsToFlush[0]
+	require.Nil(t, ev.LatestFailureEvents, "the early warning is not a failure on its own")
+	require.Equal(t, []ECSNotice{
+		{
+			Kind:     ECSNoticeLikelyToFail,
+			Severity: ECSNoticeWarning,
+			Message:  msg,
+		},
+	}, ev.Notices)
+	require.Nil(t, ev.Completion, "the deployment should not fail until ECS rolls it back")
+}
+
+func TestECSDeploymentStreamer_FetchFailureMessageFormatter(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	m := mockECS{
+		out: &ecs.Service{
+			Events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("3"),
+					Message:   aws.String("(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)"),
+					CreatedAt: aws.Time(startDate.Add(3 * time.Minute)),
+				},
+				{
+					Id:        aws.String("2"),
+					Message:   aws.String("(service my-svc) has reached a steady state."),
+					CreatedAt: aws.Time(startDate.Add(2 * time.Minute)),
+				},
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String("(service my-svc) was unable to place a task."),
+					CreatedAt: aws.Time(startDate.Add(time.Minute)),
+				},
+			},
+		},
+	}
+	var formatted []string
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+		WithFailureMessageFormatter(func(msg string, category ECSFailureCategory) string {
+			formatted = append(formatted, msg)
+			return fmt.Sprintf("[%s] %s", category, strings.TrimPrefix(msg, "(service my-svc) "))
+		}))
+	streamer.now = func() time.Time { return startDate.Add(3 * time.Minute) }
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)",
+		"(service my-svc) was unable to place a task.",
+	}, formatted, "the formatter should only receive the raw failure messages")
+	wanted := []string{
+		"[application] (task 1234) stopped: Essential container in task exited (exit code: 1)",
+		"[unknown] was unable to place a task.",
+	}
+	ev := streamer.eventsToFlush[0]
+	require.Equal(t, wanted, ev.LatestFailureEvents)
+	require.Len(t, ev.LatestFailures, 2)
+	for i, failure := range ev.LatestFailures {
+		require.Equal(t, wanted[i], failure.Message)
+	}
+}
+
+func TestECSDeploymentStreamer_FetchSummaryFailureCause(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	summary := "(service my-svc) (deployment ecs-svc/1234) deployment failed: tasks failed to start."
+	pullFailure := "(service my-svc) (task 1234) stopped: CannotPullContainerError: pull image manifest has been retried 5 time(s): failed to resolve ref my-svc:abc: not found"
+	event := func(id, msg string, at time.Duration) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(msg),
+			CreatedAt: aws.Time(startDate.Add(at)),
+		}
+	}
+	testCases := map[string]struct {
+		fetches [][]*awsecs.ServiceEvent // Service events of each Fetch, newest first.
+
+		wantedCause        string
+		wantedCauseMessage string
+	}{
+		"attributes the summary to the specific failure before it": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("3", summary, 3*time.Minute),
+					event("2", "(service my-svc) was unable to place a task.", 2*time.Minute),
+					event("1", pullFailure, time.Minute),
+				},
+			},
+			wantedCause:        "CannotPullContainerError",
+			wantedCauseMessage: pullFailure,
+		},
+		"attributes the summary to the most recent specific failure": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("3", summary, 3*time.Minute),
+					event("2", "(service my-svc) (task 5678) stopped: Essential container in task exited (exit code: 137)", 2*time.Minute),
+					event("1", pullFailure, time.Minute),
+				},
+			},
+			wantedCause:        "exit code 137",
+			wantedCauseMessage: "(service my-svc) (task 5678) stopped: Essential container in task exited (exit code: 137)",
+		},
+		"attributes the summary to a specific failure of a previous fetch": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("1", pullFailure, time.Minute),
+				},
+				{
+					event("2", summary, 3*time.Minute),
+					event("1", pullFailure, time.Minute),
+				},
+			},
+			wantedCause:        "CannotPullContainerError",
+			wantedCauseMessage: pullFailure,
+		},
+		"does not attribute the summary to a specific failure long before it": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("2", summary, ecsFailureCauseWindow+2*time.Minute),
+					event("1", pullFailure, time.Minute),
+				},
+			},
+		},
+		"does not attribute the summary to a specific failure after it": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("2", pullFailure, 2*time.Minute),
+					event("1", summary, time.Minute),
+				},
+			},
+		},
+		"does not attribute the summary without a specific failure": {
+			fetches: [][]*awsecs.ServiceEvent{
+				{
+					event("2", summary, 2*time.Minute),
+					event("1", "(service my-svc) was unable to place a task.", time.Minute),
+				},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			var outs []*ecs.Service
+			for _, events := range tc.fetches {
+				outs = append(outs, &ecs.Service{Events: events})
+			}
+			streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: outs}, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time { return startDate.Add(time.Hour) }
+
+			// WHEN
+			for range tc.fetches {
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			var got *ECSServiceFailure
+			for _, ev := range streamer.eventsToFlush {
+				for i, failure := range ev.LatestFailures {
+					if failure.Message == summary {
+						got = &ev.LatestFailures[i]
+					}
+				}
+			}
+			require.NotNil(t, got, "the summary should be reported as a failure")
+			require.Equal(t, tc.wantedCause, got.Cause)
+			require.Equal(t, tc.wantedCauseMessage, got.CauseMessage)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchHealthCheckGracePeriod(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	inGrace := "(service my-svc) (port 80) is unhealthy in (target-group 1234) due to (reason Request timed out), but (task 1234) is within the health check grace period."
+	expired := "(service my-svc) (task 1234) stopped: Task failed ELB health checks in (target-group 1234)."
+	m := mockECS{
+		out: &ecs.Service{
+			Events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("2"),
+					Message:   aws.String(expired),
+					CreatedAt: aws.Time(startDate.Add(2 * time.Minute)),
+				},
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String(inGrace),
+					CreatedAt: aws.Time(startDate.Add(time.Minute)),
+				},
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+	streamer.now = func() time.Time { return startDate.Add(3 * time.Minute) }
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err)
+	require.Equal(t, []ECSServiceFailure{
+		{Message: expired, Category: ECSFailureCategoryHealthCheck, TargetGroup: "1234"},
+	}, streamer.eventsToFlush[0].LatestFailures, "only the tasks stopped after the grace period are failures")
+	require.Equal(t, []ECSNotice{
+		{
+			Kind:     ECSNoticeHealthCheckGracePeriod,
+			Severity: ECSNoticeInfo,
+			Message:  inGrace,
+		},
+	}, streamer.eventsToFlush[0].Notices)
+}
+
+func TestECSDeploymentStreamer_FetchTaskPlacement(t *testing.T) {
+	svc := &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				Id:             aws.String("ecs-svc/2"),
+				DesiredCount:   aws.Int64(4),
+				RunningCount:   aws.Int64(1),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			},
+			{
+				Id:             aws.String("ecs-svc/1"),
+				DesiredCount:   aws.Int64(4),
+				RunningCount:   aws.Int64(4),
+				Status:         aws.String("ACTIVE"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+			},
+		},
+	}
+	task := func(startedBy, az, status string) *ecs.Task {
+		return &ecs.Task{
+			StartedBy:        aws.String(startedBy),
+			AvailabilityZone: aws.String(az),
+			LastStatus:       aws.String(status),
+		}
+	}
+	testCases := map[string]struct {
+		tasks mockECSTasks
+
+		wantedPlacement []ECSTaskPlacement
+		wantedErr       error
+	}{
+		"aggregates tasks of the primary deployment by availability zone": {
+			tasks: mockECSTasks{
+				out: []*ecs.Task{
+					task("ecs-svc/2", "us-west-2b", "PENDING"),
+					task("ecs-svc/2", "us-west-2a", "RUNNING"),
+					task("ecs-svc/2", "us-west-2b", "PROVISIONING"),
+					task("ecs-svc/1", "us-west-2a", "RUNNING"),
+					task("ecs-svc/2", "", "PROVISIONING"),
+				},
+			},
+			wantedPlacement: []ECSTaskPlacement{
+				{AvailabilityZone: "", PendingCount: 1},
+				{AvailabilityZone: "us-west-2a", RunningCount: 1},
+				{AvailabilityZone: "us-west-2b", PendingCount: 2},
+			},
+		},
+		"returns no placement if there are no tasks yet": {
+			tasks: mockECSTasks{},
+		},
+		"returns a wrapped error if tasks can't be described": {
+			tasks: mockECSTasks{
+				err: errors.New("some error"),
+			},
+			wantedErr: errors.New("describe tasks of service my-svc: some error"),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(mockECS{out: svc}, "my-cluster", "my-svc", time.Now(), WithTaskPlacement(tc.tasks))
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			if tc.wantedErr != nil {
+				require.EqualError(t, err, tc.wantedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedPlacement, streamer.eventsToFlush[0].TaskPlacement)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchTaskTransitions(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	fetchedAt := startDate.Add(time.Minute)
+	svc := &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				Id:             aws.String("ecs-svc/2"),
+				DesiredCount:   aws.Int64(3),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			},
+		},
+	}
+	task := func(arn, status string) *ecs.Task {
+		return &ecs.Task{
+			TaskArn:    aws.String(arn),
+			StartedBy:  aws.String("ecs-svc/2"),
+			LastStatus: aws.String(status),
+		}
+	}
+	tasks := &mockECSTasks{}
+	streamer := NewECSDeploymentStreamer(mockECS{out: svc}, "my-cluster", "my-svc", startDate, WithTaskTransitions(tasks, 2))
+	streamer.now = func() time.Time { return fetchedAt }
+	fetch := func(out ...*ecs.Task) []ECSTaskTransition {
+		tasks.out = out
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		return streamer.eventsToFlush[len(streamer.eventsToFlush)-1].TaskTransitions
+	}
+
+	// WHEN
+	first := fetch(task("a", "PROVISIONING"), task("b", "PROVISIONING"), task("c", "PROVISIONING"),
+		&ecs.Task{TaskArn: aws.String("d"), StartedBy: aws.String("ecs-svc/1"), LastStatus: aws.String("RUNNING")})
+	second := fetch(task("a", "PENDING"), task("b", "PROVISIONING"), task("c", "PENDING"))
+	third := fetch(task("a", "RUNNING"), task("c", "RUNNING"))
+	fourth := fetch(task("a", "RUNNING"), task("c", "RUNNING"))
+
+	// THEN
+	require.Equal(t, []ECSTaskTransition{
+		{TaskARN: "a", To: "PROVISIONING", At: fetchedAt},
+		{TaskARN: "b", To: "PROVISIONING", At: fetchedAt},
+	}, first, "only the tasks of the primary deployment should be tracked, up to the limit")
+	require.Equal(t, []ECSTaskTransition{
+		{TaskARN: "a", From: "PROVISIONING", To: "PENDING", At: fetchedAt},
+	}, second)
+	require.Equal(t, []ECSTaskTransition{
+		{TaskARN: "a", From: "PENDING", To: "RUNNING", At: fetchedAt},
+		{TaskARN: "b", From: "PROVISIONING", To: "STOPPED", At: fetchedAt},
+	}, third, "a task that is no longer listed should stop")
+	require.Equal(t, []ECSTaskTransition{
+		{TaskARN: "c", To: "RUNNING", At: fetchedAt},
+	}, fourth, "a task should be tracked once a tracked task stopped")
+}
+
+func TestECSDeploymentStreamer_FetchCompletion(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		deployments []*awsecs.Deployment
+		opts        []ECSDeploymentStreamerOpt
+
+		wantedOutcome       ECSDeploymentOutcome
+		wantedFailureReason string
+	}{
+		"remains in progress until all desired tasks are running by default": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount: aws.Int64(4),
+					RunningCount: aws.Int64(3),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+			},
+		},
+		"completes once the target running count is reached": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount: aws.Int64(4),
+					RunningCount: aws.Int64(2),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+			},
+			opts:          []ECSDeploymentStreamerOpt{WithTargetRunningCount(2)},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"caps the target running count to the desired count": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount: aws.Int64(1),
+					RunningCount: aws.Int64(1),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+			},
+			opts:          []ECSDeploymentStreamerOpt{WithTargetRunningCount(5)},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"remains in progress below the target running percentage": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount: aws.Int64(5),
+					RunningCount: aws.Int64(2),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+			},
+			opts: []ECSDeploymentStreamerOpt{WithTargetRunningPercent(50)},
+		},
+		"completes once the target running percentage is reached": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount: aws.Int64(5),
+					RunningCount: aws.Int64(3),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+			},
+			opts:          []ECSDeploymentStreamerOpt{WithTargetRunningPercent(50)},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"fails if the primary deployment failed before reaching the target": {
+			deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:       aws.Int64(4),
+					RunningCount:       aws.Int64(0),
+					RolloutState:       aws.String("FAILED"),
+					RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+					Status:             aws.String("PRIMARY"),
+				},
+			},
+			opts:                []ECSDeploymentStreamerOpt{WithTargetRunningPercent(50)},
+			wantedOutcome:       ECSDeploymentFailed,
+			wantedFailureReason: "ECS deployment circuit breaker: tasks failed to start.",
+		},
+		"fails if the watched deployment was rolled back": {
+			deployments: []*awsecs.Deployment{
+				{
+					CreatedAt:    aws.Time(startDate.Add(2 * time.Minute)),
+					DesiredCount: aws.Int64(4),
+					RunningCount: aws.Int64(4),
+					RolloutState: aws.String("IN_PROGRESS"),
+					Status:       aws.String("PRIMARY"),
+				},
+				{
+					CreatedAt:    aws.Time(startDate.Add(1 * time.Minute)),
+					DesiredCount: aws.Int64(4),
+					RunningCount: aws.Int64(0),
+					RolloutState: aws.String("FAILED"),
+					Status:       aws.String("ACTIVE"),
+				},
+			},
+			wantedOutcome:       ECSDeploymentFailed,
+			wantedFailureReason: "deployment failed",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			for _, d := range tc.deployments {
+				d.TaskDefinition = aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2")
+			}
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: tc.deployments,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedFailureReason, streamer.failureReason)
+			select {
+			case <-streamer.Done():
+				require.NotEmpty(t, tc.wantedOutcome, "done should only be closed once there is an outcome")
+			default:
+				require.Empty(t, tc.wantedOutcome, "done should be closed once there is an outcome")
+			}
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_CompletionCallbacks(t *testing.T) {
+	testCases := map[string]struct {
+		rolloutState string
+
+		wantedSteadyStateCalls int
+		wantedFailureCalls     int
+	}{
+		"calls OnSteadyState once when the deployment succeeds": {
+			rolloutState:           "COMPLETED",
+			wantedSteadyStateCalls: 1,
+		},
+		"calls OnFailure once when the deployment fails": {
+			rolloutState:       "FAILED",
+			wantedFailureCalls: 1,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{
+						{
+							DesiredCount:   aws.Int64(1),
+							RunningCount:   aws.Int64(1),
+							Status:         aws.String("PRIMARY"),
+							RolloutState:   aws.String(tc.rolloutState),
+							TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+						},
+					},
+				},
+			}
+			var steadyStateCalls, failureCalls int
+			var streamer *ECSDeploymentStreamer
+			callback := func(calls *int) func(ECSService) {
+				return func(svc ECSService) {
+					*calls++
+					require.NotNil(t, svc.Completion, "the callback should receive the last snapshot")
+					require.NotEmpty(t, streamer.Outcome(), "the callback should be able to use the accessors")
+					select {
+					case <-streamer.Done():
+						require.Fail(t, "the callback should be called before done is closed")
+					default:
+					}
+				}
+			}
+			streamer = NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(),
+				WithOnSteadyState(callback(&steadyStateCalls)),
+				WithOnFailure(callback(&failureCalls)))
+
+			// WHEN
+			for i := 0; i < 2; i++ {
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			<-streamer.Done()
+			require.Equal(t, tc.wantedSteadyStateCalls, steadyStateCalls)
+			require.Equal(t, tc.wantedFailureCalls, failureCalls)
+		})
+	}
+}
+
+type mockECSDeploymentHook struct {
+	events      []ECSService
+	completions []ECSDeploymentCompletion
+	reasons     []string
+}
+
+func (m *mockECSDeploymentHook) RecordEvent(ev ECSService) {
+	m.events = append(m.events, ev)
+}
+
+func (m *mockECSDeploymentHook) RecordCompletion(completion ECSDeploymentCompletion, failureReason string) {
+	m.completions = append(m.completions, completion)
+	m.reasons = append(m.reasons, failureReason)
+}
+
+func TestECSDeploymentStreamer_DeploymentHook(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(running int64) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+	}
+	hook := &mockECSDeploymentHook{}
+	m := &mockECSSequence{outs: []*ecs.Service{service(1), service(1), service(2)}}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithDeploymentHook(hook))
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+	// WHEN
+	for i := 0; i < 3; i++ {
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+	}
+
+	// THEN
+	require.Len(t, hook.events, 2, "the snapshot without changes is not recorded")
+	require.Equal(t, 1, hook.events[0].Deployments[0].RunningCount)
+	require.Equal(t, 2, hook.events[1].Deployments[0].RunningCount)
+	require.Equal(t, []ECSDeploymentCompletion{
+		{
+			Outcome:     ECSDeploymentSucceeded,
+			StartedAt:   startDate,
+			CompletedAt: startDate.Add(time.Minute),
+			Elapsed:     time.Minute,
+
+			MinRunningCount: 1,
+			MaxRunningCount: 2,
+		},
+	}, hook.completions)
+	require.Equal(t, []string{""}, hook.reasons)
+}
+
+func TestECSDeploymentStreamer_FailureReason(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	event := func(id, msg string) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(msg),
+			CreatedAt: aws.Time(startDate.Add(time.Minute)),
+		}
+	}
+	testCases := map[string]struct {
+		rolloutState       string
+		rolloutStateReason string
+		events             []*awsecs.ServiceEvent
+
+		wantedReason string
+		wantedCause  ECSRolloutFailureCause
+	}{
+		"empty while the deployment is in progress": {
+			rolloutState: "IN_PROGRESS",
+			events:       []*awsecs.ServiceEvent{event("1", "(service my-svc) failed to register targets in (target-group 1234)")},
+		},
+		"prefers the most recent classified failure": {
+			rolloutState:       "FAILED",
+			rolloutStateReason: "ECS deployment circuit breaker: tasks failed to start.",
+			events: []*awsecs.ServiceEvent{
+				event("3", "(service my-svc) was unable to place a task."),
+				event("2", "(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)"),
+				event("1", "(service my-svc) failed to register targets in (target-group 1234)"),
+			},
+			wantedReason: "(service my-svc) (task 1234) stopped: Essential container in task exited (exit code: 1)",
+			wantedCause:  ECSRolloutFailureCauseCircuitBreaker,
+		},
+		"falls back to the rollout state reason": {
+			rolloutState:       "FAILED",
+			rolloutStateReason: "ECS deployment circuit breaker: tasks failed to start.",
+			events:             []*awsecs.ServiceEvent{event("1", "(service my-svc) was unable to place a task.")},
+			wantedReason:       "ECS deployment circuit breaker: tasks failed to start.",
+			wantedCause:        ECSRolloutFailureCauseCircuitBreaker,
+		},
+		"reports that ECS timed out the deployment": {
+			rolloutState:       "FAILED",
+			rolloutStateReason: "ECS deployment timed out.",
+			events:             []*awsecs.ServiceEvent{event("1", "(service my-svc) failed to register targets in (target-group 1234)")},
+			wantedReason:       "ECS deployment timed out",
+			wantedCause:        ECSRolloutFailureCauseTimeout,
+		},
+		"falls back to a generic reason": {
+			rolloutState: "FAILED",
+			wantedReason: "deployment failed",
+			wantedCause:  ECSRolloutFailureCauseUnknown,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{
+						{
+							DesiredCount:       aws.Int64(2),
+							RunningCount:       aws.Int64(0),
+							Status:             aws.String("PRIMARY"),
+							RolloutState:       aws.String(tc.rolloutState),
+							RolloutStateReason: aws.String(tc.rolloutStateReason),
+							TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+						},
+					},
+					Events: tc.events,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+
+			// WHEN
+			reason := streamer.FailureReason()
+
+			// THEN
+			require.Equal(t, tc.wantedReason, reason)
+			require.Equal(t, tc.wantedCause, streamer.RolloutFailureCause())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_Notify(t *testing.T) {
+	// GIVEN
+	wantedEvents := []ECSService{
+		{
+			Deployments: []ECSDeployment{
+				{
+					Status: "PRIMARY",
+				},
+				{
+					Status: "ACTIVE",
+				},
+			},
+		},
+	}
+	sub := make(chan ECSService, 2)
+	streamer := &ECSDeploymentStreamer{
+		subscribers:   []chan ECSService{sub},
+		eventsToFlush: wantedEvents,
+	}
+
+	// WHEN
+	streamer.Notify()
+	close(sub) // Close the channel to stop expecting to receive new events.
+
+	// THEN
+	var actualEvents []ECSService
+	for event := range sub {
+		actualEvents = append(actualEvents, event)
+	}
+	require.ElementsMatch(t, wantedEvents, actualEvents)
+}
+
+func TestECSDeploymentStreamer_NotifyCopies(t *testing.T) {
+	// GIVEN
+	event := ECSService{
+		Deployments: []ECSDeployment{
+			{
+				Status:       "PRIMARY",
+				RunningCount: 1,
+			},
+		},
+		LatestFailureEvents: []string{"(service my-svc) was unable to place a task."},
+	}
+	first, second := make(chan ECSService, 1), make(chan ECSService, 1)
+	streamer := &ECSDeploymentStreamer{
+		subscribers:   []chan ECSService{first, second},
+		eventsToFlush: []ECSService{event},
+	}
+
+	// WHEN
+	streamer.Notify()
+	firstEvent := <-first
+	firstEvent.Deployments[0].RunningCount = 10
+	firstEvent.LatestFailureEvents[0] = "mutated"
+
+	// THEN
+	secondEvent := <-second
+	require.Equal(t, 1, secondEvent.Deployments[0].RunningCount)
+	require.Equal(t, "(service my-svc) was unable to place a task.", secondEvent.LatestFailureEvents[0])
+	require.Equal(t, 1, event.Deployments[0].RunningCount, "the streamer's event should not be mutated either")
+}
+
+// notifyAndCollect calls Notify and returns the snapshots received by sub.
+func notifyAndCollect(streamer *ECSDeploymentStreamer, sub <-chan ECSService) []ECSService {
+	done := make(chan struct{})
+	go func() {
+		streamer.Notify()
+		close(done)
+	}()
+	var got []ECSService
+	for {
+		select {
+		case ev := <-sub:
+			got = append(got, ev)
+		case <-done:
+			return got
+		}
+	}
+}
+
+func TestECSDeploymentStreamer_SubscribeBuffered(t *testing.T) {
+	events := func() []ECSService {
+		return []ECSService{
+			{Deployments: []ECSDeployment{{Status: "PRIMARY", RunningCount: 1}}},
+			{Deployments: []ECSDeployment{{Status: "PRIMARY", RunningCount: 2}}},
+			{Deployments: []ECSDeployment{{Status: "PRIMARY", RunningCount: 3}}},
+		}
+	}
+	sequences := func(evs []ECSService) []uint64 {
+		var seqs []uint64
+		for _, ev := range evs {
+			seqs = append(seqs, ev.Sequence)
+		}
+		return seqs
+	}
+	drain := func(sub <-chan ECSService) []ECSService {
+		var evs []ECSService
+		for {
+			select {
+			case ev := <-sub:
+				evs = append(evs, ev)
+			default:
+				return evs
+			}
+		}
+	}
+	t.Run("drops the oldest descriptions of a slow consumer", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+		sub := streamer.SubscribeBuffered(2, ECSOverflowDropOldest)
+		streamer.eventsToFlush = events()
+
+		// WHEN
+		streamer.Notify() // Returns without anyone receiving.
+
+		// THEN
+		require.Equal(t, []uint64{2, 3}, sequences(drain(sub)))
+	})
+	t.Run("drops the newest descriptions of a slow consumer", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+		sub := streamer.SubscribeBuffered(2, ECSOverflowDropNewest)
+		streamer.eventsToFlush = events()
+
+		// WHEN
+		streamer.Notify() // Returns without anyone receiving.
+
+		// THEN
+		require.Equal(t, []uint64{1, 2}, sequences(drain(sub)))
+	})
+	t.Run("blocks until a slow consumer receives every description", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+		sub := streamer.SubscribeBuffered(1, ECSOverflowBlock)
+		streamer.eventsToFlush = events()
+
+		// WHEN
+		done := make(chan struct{})
+		go func() {
+			streamer.Notify()
+			close(done)
+		}()
+		first := <-sub
+
+		// THEN
+		require.False(t, isClosed(done), "Notify should wait for the third description to be received")
+		second, third := <-sub, <-sub
+		<-done
+		require.Equal(t, []uint64{1, 2, 3}, sequences([]ECSService{first, second, third}))
+	})
+	t.Run("keeps the other subscribers unblocked", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+		slow := streamer.SubscribeBuffered(0, ECSOverflowDropOldest)
+		fast := streamer.Subscribe()
+		streamer.eventsToFlush = events()
+
+		// WHEN
+		got := notifyAndCollect(streamer, fast)
+
+		// THEN
+		require.Equal(t, []uint64{1, 2, 3}, sequences(got))
+		require.Equal(t, []uint64{3}, sequences(drain(slow)))
+	})
+}
+
+func TestECSDeploymentStreamer_SubscribeFiltered(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+	all := streamer.Subscribe()
+	failures := streamer.SubscribeFiltered(func(ev ECSService) bool {
+		return len(ev.LatestFailureEvents) > 0
+	})
+	failure := ECSService{LatestFailureEvents: []string{"(service my-svc) failed to launch a task."}}
+	streamer.eventsToFlush = []ECSService{{}, failure, {}}
+
+	// WHEN
+	done := make(chan struct{})
+	go func() {
+		streamer.Notify()
+		close(done)
+	}()
+	var gotAll, gotFailures []ECSService
+	for {
+		select {
+		case ev := <-all:
+			gotAll = append(gotAll, ev)
+			continue
+		case ev := <-failures:
+			gotFailures = append(gotFailures, ev)
+			continue
+		case <-done:
+		}
+		break
+	}
+
+	// THEN
+	failure.Sequence = 2
+	require.Equal(t, []ECSService{{Sequence: 1}, failure, {Sequence: 3}}, gotAll)
+	require.Equal(t, []ECSService{failure}, gotFailures)
+}
+
+func TestECSDeploymentStreamer_NotifyQuietUntilFailure(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now(), WithQuietUntilFailure())
+	sub := streamer.Subscribe()
+	progress := ECSService{Deployments: []ECSDeployment{{Status: "PRIMARY", DesiredCount: 2, RunningCount: 1}}}
+	failure := ECSService{LatestFailureEvents: []string{"(service my-svc) failed to launch a task."}}
+	completion := ECSService{Completion: &ECSDeploymentCompletion{Outcome: ECSDeploymentFailed}}
+	streamer.eventsToFlush = []ECSService{progress, failure, progress}
+
+	// WHEN
+	gotFailures := notifyAndCollect(streamer, sub)
+	streamer.eventsToFlush = []ECSService{progress, completion}
+	gotCompletion := notifyAndCollect(streamer, sub)
+
+	// THEN
+	failure.Sequence = 1
+	require.Equal(t, []ECSService{failure}, gotFailures, "progress should be suppressed")
+	completion.Sequence = 2
+	require.Equal(t, []ECSService{completion}, gotCompletion)
+}
+
+func TestECSDeploymentStreamer_EmitSchedule(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	const placeFailure = "(service my-svc) was unable to place a task."
+	service := func(running int64, rolloutState string, events ...*awsecs.ServiceEvent) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String(rolloutState),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+			Events: events,
+		}
+	}
+	failure := &awsecs.ServiceEvent{
+		Id:        aws.String("1"),
+		Message:   aws.String(placeFailure),
+		CreatedAt: aws.Time(startDate.Add(time.Minute)),
+	}
+	m := &mockECSSequence{outs: []*ecs.Service{
+		service(0, "IN_PROGRESS", failure),
+		service(1, "IN_PROGRESS", failure),
+		service(2, "COMPLETED", failure),
+	}}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithEmitSchedule(time.Second))
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+	sub := streamer.Subscribe()
+	require.Equal(t, time.Second, streamer.emitInterval())
+	collect := func(emit func()) []ECSService {
+		done := make(chan struct{})
+		go func() {
+			emit()
+			close(done)
+		}()
+		var got []ECSService
+		for {
+			select {
+			case ev := <-sub:
+				got = append(got, ev)
+			case <-done:
+				return got
+			}
+		}
+	}
+	fetchAndNotify := func() []ECSService {
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		return collect(streamer.Notify)
+	}
+
+	// WHEN
+	require.Empty(t, collect(streamer.emitScheduled), "nothing should be emitted before the first fetch")
+	require.Empty(t, fetchAndNotify(), "progress should be held until the next tick")
+	require.Empty(t, fetchAndNotify())
+	coalesced := collect(streamer.emitScheduled)
+	repeated := collect(streamer.emitScheduled)
+	completed := fetchAndNotify()
+
+	// THEN
+	require.Len(t, coalesced, 1)
+	require.Equal(t, []string{placeFailure}, coalesced[0].LatestFailureEvents, "failures since the last tick should be kept")
+	require.Equal(t, 1, coalesced[0].Deployments[0].RunningCount)
+	require.Len(t, repeated, 1)
+	require.Empty(t, repeated[0].LatestFailureEvents, "failures should not be repeated")
+	require.Equal(t, 1, repeated[0].Deployments[0].RunningCount)
+	require.Len(t, completed, 1, "the completion should be emitted right away")
+	require.NotNil(t, completed[0].Completion)
+	require.Equal(t, []uint64{1, 2, 3}, []uint64{coalesced[0].Sequence, repeated[0].Sequence, completed[0].Sequence})
+	require.Empty(t, collect(streamer.emitScheduled), "nothing should be emitted after the completion")
+}
+
+func TestECSDeploymentStreamer_NotifyStartEvent(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	fetchedAt := startDate.Add(time.Minute)
+	m := &mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		},
+	}
+	deployments := []ECSDeployment{{Status: "PRIMARY", TaskDefRevision: "2", DesiredCount: 2, RunningCount: 1}}
+	testCases := map[string]struct {
+		opts []ECSDeploymentStreamerOpt
+
+		wantedFirst  []*ECSDeploymentStart // Start of each snapshot received on the first Notify.
+		wantedSecond []*ECSDeploymentStart // Start of each snapshot received on the second Notify.
+	}{
+		"emits the start event before the first snapshot only": {
+			opts: []ECSDeploymentStreamerOpt{WithStartEvent()},
+			wantedFirst: []*ECSDeploymentStart{
+				{DeploymentCreationTime: startDate, WatchStartedAt: fetchedAt},
+				nil,
+			},
+			wantedSecond: []*ECSDeploymentStart{nil},
+		},
+		"does not coalesce the start event with throttled snapshots": {
+			opts: []ECSDeploymentStreamerOpt{WithStartEvent(), WithMinEmitInterval(time.Hour)},
+			wantedFirst: []*ECSDeploymentStart{
+				{DeploymentCreationTime: startDate, WatchStartedAt: fetchedAt},
+				nil,
+			},
+		},
+		"does not emit a start event by default": {
+			wantedFirst:  []*ECSDeploymentStart{nil},
+			wantedSecond: []*ECSDeploymentStart{nil},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return fetchedAt }
+			sub := streamer.Subscribe()
+			starts := func(events []ECSService) []*ECSDeploymentStart {
+				var got []*ECSDeploymentStart
+				for _, ev := range events {
+					require.Equal(t, deployments, ev.Deployments)
+					got = append(got, ev.Start)
+				}
+				return got
+			}
+
+			// WHEN
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			first := notifyAndCollect(streamer, sub)
+			_, err = streamer.Fetch()
+			require.NoError(t, err)
+			second := notifyAndCollect(streamer, sub)
+
+			// THEN
+			require.Equal(t, tc.wantedFirst, starts(first))
+			require.Equal(t, tc.wantedSecond, starts(second))
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchStartEventCircuitBreaker(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		config *awsecs.DeploymentConfiguration
+
+		wanted *ECSCircuitBreaker
+	}{
+		"service without a deployment configuration": {},
+		"service without a circuit breaker": {
+			config: &awsecs.DeploymentConfiguration{MinimumHealthyPercent: aws.Int64(100)},
+		},
+		"circuit breaker without rollback": {
+			config: &awsecs.DeploymentConfiguration{
+				DeploymentCircuitBreaker: &awsecs.DeploymentCircuitBreaker{Enable: aws.Bool(true), Rollback: aws.Bool(false)},
+			},
+			wanted: &ECSCircuitBreaker{Enabled: true},
+		},
+		"circuit breaker with rollback": {
+			config: &awsecs.DeploymentConfiguration{
+				DeploymentCircuitBreaker: &awsecs.DeploymentCircuitBreaker{Enable: aws.Bool(true), Rollback: aws.Bool(true)},
+			},
+			wanted: &ECSCircuitBreaker{Enabled: true, Rollback: true},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					DeploymentConfiguration: tc.config,
+					Deployments: []*awsecs.Deployment{
+						{
+							DesiredCount:   aws.Int64(1),
+							Status:         aws.String("PRIMARY"),
+							TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+						},
+					},
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithStartEvent())
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.NotNil(t, streamer.startToFlush)
+			require.Equal(t, tc.wanted, streamer.startToFlush.Start.CircuitBreaker)
+		})
+	}
+}
+
+func TestECSService_Coalesce(t *testing.T) {
+	// GIVEN
+	failure := func(msg string) ECSServiceFailure {
+		return ECSServiceFailure{Message: msg, Category: ECSFailureCategoryUnknown}
+	}
+	older := ECSService{
+		LatestFailureEvents: []string{"failure 2", "failure 1"},
+		LatestFailures:      []ECSServiceFailure{failure("failure 2"), failure("failure 1")},
+	}
+	newer := ECSService{
+		Deployments:         []ECSDeployment{{Status: "PRIMARY", DesiredCount: 3, RunningCount: 2}},
+		LatestFailureEvents: []string{"failure 4", "failure 3"},
+		LatestFailures:      []ECSServiceFailure{failure("failure 4"), failure("failure 3")},
+	}
+
+	// WHEN
+	coalesced := older.coalesce(newer)
+
+	// THEN
+	require.Equal(t, newer.Deployments, coalesced.Deployments)
+	require.Equal(t, []string{"failure 4", "failure 3", "failure 2", "failure 1"}, coalesced.LatestFailureEvents,
+		"failures should stay sorted from newest to oldest")
+	require.Equal(t, []ECSServiceFailure{failure("failure 4"), failure("failure 3"), failure("failure 2"), failure("failure 1")},
+		coalesced.LatestFailures)
+}
+
+func TestECSDeploymentStreamer_NotifySequence(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+	sub := streamer.Subscribe()
+	notify := func(events ...ECSService) []uint64 {
+		streamer.eventsToFlush = events
+		var sequences []uint64
+		for _, ev := range notifyAndCollect(streamer, sub) {
+			sequences = append(sequences, ev.Sequence)
+		}
+		return sequences
+	}
+
+	// WHEN
+	first := notify(ECSService{}, ECSService{})
+	second := notify(ECSService{})
+	require.NoError(t, streamer.Reset(time.Now()))
+	afterReset := notify(ECSService{})
+
+	// THEN
+	require.Equal(t, []uint64{1, 2}, first)
+	require.Equal(t, []uint64{3}, second, "sequence numbers should keep increasing across calls to Notify")
+	require.Equal(t, []uint64{1}, afterReset, "sequence numbers should start over after Reset")
+}
+
+func TestECSDeploymentStreamer_NotifyMinEmitInterval(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", startDate, WithMinEmitInterval(10*time.Second))
+	sub := streamer.Subscribe()
+	snapshot := func(running int, failure string, sequence uint64) ECSService {
+		return ECSService{
+			Deployments:         []ECSDeployment{{Status: "PRIMARY", DesiredCount: 3, RunningCount: running}},
+			LatestFailureEvents: []string{failure},
+			Sequence:            sequence,
+		}
+	}
+	notifyAt := func(after time.Duration, events ...ECSService) []ECSService {
+		streamer.now = func() time.Time { return startDate.Add(after) }
+		streamer.eventsToFlush = events
+		return notifyAndCollect(streamer, sub)
+	}
+
+	// WHEN
+	first := notifyAt(0, snapshot(1, "failure 1", 0))
+	throttled := notifyAt(2*time.Second, snapshot(2, "failure 2", 0), snapshot(3, "failure 3", 0))
+	idle := notifyAt(4 * time.Second)
+	coalesced := notifyAt(10 * time.Second)
+	streamer.outcome = ECSDeploymentSucceeded
+	final := notifyAt(11*time.Second, snapshot(3, "failure 4", 0))
+
+	// THEN
+	require.Equal(t, []ECSService{snapshot(1, "failure 1", 1)}, first)
+	require.Empty(t, throttled)
+	require.Empty(t, idle)
+	require.Equal(t, []ECSService{
+		{
+			Deployments:         []ECSDeployment{{Status: "PRIMARY", DesiredCount: 3, RunningCount: 3}},
+			LatestFailureEvents: []string{"failure 3", "failure 2"},
+			Sequence:            2,
+		},
+	}, coalesced)
+	require.Equal(t, []ECSService{snapshot(3, "failure 4", 3)}, final, "the last snapshot should not be throttled once done")
+}
+
+type mockECSServiceUpdater struct {
+	taskDefs []string
+	err      error
+}
+
+func (m *mockECSServiceUpdater) UpdateServiceTaskDefinition(clusterName, serviceName, taskDefinition string) error {
+	m.taskDefs = append(m.taskDefs, taskDefinition)
+	return m.err
+}
+
+func TestECSDeploymentStreamer_AbortDeployment(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	newService := func() *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(0),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+					CreatedAt:      aws.Time(startDate),
+				},
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(2),
+					Status:         aws.String("ACTIVE"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate.Add(-time.Hour)),
+				},
+				{
+					DesiredCount:   aws.Int64(0),
+					RunningCount:   aws.Int64(0),
+					Status:         aws.String("ACTIVE"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+					CreatedAt:      aws.Time(startDate.Add(-2 * time.Hour)),
+				},
+			},
+		}
+	}
+	t.Run("errors if the streamer cannot update the service", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService()}, "my-cluster", "my-svc", startDate)
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		err = streamer.AbortDeployment()
+
+		// THEN
+		require.Equal(t, ErrAbortUnsupported, err)
+	})
+	t.Run("errors if there is no previous deployment", func(t *testing.T) {
+		// GIVEN
+		out := newService()
+		out.Deployments = out.Deployments[:1]
+		streamer := NewECSDeploymentStreamer(mockECS{out: out}, "my-cluster", "my-svc", startDate, WithServiceUpdater(&mockECSServiceUpdater{}))
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		err = streamer.AbortDeployment()
+
+		// THEN
+		require.EqualError(t, err, "no previous deployment of service my-svc to roll back to")
+	})
+	t.Run("wraps the error from the updater", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: newService()}, "my-cluster", "my-svc", startDate,
+			WithServiceUpdater(&mockECSServiceUpdater{err: errors.New("some error")}))
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		err = streamer.AbortDeployment()
+
+		// THEN
+		require.EqualError(t, err, "roll back service my-svc: some error")
+	})
+	t.Run("rolls back to the previous deployment and streams the rollback to completion", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{out: newService()}
+		updater := &mockECSServiceUpdater{}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithServiceUpdater(updater))
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		err = streamer.AbortDeployment()
+		require.NoError(t, err)
+		m.out.Deployments = []*awsecs.Deployment{
+			{
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(2),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				CreatedAt:      aws.Time(startDate.Add(time.Minute)),
+			},
+		}
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+
+		// THEN
+		require.Equal(t, []string{"arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"}, updater.taskDefs)
+		<-streamer.Done()
+		require.Equal(t, ECSDeploymentRolledBack, streamer.Outcome())
+		require.Nil(t, streamer.eventsToFlush[1].Notices, "the revision change of a rollback should not be reported")
+	})
+	t.Run("rolls back the deployment watched with its ID to completion", func(t *testing.T) {
+		// GIVEN
+		out := newService()
+		out.Deployments[0].Id, out.Deployments[0].RolloutState = aws.String("ecs-svc/3"), aws.String("IN_PROGRESS")
+		out.Deployments[1].Id = aws.String("ecs-svc/2")
+		m := &mockECS{out: out}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+			WithServiceUpdater(&mockECSServiceUpdater{}), WithDeploymentID("ecs-svc/3"))
+		now := startDate.Add(time.Minute)
+		streamer.now = func() time.Time { return now }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		err = streamer.AbortDeployment()
+		require.NoError(t, err)
+		rollback := &awsecs.Deployment{
+			Id:             aws.String("ecs-svc/4"),
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(1),
+			Status:         aws.String("PRIMARY"),
+			RolloutState:   aws.String("IN_PROGRESS"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			CreatedAt:      aws.Time(now),
+		}
+		aborted := out.Deployments[0]
+		aborted.Status = aws.String("ACTIVE")
+		m.out.Deployments = []*awsecs.Deployment{rollback, aborted}
+
+		// WHEN
+		now = now.Add(time.Minute)
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+		inProgress := streamer.Outcome()
+		rollback.RunningCount, rollback.RolloutState = aws.Int64(2), aws.String("COMPLETED")
+		m.out.Deployments = []*awsecs.Deployment{rollback}
+		now = now.Add(time.Minute)
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+
+		// THEN
+		require.Empty(t, inProgress, "the rollback deployment should not supersede the aborted one")
+		require.Equal(t, ECSDeploymentRolledBack, streamer.Outcome())
+		require.Empty(t, streamer.FailureReason())
+	})
+	t.Run("rolls back a deployment watched with its expected revision", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{out: newService()}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+			WithServiceUpdater(&mockECSServiceUpdater{}), WithExpectedRevision("3"))
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		err = streamer.AbortDeployment()
+		require.NoError(t, err)
+		m.out.Deployments = []*awsecs.Deployment{
+			{
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(2),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				CreatedAt:      aws.Time(startDate.Add(time.Minute)),
+			},
+		}
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+
+		// THEN
+		require.Equal(t, ECSDeploymentRolledBack, streamer.Outcome())
+		require.Empty(t, streamer.FailureReason())
+	})
+	t.Run("does not wait for the rollback deployment as a prior deployment", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{out: newService()}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithServiceUpdater(&mockECSServiceUpdater{}))
+		now := startDate.Add(time.Minute)
+		streamer.now = func() time.Time { return now }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		err = streamer.AbortDeployment()
+		require.NoError(t, err)
+		rollback := &awsecs.Deployment{
+			Id:             aws.String("ecs-svc/rollback"),
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(1),
+			Status:         aws.String("PRIMARY"),
+			RolloutState:   aws.String("IN_PROGRESS"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			// ECS's clock can be slightly behind the caller's.
+			CreatedAt: aws.Time(startDate.Add(50 * time.Second)),
+		}
+		m.out.Deployments = []*awsecs.Deployment{rollback}
+
+		// WHEN
+		now = now.Add(time.Minute)
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+		inProgress := streamer.eventsToFlush[len(streamer.eventsToFlush)-1]
+		rollback.RunningCount, rollback.RolloutState = aws.Int64(2), aws.String("COMPLETED")
+		now = now.Add(time.Minute)
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+
+		// THEN
+		require.Empty(t, inProgress.WaitingFor)
+		for _, notice := range inProgress.Notices {
+			require.NotEqual(t, ECSNoticeWaitingForPriorDeployment, notice.Kind)
+		}
+		<-streamer.Done()
+		require.Equal(t, ECSDeploymentRolledBack, streamer.Outcome())
+	})
+}
+
+func TestECSDeploymentStreamer_Close(t *testing.T) {
+	// GIVEN
+	streamer := &ECSDeploymentStreamer{}
+	c := streamer.Subscribe()
+
+	// WHEN
+	streamer.Close()
+
+	// THEN
+	_, isOpen := <-c
+	require.False(t, isOpen, "expected subscribed channels to be closed")
+}
+
+func TestECSDeploymentStreamer_Reset(t *testing.T) {
+	t.Run("reopens done and forgets past events while keeping subscribers", func(t *testing.T) {
+		// GIVEN
+		startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+		m := mockECS{
+			out: &ecs.Service{
+				Deployments: []*awsecs.Deployment{
+					{
+						DesiredCount:   aws.Int64(1),
+						RunningCount:   aws.Int64(1),
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					},
+				},
+				Events: []*awsecs.ServiceEvent{
+					{
+						Id:        aws.String("1"),
+						Message:   aws.String("(service my-svc) failed to launch a task with (error some-error)."),
+						CreatedAt: aws.Time(startDate.Add(1 * time.Minute)),
+					},
+				},
+			},
+		}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+		sub := streamer.Subscribe()
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		_, isOpen := <-streamer.Done()
+		require.False(t, isOpen)
+		streamer.expiredRetries = 1         // Left over from an expired credentials error of the previous deployment.
+		streamer.failuresAnchor = startDate // Left over from the failures of the previous deployment.
+
+		// WHEN
+		err = streamer.Reset(startDate)
+
+		// THEN
+		require.NoError(t, err)
+		select {
+		case <-streamer.Done():
+			require.FailNow(t, "done should be reopened after Reset")
+		default:
+		}
+		require.Nil(t, streamer.eventsToFlush, "pending events should be discarded")
+		require.Empty(t, streamer.pastEventIDs, "past events should be forgotten")
+		require.Equal(t, startDate, streamer.deploymentCreationTime)
+		require.Zero(t, streamer.expiredRetries, "retries after expired credentials should start over")
+		require.True(t, streamer.failuresAnchor.IsZero(), "failures of the previous deployment should be forgotten")
+
+		_, err = streamer.Fetch()
+		require.NoError(t, err)
+		go streamer.Notify()
+		ev := <-sub
+		require.Equal(t, []string{"(service my-svc) failed to launch a task with (error some-error)."}, ev.LatestFailureEvents,
+			"the same subscriber should receive events of the next deployment")
+	})
+	t.Run("returns ErrStreamerClosed if the streamer was closed", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+		streamer.Close()
+
+		// WHEN
+		err := streamer.Reset(time.Now())
+
+		// THEN
+		require.True(t, errors.Is(err, ErrStreamerClosed))
+	})
+}
+
+func TestECSDeploymentStreamer_FailureHistory(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	event := func(id, msg string, createdAt time.Time) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(msg),
+			CreatedAt: aws.Time(createdAt),
+		}
+	}
+	const (
+		imageFailure  = "(service my-svc) failed to launch a task: missing image my-svc:abc"
+		placeFailure  = "(service my-svc) was unable to place a task."
+		healthFailure = "(service my-svc) (task 1234) failed container health checks."
+	)
+	redeployDate := startDate.Add(time.Hour)
+	m := &mockECSSequence{outs: []*ecs.Service{
+		{Events: []*awsecs.ServiceEvent{
+			event("2", placeFailure, startDate.Add(2*time.Minute)),
+			event("1", imageFailure, startDate.Add(time.Minute)),
+		}},
+		{Events: []*awsecs.ServiceEvent{
+			event("3", healthFailure, redeployDate.Add(time.Minute)),
+			event("2", placeFailure, startDate.Add(2*time.Minute)),
+		}},
+	}}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithFailureHistory(2))
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+	_, err := streamer.Fetch()
+	require.NoError(t, err)
+
+	// WHEN
+	require.NoError(t, streamer.Reset(redeployDate))
+	_, err = streamer.Fetch()
+	require.NoError(t, err)
+
+	// THEN
+	require.Equal(t, 2, streamer.Attempt())
+	require.Equal(t, []ECSAttemptFailure{
+		{Attempt: 1, Failure: ECSServiceFailure{Message: placeFailure, Category: ECSFailureCategoryUnknown}},
+		{Attempt: 2, Failure: ECSServiceFailure{Message: healthFailure, Category: ECSFailureCategoryUnknown}},
+	}, streamer.FailureHistory(), "the oldest failure should be dropped")
+}
+
+func TestECSDeploymentStreamer_DebugState(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	m := mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(1),
+					PendingCount:   aws.Int64(1),
+					RolloutState:   aws.String("IN_PROGRESS"),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+			Events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String("(service my-svc) has started 1 tasks: (task 1234)."),
+					CreatedAt: aws.Time(startDate.Add(1 * time.Minute)),
+				},
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+	_, err := streamer.Fetch()
+	require.NoError(t, err)
+
+	// WHEN
+	state := streamer.DebugState()
+	state.Deployments[0].RunningCount = 2 // Mutating the snapshot should not affect the streamer.
+
+	// THEN
+	require.Equal(t, "my-cluster", state.Cluster)
+	require.Equal(t, "my-svc", state.Service)
+	require.Equal(t, startDate, state.DeploymentCreationTime)
+	require.False(t, state.LastFetchedAt.IsZero())
+	require.Equal(t, 1, state.PastEventsCount)
+	require.Equal(t, 1, state.PendingEventsCount)
+	require.Equal(t, map[string]int{"2": 1}, state.RunningCountByRevision)
+	require.Empty(t, state.Outcome)
+	require.Equal(t, 1, streamer.DebugState().Deployments[0].RunningCount)
+}
+
+func TestECSDeploymentStreamer_SubscriberCount(t *testing.T) {
+	// GIVEN
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now())
+	require.Zero(t, streamer.SubscriberCount())
+
+	// WHEN
+	streamer.Subscribe()
+	streamer.SubscribeFiltered(func(ECSService) bool { return true })
+	streamer.SubscribeDeltas()
+
+	// THEN
+	require.Equal(t, 3, streamer.SubscriberCount())
+	require.Equal(t, 3, streamer.DebugState().SubscriberCount)
+	streamer.Close()
+	require.Zero(t, streamer.SubscriberCount(), "closed channels should not be counted")
+}
+
+func TestECSDeploymentStreamer_FetchStabilityDwell(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	type fetch struct {
+		at       time.Duration // Time since startDate.
+		running  int64
+		failure  bool
+		wantDone bool
+	}
+	testCases := map[string][]fetch{
+		"succeeds once the service holds its target for the dwell time": {
+			{at: 0, running: 2},
+			{at: 4 * time.Minute, running: 2},
+			{at: 5 * time.Minute, running: 2, wantDone: true},
+		},
+		"restarts the dwell time if the running count dips": {
+			{at: 0, running: 2},
+			{at: 2 * time.Minute, running: 1},
+			{at: 3 * time.Minute, running: 2},
+			{at: 7 * time.Minute, running: 2},
+			{at: 8 * time.Minute, running: 2, wantDone: true},
+		},
+		"restarts the dwell time on new failures": {
+			{at: 0, running: 2},
+			{at: 2 * time.Minute, running: 2, failure: true},
+			{at: 6 * time.Minute, running: 2},
+			{at: 7 * time.Minute, running: 2, wantDone: true},
+		},
+	}
+	for name, fetches := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			primary := &awsecs.Deployment{
+				DesiredCount:   aws.Int64(2),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			}
+			m := &mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{primary},
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, WithStabilityDwell(5*time.Minute))
+
+			for i, f := range fetches {
+				// WHEN
+				now := startDate.Add(f.at)
+				streamer.now = func() time.Time { return now }
+				primary.RunningCount = aws.Int64(f.running)
+				m.out.Events = nil
+				if f.failure {
+					m.out.Events = []*awsecs.ServiceEvent{
+						{
+							Id:        aws.String(fmt.Sprintf("%d", i)),
+							Message:   aws.String("(service my-svc) failed to launch a task."),
+							CreatedAt: aws.Time(now),
+						},
+					}
+				}
+				_, err := streamer.Fetch()
+
+				// THEN
+				require.NoError(t, err)
+				if f.wantDone {
+					require.Equal(t, ECSDeploymentSucceeded, streamer.Outcome(), "fetch %d", i)
+				} else {
+					require.Empty(t, streamer.Outcome(), "fetch %d", i)
+				}
+			}
+		})
+	}
+}
+
+type mockECSTargetHealth struct {
+	healthy map[string][]ECSTarget
+	err     error
+}
+
+func (m *mockECSTargetHealth) HealthyTargets(targetGroupARN string) ([]ECSTarget, error) {
+	return m.healthy[targetGroupARN], m.err
+}
+
+type mockECSStoppedTasks struct {
+	tasks      []*ecs.Task
+	err        error
+	maxResults int
+}
+
+func (m *mockECSStoppedTasks) StoppedServiceTasks(clusterName, serviceName string, maxResults int) ([]*ecs.Task, error) {
+	m.maxResults = maxResults
+	return m.tasks, m.err
+}
+
+func TestECSDeploymentStreamer_FetchStoppedTaskReasons(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	failed := &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				Id:                 aws.String("ecs-svc/2"),
+				DesiredCount:       aws.Int64(2),
+				RunningCount:       aws.Int64(0),
+				Status:             aws.String("PRIMARY"),
+				RolloutState:       aws.String("FAILED"),
+				RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+				TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			},
+		},
+	}
+	stoppedTask := func(startedBy, reason string, stoppedAfter time.Duration) *ecs.Task {
+		return &ecs.Task{
+			StartedBy:     aws.String(startedBy),
+			StoppedReason: aws.String(reason),
+			StoppedAt:     aws.Time(startDate.Add(stoppedAfter)),
+		}
+	}
+	t.Run("reports the distinct stopped reasons of the failed deployment's tasks, most recent first", func(t *testing.T) {
+		// GIVEN
+		tasks := &mockECSStoppedTasks{
+			tasks: []*ecs.Task{
+				stoppedTask("ecs-svc/2", "CannotPullContainerError: pull image manifest has been retried 5 time(s)", time.Minute),
+				stoppedTask("ecs-svc/1", "Scaling activity initiated by (deployment ecs-svc/2)", 2*time.Minute),
+				stoppedTask("ecs-svc/2", "Essential container in task exited", 3*time.Minute),
+				stoppedTask("ecs-svc/2", "CannotPullContainerError: pull image manifest has been retried 5 time(s)", 4*time.Minute),
+				stoppedTask("ecs-svc/2", "", 5*time.Minute),
+			},
+		}
+		streamer := NewECSDeploymentStreamer(mockECS{out: failed}, "my-cluster", "my-svc", startDate, WithStoppedTaskReasons(tasks))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, ECSDeploymentFailed, streamer.Outcome())
+		require.Equal(t, []string{
+			"CannotPullContainerError: pull image manifest has been retried 5 time(s)",
+			"Essential container in task exited",
+		}, streamer.StoppedTaskReasons())
+		require.Equal(t, ecsMaxStoppedTasks, tasks.maxResults)
+	})
+	t.Run("reports the stopped reasons of the failed deployment once the rollback deployment is primary", func(t *testing.T) {
+		// GIVEN
+		rolledBack := &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					Id:             aws.String("ecs-svc/3"),
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String("IN_PROGRESS"),
+					CreatedAt:      aws.Time(startDate.Add(5 * time.Minute)),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:1"),
+				},
+				{
+					Id:                 aws.String("ecs-svc/2"),
+					DesiredCount:       aws.Int64(0),
+					RunningCount:       aws.Int64(0),
+					Status:             aws.String("ACTIVE"),
+					RolloutState:       aws.String("FAILED"),
+					RolloutStateReason: aws.String("ECS deployment circuit breaker: tasks failed to start."),
+					CreatedAt:          aws.Time(startDate.Add(time.Second)),
+					TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+		tasks := &mockECSStoppedTasks{
+			tasks: []*ecs.Task{
+				stoppedTask("ecs-svc/2", "Essential container in task exited", time.Minute),
+				stoppedTask("ecs-svc/3", "Task failed ELB health checks", 6*time.Minute),
+			},
+		}
+		streamer := NewECSDeploymentStreamer(mockECS{out: rolledBack}, "my-cluster", "my-svc", startDate, WithStoppedTaskReasons(tasks))
+		streamer.now = func() time.Time { return startDate.Add(6 * time.Minute) }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, ECSDeploymentFailed, streamer.Outcome())
+		require.Equal(t, []string{"Essential container in task exited"}, streamer.StoppedTaskReasons())
+		result, ok := streamer.Result()
+		require.True(t, ok)
+		require.Equal(t, []string{"Essential container in task exited"}, result.StoppedReasons)
+	})
+	t.Run("completes the deployment without stopped reasons if the tasks can't be described", func(t *testing.T) {
+		// GIVEN
+		tasks := &mockECSStoppedTasks{err: errors.New("some error")}
+		streamer := NewECSDeploymentStreamer(mockECS{out: failed}, "my-cluster", "my-svc", startDate, WithStoppedTaskReasons(tasks))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		<-streamer.Done()
+		require.Empty(t, streamer.StoppedTaskReasons())
+	})
+	t.Run("does not describe stopped tasks if the deployment succeeds", func(t *testing.T) {
+		// GIVEN
+		tasks := &mockECSStoppedTasks{}
+		succeeded := &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					Id:             aws.String("ecs-svc/2"),
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(2),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+		streamer := NewECSDeploymentStreamer(mockECS{out: succeeded}, "my-cluster", "my-svc", startDate, WithStoppedTaskReasons(tasks))
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, ECSDeploymentSucceeded, streamer.Outcome())
+		require.Zero(t, tasks.maxResults)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchTargetHealth(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	awsvpcTask := func(startedBy, ip string) *ecs.Task {
+		return &ecs.Task{
+			StartedBy: aws.String(startedBy),
+			Containers: []*awsecs.Container{
+				{NetworkInterfaces: []*awsecs.NetworkInterface{{PrivateIpv4Address: aws.String(ip)}}},
+			},
+		}
+	}
+	bridgeTask := func(startedBy string, hostPort int64) *ecs.Task {
+		return &ecs.Task{
+			StartedBy: aws.String(startedBy),
+			Containers: []*awsecs.Container{
+				{NetworkBindings: []*awsecs.NetworkBinding{{HostPort: aws.Int64(hostPort)}}},
+			},
+		}
+	}
+	awsvpcTasks := []*ecs.Task{
+		awsvpcTask("ecs-svc/2", "10.0.0.1"),
+		awsvpcTask("ecs-svc/2", "10.0.0.2"),
+		awsvpcTask("ecs-svc/1", "10.0.0.9"),
+	}
+	testCases := map[string]struct {
+		loadBalancers []*awsecs.LoadBalancer
+		tasks         []*ecs.Task
+		health        *mockECSTargetHealth
+
+		wantedOutcome ECSDeploymentOutcome
+		wantedErr     string
+	}{
+		"keeps the deployment in progress until its targets are healthy": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{TargetGroupArn: aws.String("tg-1")},
+				{TargetGroupArn: aws.String("tg-2")},
+			},
+			tasks: awsvpcTasks,
+			health: &mockECSTargetHealth{
+				healthy: map[string][]ECSTarget{
+					"tg-1": {{ID: "10.0.0.1", Port: 80}, {ID: "10.0.0.2", Port: 80}},
+					"tg-2": {{ID: "10.0.0.1", Port: 80}},
+				},
+			},
+		},
+		"completes the deployment once the targets of every target group are healthy": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{TargetGroupArn: aws.String("tg-1")},
+				{TargetGroupArn: aws.String("tg-2")},
+			},
+			tasks: awsvpcTasks,
+			health: &mockECSTargetHealth{
+				healthy: map[string][]ECSTarget{
+					"tg-1": {{ID: "10.0.0.1", Port: 80}, {ID: "10.0.0.2", Port: 80}},
+					"tg-2": {{ID: "10.0.0.1", Port: 80}, {ID: "10.0.0.2", Port: 80}},
+				},
+			},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"does not count the healthy targets of the tasks of previous deployments": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{TargetGroupArn: aws.String("tg-1")},
+			},
+			tasks: awsvpcTasks,
+			health: &mockECSTargetHealth{
+				healthy: map[string][]ECSTarget{
+					"tg-1": {{ID: "10.0.0.1", Port: 80}, {ID: "10.0.0.9", Port: 80}},
+				},
+			},
+		},
+		"matches the targets of tasks without a network interface by the host port of their containers": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{TargetGroupArn: aws.String("tg-1")},
+			},
+			tasks: []*ecs.Task{
+				bridgeTask("ecs-svc/2", 32768),
+				bridgeTask("ecs-svc/2", 32769),
+				bridgeTask("ecs-svc/1", 32770),
+			},
+			health: &mockECSTargetHealth{
+				healthy: map[string][]ECSTarget{
+					"tg-1": {{ID: "i-1", Port: 32768}, {ID: "i-2", Port: 32769}, {ID: "i-1", Port: 32770}},
+				},
+			},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"falls back to the running count for services without target groups": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{LoadBalancerName: aws.String("classic")},
+			},
+			health:        &mockECSTargetHealth{},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"returns a wrapped error if the targets cannot be counted": {
+			loadBalancers: []*awsecs.LoadBalancer{
+				{TargetGroupArn: aws.String("tg-1")},
+			},
+			health: &mockECSTargetHealth{
+				err: errors.New("some error"),
+			},
+			wantedErr: "count healthy targets in target group tg-1: some error",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{
+						{
+							Id:             aws.String("ecs-svc/2"),
+							DesiredCount:   aws.Int64(2),
+							RunningCount:   aws.Int64(2),
+							Status:         aws.String("PRIMARY"),
+							TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+						},
+					},
+					LoadBalancers: tc.loadBalancers,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+				WithTargetHealth(tc.health, mockECSTasks{out: tc.tasks}))
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			if tc.wantedErr != "" {
+				require.EqualError(t, err, tc.wantedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+		})
+	}
+	t.Run("does not stall while the running tasks wait for their targets to be healthy", func(t *testing.T) {
+		// GIVEN
+		m := &mockECS{
+			out: &ecs.Service{
+				Deployments: []*awsecs.Deployment{
+					{
+						Id:             aws.String("ecs-svc/2"),
+						DesiredCount:   aws.Int64(2),
+						RunningCount:   aws.Int64(2),
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					},
+				},
+				LoadBalancers: []*awsecs.LoadBalancer{{TargetGroupArn: aws.String("tg-1")}},
+			},
+		}
+		health := &mockECSTargetHealth{
+			healthy: map[string][]ECSTarget{"tg-1": {{ID: "10.0.0.1", Port: 80}}},
+		}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate,
+			WithTargetHealth(health, mockECSTasks{out: awsvpcTasks}), WithStallTimeout(5*time.Minute))
+		now := startDate
+		streamer.now = func() time.Time { return now }
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+
+		// WHEN
+		now = now.Add(10 * time.Minute)
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Empty(t, streamer.Outcome())
+	})
+}
+
+func TestECSDeploymentStreamer_FetchMissingKeyword(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	const benign = "(service my-svc) has started 1 tasks: (task 1234). Placing the task in the missing availability zone us-west-2c."
+	out := &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(1),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			},
+		},
+		Events: []*awsecs.ServiceEvent{
+			{
+				Id:        aws.String("1"),
+				Message:   aws.String(benign),
+				CreatedAt: aws.Time(startDate.Add(time.Minute)),
+			},
+		},
+	}
+	testCases := map[string]struct {
+		opts []ECSDeploymentStreamerOpt
+
+		wantedFailureEvents []string
+	}{
+		"ignores benign events containing missing by default": {},
+		"reports events containing missing as failures WithMissingKeyword": {
+			opts:                []ECSDeploymentStreamerOpt{WithMissingKeyword()},
+			wantedFailureEvents: []string{benign},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(mockECS{out: out}, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedFailureEvents, streamer.eventsToFlush[0].LatestFailureEvents)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchPriorDeployment(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	deployment := func(id, status string, revision int, running int64, rolloutState string, createdAt time.Time) *awsecs.Deployment {
+		return &awsecs.Deployment{
+			Id:             aws.String(id),
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(running),
+			Status:         aws.String(status),
+			RolloutState:   aws.String(rolloutState),
+			TaskDefinition: aws.String(fmt.Sprintf("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:%d", revision)),
+			CreatedAt:      aws.Time(createdAt),
+		}
+	}
+	waitingNotice := ECSNotice{
+		Kind:     ECSNoticeWaitingForPriorDeployment,
+		Severity: ECSNoticeInfo,
+		Message:  "waiting for deployment ecs-svc/1 on revision 1 to finish before the deployment starts",
+	}
+	testCases := map[string]struct {
+		outs []*ecs.Service
+		opts []ECSDeploymentStreamerOpt
+	}{
+		"queued deployment watched by id": {
+			outs: []*ecs.Service{
+				{Deployments: []*awsecs.Deployment{
+					deployment("ecs-svc/1", "PRIMARY", 1, 2, "IN_PROGRESS", startDate.Add(-time.Minute)),
+					deployment("ecs-svc/2", "ACTIVE", 2, 0, "IN_PROGRESS", startDate),
+				}},
+				{Deployments: []*awsecs.Deployment{
+					deployment("ecs-svc/2", "PRIMARY", 2, 2, "COMPLETED", startDate),
+				}},
+			},
+			opts: []ECSDeploymentStreamerOpt{WithDeploymentID("ecs-svc/2")},
+		},
+		"deployment not created yet": {
+			outs: []*ecs.Service{
+				{Deployments: []*awsecs.Deployment{
+					deployment("ecs-svc/1", "PRIMARY", 1, 2, "IN_PROGRESS", startDate.Add(-time.Minute)),
+				}},
+				{Deployments: []*awsecs.Deployment{
+					deployment("ecs-svc/2", "PRIMARY", 2, 2, "COMPLETED", startDate.Add(time.Second)),
+				}},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECSSequence{outs: tc.outs}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			// WHEN
+			for i := 0; i < len(tc.outs); i++ {
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			require.Len(t, streamer.eventsToFlush, 2)
+			waiting, started := streamer.eventsToFlush[0], streamer.eventsToFlush[1]
+			require.Equal(t, "ecs-svc/1", waiting.WaitingFor)
+			require.Equal(t, []ECSNotice{waitingNotice}, waiting.Notices)
+			require.Nil(t, waiting.Completion, "the counts of the prior deployment should not complete the watched one")
+			require.Empty(t, started.WaitingFor)
+			require.NotNil(t, started.Completion)
+			require.Equal(t, ECSDeploymentSucceeded, started.Completion.Outcome)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchMinimumHealthyPercent(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(desired, running int64, rolloutState string) *ecs.Service {
+		return &ecs.Service{
+			DeploymentConfiguration: &awsecs.DeploymentConfiguration{
+				MinimumHealthyPercent: aws.Int64(50),
+				MaximumPercent:        aws.Int64(200),
+			},
+			Deployments: []*awsecs.Deployment{
+				{
+					Id:             aws.String("ecs-svc/1"),
+					DesiredCount:   aws.Int64(desired),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String(rolloutState),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate),
+				},
+			},
+		}
+	}
+	// The desired count is lowered from 4 to 1 mid-rollout, before the deployment scales back up.
+	outs := []*ecs.Service{
+		service(4, 0, "IN_PROGRESS"),
+		service(1, 1, "IN_PROGRESS"),
+		service(2, 2, "IN_PROGRESS"),
+	}
+	testCases := map[string]struct {
+		outs []*ecs.Service
+		opts []ECSDeploymentStreamerOpt
+
+		wantedDoneAfter int // Number of fetches after which the deployment is done.
+	}{
+		"completes once the running count meets the lowered desired count by default": {
+			outs:            outs,
+			wantedDoneAfter: 2,
+		},
+		"waits for the minimum healthy percent of the initial desired count": {
+			outs:            outs,
+			opts:            []ECSDeploymentStreamerOpt{WithMinimumHealthyPercent()},
+			wantedDoneAfter: 3,
+		},
+		"waives the minimum healthy percent once the rollout is completed": {
+			outs: []*ecs.Service{
+				service(4, 0, "IN_PROGRESS"),
+				service(1, 1, "COMPLETED"),
+			},
+			opts:            []ECSDeploymentStreamerOpt{WithMinimumHealthyPercent()},
+			wantedDoneAfter: 2,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := &mockECSSequence{outs: tc.outs}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate, tc.opts...)
+			streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+			for i := 1; i <= len(tc.outs); i++ {
+				// WHEN
+				_, err := streamer.Fetch()
+
+				// THEN
+				require.NoError(t, err)
+				require.Equal(t, i >= tc.wantedDoneAfter, isClosed(streamer.Done()), "unexpected done state after fetch %d", i)
+			}
+			require.Equal(t, ECSDeploymentSucceeded, streamer.Outcome())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchAlreadyCompleted(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(running int64, rolloutState string) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String(rolloutState),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate.Add(time.Second)),
+				},
+			},
+		}
+	}
+	isAlreadyCompleted := func(notice ECSNotice) bool {
+		return notice.Kind == ECSNoticeAlreadyCompleted
+	}
+	t.Run("reports that the deployment already completed on the first fetch", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(mockECS{out: service(2, "COMPLETED")}, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Len(t, streamer.eventsToFlush, 1)
+		ev := streamer.eventsToFlush[0]
+		require.Len(t, ev.Notices, 1)
+		require.True(t, isAlreadyCompleted(ev.Notices[0]))
+		require.Equal(t, ECSDeploymentSucceeded, ev.Completion.Outcome)
+		require.True(t, isClosed(streamer.Done()))
+	})
+	t.Run("doesn't report a deployment that completes while watching", func(t *testing.T) {
+		// GIVEN
+		m := &mockECSSequence{outs: []*ecs.Service{service(1, "IN_PROGRESS"), service(2, "COMPLETED")}}
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+		streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+
+		// WHEN
+		for i := 0; i < 2; i++ {
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+
+		// THEN
+		for _, ev := range streamer.eventsToFlush {
+			for _, notice := range ev.Notices {
+				require.False(t, isAlreadyCompleted(notice))
+			}
+		}
+		require.NotNil(t, streamer.eventsToFlush[1].Completion)
+	})
+}
+
+func TestECSDeploymentStreamer_FetchNoChanges(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	deployment := func(status, rolloutState string, createdAt time.Time) *awsecs.Deployment {
+		return &awsecs.Deployment{
+			DesiredCount:   aws.Int64(2),
+			RunningCount:   aws.Int64(2),
+			Status:         aws.String(status),
+			RolloutState:   aws.String(rolloutState),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			CreatedAt:      aws.Time(createdAt),
+		}
+	}
+	testCases := map[string]struct {
+		deployments []*awsecs.Deployment
+
+		wantedOutcome ECSDeploymentOutcome
+	}{
+		"no changes if the completed primary deployment was created before the deployment creation time": {
+			deployments: []*awsecs.Deployment{deployment("PRIMARY", "COMPLETED", startDate.Add(-time.Hour))},
+
+			wantedOutcome: ECSDeploymentNoChanges,
+		},
+		"succeeds if the primary deployment was created since the deployment creation time": {
+			deployments: []*awsecs.Deployment{deployment("PRIMARY", "COMPLETED", startDate.Add(time.Second))},
+
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"succeeds if another deployment is still draining": {
+			deployments: []*awsecs.Deployment{
+				deployment("PRIMARY", "COMPLETED", startDate.Add(-time.Hour)),
+				deployment("ACTIVE", "COMPLETED", startDate.Add(-2*time.Hour)),
+			},
+
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: tc.deployments}}, "my-cluster", "my-svc", startDate)
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedOutcome, streamer.eventsToFlush[0].Completion.Outcome)
+		})
+	}
+	t.Run("is only detected on the first fetch", func(t *testing.T) {
+		// GIVEN
+		primary := deployment("PRIMARY", "IN_PROGRESS", startDate.Add(-time.Hour))
+		primary.RunningCount = aws.Int64(1)
+		streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}}, "my-cluster", "my-svc", startDate)
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+		primary.RunningCount, primary.RolloutState = aws.Int64(2), aws.String("COMPLETED")
+
+		// WHEN
+		_, err = streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Equal(t, ECSDeploymentSucceeded, streamer.Outcome())
+	})
+}
+
+func TestECSDeploymentStreamer_FetchPendingWarning(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	type fetch struct {
+		at      time.Duration // Time since startDate.
+		running int64
+		pending int64
+	}
+	testCases := map[string]struct {
+		fetches []fetch
+
+		wantedWarnings map[int]string // Warning message by index of the fetch that emitted it.
+	}{
+		"warns once if tasks stay pending without progress for the window": {
+			fetches: []fetch{
+				{at: 0, pending: 4},
+				{at: 2 * time.Minute, pending: 4},
+				{at: 5 * time.Minute, pending: 3},
+				{at: 6 * time.Minute, pending: 3},
+			},
+			wantedWarnings: map[int]string{
+				2: "3 tasks stuck in PENDING for 5m0s without any new running task, the tasks may not be placeable or the cluster may lack capacity",
+			},
+		},
+		"does not warn if the running count grows": {
+			fetches: []fetch{
+				{at: 0, pending: 4},
+				{at: 3 * time.Minute, running: 1, pending: 3},
+				{at: 6 * time.Minute, running: 2, pending: 3},
+			},
+		},
+		"does not warn below the threshold and warns again once stuck again": {
+			fetches: []fetch{
+				{at: 0, pending: 3},
+				{at: 5 * time.Minute, pending: 3},
+				{at: 6 * time.Minute, pending: 1},
+				{at: 7 * time.Minute, pending: 3},
+				{at: 12 * time.Minute, pending: 3},
+			},
+			wantedWarnings: map[int]string{
+				1: "3 tasks stuck in PENDING for 5m0s without any new running task, the tasks may not be placeable or the cluster may lack capacity",
+				4: "3 tasks stuck in PENDING for 5m0s without any new running task, the tasks may not be placeable or the cluster may lack capacity",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			primary := &awsecs.Deployment{
+				DesiredCount:   aws.Int64(6),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			}
+			streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}},
+				"my-cluster", "my-svc", startDate, WithPendingWarning(3, 5*time.Minute))
+
+			// WHEN
+			warnings := make(map[int]string)
+			for i, f := range tc.fetches {
+				now := startDate.Add(f.at)
+				streamer.now = func() time.Time { return now }
+				primary.RunningCount = aws.Int64(f.running)
+				primary.PendingCount = aws.Int64(f.pending)
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+				for _, notice := range streamer.eventsToFlush[i].Notices {
+					if notice.Kind == ECSNoticeTasksStuckPending {
+						require.Equal(t, ECSNoticeWarning, notice.Severity)
+						warnings[i] = notice.Message
+					}
+				}
+			}
+
+			// THEN
+			if tc.wantedWarnings == nil {
+				tc.wantedWarnings = make(map[int]string)
+			}
+			require.Equal(t, tc.wantedWarnings, warnings)
+			require.Empty(t, streamer.Outcome(), "the deployment should not fail")
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchDeploymentTimeouts(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		serviceCreatedAt time.Time
+		elapsed          time.Duration
+
+		wantedInitial bool
+		wantedOutcome ECSDeploymentOutcome
+		wantedReason  string
+	}{
+		"gives the initial deployment of a new service the initial timeout": {
+			serviceCreatedAt: startDate.Add(time.Second),
+			elapsed:          10 * time.Minute,
+
+			wantedInitial: true,
+		},
+		"fails the initial deployment once the initial timeout elapses": {
+			serviceCreatedAt: startDate.Add(time.Second),
+			elapsed:          20 * time.Minute,
+
+			wantedInitial: true,
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "initial deployment did not complete within 20m0s",
+		},
+		"fails the update of an existing service once the update timeout elapses": {
+			serviceCreatedAt: startDate.Add(-24 * time.Hour),
+			elapsed:          10 * time.Minute,
+
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "deployment did not complete within 5m0s",
+		},
+		"gives the update of an existing service the update timeout": {
+			serviceCreatedAt: startDate.Add(-24 * time.Hour),
+			elapsed:          4 * time.Minute,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			out := &ecs.Service{
+				CreatedAt: aws.Time(tc.serviceCreatedAt),
+				Deployments: []*awsecs.Deployment{
+					{
+						DesiredCount:   aws.Int64(2),
+						RunningCount:   aws.Int64(1),
+						Status:         aws.String("PRIMARY"),
+						TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					},
+				},
+			}
+			streamer := NewECSDeploymentStreamer(mockECS{out: out}, "my-cluster", "my-svc", startDate,
+				WithDeploymentTimeouts(20*time.Minute, 5*time.Minute))
+			now := startDate.Add(time.Minute)
+			streamer.now = func() time.Time { return now }
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			require.Empty(t, streamer.Outcome())
+
+			// WHEN
+			now = startDate.Add(tc.elapsed)
+			_, err = streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedInitial, streamer.IsInitialDeployment())
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedReason, streamer.FailureReason())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchProgressTracking(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	type fetch struct {
+		at      time.Duration // Time since startDate.
+		running int64
+		failed  int64
+	}
+	testCases := map[string]struct {
+		opts    []ECSDeploymentStreamerOpt
+		fetches []fetch
+
+		wantedOutcome ECSDeploymentOutcome
+		wantedReason  string
+	}{
+		"fails once the deployment stalls": {
+			opts: []ECSDeploymentStreamerOpt{WithStallTimeout(5 * time.Minute)},
+			fetches: []fetch{
+				{at: 0, running: 0},
+				{at: 2 * time.Minute, running: 1},
+				{at: 7 * time.Minute, running: 1},
+			},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "no new running task for 5m0s",
+		},
+		"recovers from a stall and completes": {
+			opts: []ECSDeploymentStreamerOpt{WithStallTimeout(5 * time.Minute)},
+			fetches: []fetch{
+				{at: 0, running: 0},
+				{at: 4 * time.Minute, running: 0},
+				{at: 6 * time.Minute, running: 1},
+				{at: 10 * time.Minute, running: 1},
+				{at: 11 * time.Minute, running: 2},
+			},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+		"fails fast once too many tasks failed without progress": {
+			opts: []ECSDeploymentStreamerOpt{WithMaxFailedTasks(3)},
+			fetches: []fetch{
+				{at: 0, running: 0, failed: 1},
+				{at: time.Minute, running: 0, failed: 4},
+			},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "3 tasks failed to start without any new running task",
+		},
+		"fails a deployment whose tasks crash and restart without running more tasks than before": {
+			opts: []ECSDeploymentStreamerOpt{WithStallTimeout(5 * time.Minute)},
+			fetches: []fetch{
+				{at: 0, running: 1},
+				{at: 2 * time.Minute, running: 0},
+				{at: 4 * time.Minute, running: 1},
+				{at: 6 * time.Minute, running: 0},
+			},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "no new running task for 6m0s",
+		},
+		"counts the failed tasks of a crash loop without progress": {
+			opts: []ECSDeploymentStreamerOpt{WithMaxFailedTasks(3)},
+			fetches: []fetch{
+				{at: 0, running: 1, failed: 0},
+				{at: time.Minute, running: 0, failed: 1},
+				{at: 2 * time.Minute, running: 1, failed: 2},
+				{at: 3 * time.Minute, running: 0, failed: 3},
+			},
+			wantedOutcome: ECSDeploymentFailed,
+			wantedReason:  "3 tasks failed to start without any new running task",
+		},
+		"resets the failed tasks count on progress": {
+			opts: []ECSDeploymentStreamerOpt{WithMaxFailedTasks(3)},
+			fetches: []fetch{
+				{at: 0, running: 0, failed: 2},
+				{at: time.Minute, running: 1, failed: 4},
+				{at: 2 * time.Minute, running: 1, failed: 6},
+				{at: 3 * time.Minute, running: 2, failed: 6},
+			},
+			wantedOutcome: ECSDeploymentSucceeded,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			primary := &awsecs.Deployment{
+				DesiredCount:   aws.Int64(2),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			}
+			streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}},
+				"my-cluster", "my-svc", startDate, tc.opts...)
+
+			// WHEN
+			for i, f := range tc.fetches {
+				now := startDate.Add(f.at)
+				streamer.now = func() time.Time { return now }
+				primary.RunningCount = aws.Int64(f.running)
+				primary.FailedTasks = aws.Int64(f.failed)
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+				if i < len(tc.fetches)-1 {
+					require.Empty(t, streamer.Outcome(), "fetch %d", i)
+				}
+			}
+
+			// THEN
+			require.Equal(t, tc.wantedOutcome, streamer.Outcome())
+			require.Equal(t, tc.wantedReason, streamer.FailureReason())
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchPrimaryDeploymentOnly(t *testing.T) {
+	// GIVEN
+	m := mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(2),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:3"),
+				},
+				{
+					DesiredCount:   aws.Int64(1),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("ACTIVE"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		},
+	}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), WithPrimaryDeploymentOnly())
+
+	// WHEN
+	_, err := streamer.Fetch()
+
+	// THEN
+	require.NoError(t, err)
+	ev := streamer.eventsToFlush[0]
+	require.Equal(t, []ECSDeployment{
+		{
+			Status:          "PRIMARY",
+			TaskDefRevision: "3",
+			DesiredCount:    2,
+			RunningCount:    1,
+		},
+	}, ev.Deployments)
+	require.Equal(t, 1, ev.OtherDeploymentsCount)
+	require.Len(t, streamer.DebugState().Deployments, 2, "the streamer should keep track of all deployments")
+}
+
+func TestECSService_Primary(t *testing.T) {
+	t.Run("returns the primary deployment", func(t *testing.T) {
+		// GIVEN
+		svc := ECSService{
+			Deployments: []ECSDeployment{
+				{Status: "ACTIVE", TaskDefRevision: "2"},
+				{Status: "PRIMARY", TaskDefRevision: "3"},
+			},
+		}
+
+		// WHEN
+		primary, ok := svc.Primary()
+
+		// THEN
+		require.True(t, ok)
+		require.Equal(t, "3", primary.TaskDefRevision)
+	})
+	t.Run("returns false without a primary deployment", func(t *testing.T) {
+		// WHEN
+		_, ok := ECSService{}.Primary()
+
+		// THEN
+		require.False(t, ok)
+	})
+}
+
+func TestECSService_RunningCountByRevision(t *testing.T) {
+	// GIVEN
+	svc := ECSService{
+		Deployments: []ECSDeployment{
+			{Status: "PRIMARY", TaskDefRevision: "7", RunningCount: 4},
+			{Status: "ACTIVE", TaskDefRevision: "6", RunningCount: 1},
+			{Status: "ACTIVE", TaskDefRevision: "7", RunningCount: 1},
+			{Status: "ACTIVE", TaskDefRevision: "5", RunningCount: 0},
+		},
+	}
+
+	// WHEN
+	counts := svc.RunningCountByRevision()
+
+	// THEN
+	require.Equal(t, map[string]int{"7": 5, "6": 1}, counts)
+}
+
+func TestECSDeploymentStreamer_FetchLaunchDetails(t *testing.T) {
+	m := mockECS{
+		out: &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:    aws.Int64(1),
+					RunningCount:    aws.Int64(0),
+					Status:          aws.String("PRIMARY"),
+					TaskDefinition:  aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					LaunchType:      aws.String("FARGATE"),
+					PlatformVersion: aws.String("1.4.0"),
+				},
+			},
+		},
+	}
+	t.Run("omits launch details by default", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		require.Empty(t, streamer.eventsToFlush[0].Deployments[0].LaunchType)
+		require.Empty(t, streamer.eventsToFlush[0].Deployments[0].PlatformVersion)
+	})
+	t.Run("sets launch details in snapshots and debug state if enabled", func(t *testing.T) {
+		// GIVEN
+		streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), WithLaunchDetails())
+
+		// WHEN
+		_, err := streamer.Fetch()
+
+		// THEN
+		require.NoError(t, err)
+		for _, deployment := range []ECSDeployment{streamer.eventsToFlush[0].Deployments[0], streamer.DebugState().Deployments[0]} {
+			require.Equal(t, "FARGATE", deployment.LaunchType)
+			require.Equal(t, "1.4.0", deployment.PlatformVersion)
+		}
+	})
+}
+
+func TestECSDeploymentStreamer_EventStaleness(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		events []*awsecs.ServiceEvent
+
+		wantedStaleness time.Duration
+	}{
+		"measures since the deployment creation time if there are no events": {
+			wantedStaleness: 10 * time.Minute,
+		},
+		"measures since the deployment creation time if events are older": {
+			events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String("(service my-svc) has reached a steady state."),
+					CreatedAt: aws.Time(startDate.Add(-1 * time.Hour)),
+				},
+			},
+			wantedStaleness: 10 * time.Minute,
+		},
+		"measures since the most recent event": {
+			events: []*awsecs.ServiceEvent{
+				{
+					Id:        aws.String("2"),
+					Message:   aws.String("(service my-svc) has started 1 tasks: (task 1234)."),
+					CreatedAt: aws.Time(startDate.Add(7 * time.Minute)),
+				},
+				{
+					Id:        aws.String("1"),
+					Message:   aws.String("(service my-svc) was unable to place a task."),
+					CreatedAt: aws.Time(startDate.Add(1 * time.Minute)),
+				},
+			},
+			wantedStaleness: 3 * time.Minute,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			m := mockECS{
+				out: &ecs.Service{
+					Events: tc.events,
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time {
+				return startDate.Add(10 * time.Minute)
+			}
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			require.Equal(t, tc.wantedStaleness, streamer.EventStaleness())
+			require.Equal(t, tc.wantedStaleness, streamer.eventsToFlush[0].EventStaleness)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_RecentSnapshots(t *testing.T) {
+	fetchRunningCounts := func(t *testing.T, streamer *ECSDeploymentStreamer, primary *awsecs.Deployment, counts ...int64) {
+		for _, count := range counts {
+			primary.RunningCount = aws.Int64(count)
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+		}
+	}
+	newPrimary := func() *awsecs.Deployment {
+		return &awsecs.Deployment{
+			Status:         aws.String("PRIMARY"),
+			TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			DesiredCount:   aws.Int64(10),
+		}
+	}
+	t.Run("returns the last snapshots oldest first", func(t *testing.T) {
+		// GIVEN
+		primary := newPrimary()
+		streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}},
+			"my-cluster", "my-svc", time.Now(), WithRecentSnapshots(3))
+		fetchRunningCounts(t, streamer, primary, 0, 1, 2, 3, 4)
+
+		// WHEN
+		snapshots := streamer.RecentSnapshots()
+
+		// THEN
+		var counts []int
+		for _, snapshot := range snapshots {
+			counts = append(counts, snapshot.Deployments[0].RunningCount)
+		}
+		require.Equal(t, []int{2, 3, 4}, counts)
+	})
+	t.Run("retains no snapshots if disabled", func(t *testing.T) {
+		// GIVEN
+		primary := newPrimary()
+		streamer := NewECSDeploymentStreamer(mockECS{out: &ecs.Service{Deployments: []*awsecs.Deployment{primary}}},
+			"my-cluster", "my-svc", time.Now(), WithRecentSnapshots(0))
+		fetchRunningCounts(t, streamer, primary, 0, 1)
+
+		// WHEN
+		snapshots := streamer.RecentSnapshots()
+
+		// THEN
+		require.Empty(t, snapshots)
+	})
+}
+
+func TestECSDeploymentStreamer_RunningCountRange(t *testing.T) {
+	// GIVEN
+	service := func(running int64) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(4),
+					RunningCount:   aws.Int64(running),
+					Status:         aws.String("PRIMARY"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+				},
+			},
+		}
+	}
+	m := &mockECSSequence{outs: []*ecs.Service{service(2), service(3), service(1), service(2)}}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now())
+	min, max := streamer.RunningCountRange()
+	require.Zero(t, min)
+	require.Zero(t, max)
+
+	// WHEN
+	for i := 0; i < 4; i++ {
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+	}
+
+	// THEN
+	min, max = streamer.RunningCountRange()
+	require.Equal(t, 1, min)
+	require.Equal(t, 3, max)
+	state := streamer.DebugState()
+	require.Equal(t, 1, state.MinRunningCount)
+	require.Equal(t, 3, state.MaxRunningCount)
+}
+
+func TestECSDeploymentStreamer_FailureCategoryCounts(t *testing.T) {
+	// GIVEN
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	event := func(id, msg string) *awsecs.ServiceEvent {
+		return &awsecs.ServiceEvent{
+			Id:        aws.String(id),
+			Message:   aws.String(msg),
+			CreatedAt: aws.Time(startDate.Add(time.Minute)),
+		}
+	}
+	const (
+		registerFailure = "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)"
+		imageFailure    = "(service my-svc) failed to launch a task: missing image my-svc:abc"
+		placeFailure    = "(service my-svc) was unable to place a task."
+	)
+	m := &mockECSSequence{outs: []*ecs.Service{
+		{Events: []*awsecs.ServiceEvent{event("2", imageFailure), event("1", registerFailure)}},
+		{Events: []*awsecs.ServiceEvent{event("4", imageFailure), event("3", placeFailure), event("2", imageFailure)}},
+	}}
+	streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+	streamer.now = func() time.Time { return startDate.Add(time.Minute) }
+	require.Empty(t, streamer.FailureCategoryCounts())
+
+	// WHEN
+	for i := 0; i < 2; i++ {
+		_, err := streamer.Fetch()
+		require.NoError(t, err)
+	}
+
+	// THEN
+	wanted := map[ECSFailureCategory]int{
+		ECSFailureCategoryNetworking:      1,
+		ECSFailureCategoryMissingResource: 2,
+		ECSFailureCategoryUnknown:         1,
+	}
+	require.Equal(t, wanted, streamer.FailureCategoryCounts())
+	require.Equal(t, map[ECSFailureCategory]int{
+		ECSFailureCategoryNetworking:      1,
+		ECSFailureCategoryMissingResource: 1,
+	}, streamer.eventsToFlush[0].FailureCategoryCounts, "the counts of a description should not change once it's emitted")
+	require.Equal(t, wanted, streamer.eventsToFlush[1].FailureCategoryCounts)
+	require.Contains(t, FormatECSServiceJSON(streamer.eventsToFlush[1]), `"failureCategoryCounts":{"missing_resource":2,"networking":1,"unknown":1}`)
+}
+
+func TestECSDeploymentStreamer_FetchLoadBalancers(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(lbs ...*awsecs.LoadBalancer) *ecs.Service {
+		return &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:   aws.Int64(1),
+					RunningCount:   aws.Int64(1),
+					Status:         aws.String("PRIMARY"),
+					RolloutState:   aws.String("COMPLETED"),
+					TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:      aws.Time(startDate),
+				},
+			},
+			LoadBalancers: lbs,
+		}
+	}
+	const tgARN = "arn:aws:elasticloadbalancing:us-west-2:1111:targetgroup/my-tg/1234"
+	testCases := map[string]struct {
+		out  *ecs.Service
+		opts []ECSDeploymentStreamerOpt
+
+		wanted []ECSLoadBalancer
+	}{
+		"reports the load balancers of the service": {
+			out: service(&awsecs.LoadBalancer{
+				TargetGroupArn: aws.String(tgARN),
+				ContainerName:  aws.String("my-svc"),
+				ContainerPort:  aws.Int64(8080),
+			}, &awsecs.LoadBalancer{
+				LoadBalancerName: aws.String("my-classic-lb"),
+				ContainerName:    aws.String("my-svc"),
+				ContainerPort:    aws.Int64(80),
+			}),
+			opts: []ECSDeploymentStreamerOpt{WithLoadBalancers()},
+			wanted: []ECSLoadBalancer{
+				{TargetGroupARN: tgARN, ContainerName: "my-svc", ContainerPort: 8080},
+				{LoadBalancerName: "my-classic-lb", ContainerName: "my-svc", ContainerPort: 80},
+			},
+		},
+		"reports none if the service has no load balancer": {
+			out:  service(),
+			opts: []ECSDeploymentStreamerOpt{WithLoadBalancers()},
+		},
+		"reports none unless enabled": {
+			out: service(&awsecs.LoadBalancer{
+				TargetGroupArn: aws.String(tgARN),
+				ContainerName:  aws.String("my-svc"),
+				ContainerPort:  aws.Int64(8080),
+			}),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			streamer := NewECSDeploymentStreamer(mockECS{out: tc.out}, "my-cluster", "my-svc", startDate, tc.opts...)
+
+			// WHEN
+			_, err := streamer.Fetch()
+
+			// THEN
+			require.NoError(t, err)
+			completion := streamer.eventsToFlush[0].Completion
+			require.NotNil(t, completion)
+			require.Equal(t, tc.wanted, completion.LoadBalancers)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_DeployDuration(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	primary := &awsecs.Deployment{
+		DesiredCount:   aws.Int64(2),
+		RunningCount:   aws.Int64(0),
+		RolloutState:   aws.String("IN_PROGRESS"),
+		Status:         aws.String("PRIMARY"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+	}
+	testCases := map[string]struct {
+		update func(d *awsecs.Deployment)
+
+		wantedOutcome    ECSDeploymentOutcome
+		wantedMaxRunning int
+	}{
+		"reports the elapsed time of a successful deployment": {
+			update: func(d *awsecs.Deployment) {
+				d.RunningCount = aws.Int64(2)
+			},
+			wantedOutcome:    ECSDeploymentSucceeded,
+			wantedMaxRunning: 2,
+		},
+		"reports the elapsed time of a failed deployment": {
+			update: func(d *awsecs.Deployment) {
+				d.RolloutState = aws.String("FAILED")
+			},
+			wantedOutcome: ECSDeploymentFailed,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			deployment := *primary
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{&deployment},
+				},
+			}
+			now := startDate.Add(1 * time.Minute)
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time {
+				return now
+			}
+
+			// WHEN
+			_, err := streamer.Fetch()
+			require.NoError(t, err)
+			require.Zero(t, streamer.DeployDuration(), "there should be no duration while in progress")
+			now = startDate.Add(2*time.Minute + 13*time.Second)
+			tc.update(&deployment)
+			_, err = streamer.Fetch()
+			require.NoError(t, err)
+
+			// THEN
+			require.Equal(t, 2*time.Minute+13*time.Second, streamer.DeployDuration())
+			require.Nil(t, streamer.eventsToFlush[0].Completion)
+			require.Equal(t, &ECSDeploymentCompletion{
+				Outcome:     tc.wantedOutcome,
+				StartedAt:   startDate,
+				CompletedAt: now,
+				Elapsed:     2*time.Minute + 13*time.Second,
+
+				MaxRunningCount: tc.wantedMaxRunning,
+			}, streamer.eventsToFlush[1].Completion)
+		})
+	}
+}
+
+func TestECSDeploymentStreamer_FetchRolloutStateReason(t *testing.T) {
+	reasons := []string{
+		"ECS deployment ecs-svc/1 in progress.",
+		"ECS deployment ecs-svc/1 in progress.",
+		"ECS deployment ecs-svc/1 in progress.",
+		"ECS deployment circuit breaker: rolling back to deploymentId ecs-svc/0.",
+		"ECS deployment circuit breaker: rolling back to deploymentId ecs-svc/0.",
+	}
+	testCases := map[string]struct {
+		opts []ECSDeploymentStreamerOpt
+
+		wantedReasons []string
+	}{
+		"emits the reason on every description by default": {
+			wantedReasons: reasons,
+		},
+		"emits the reason only when it changes": {
+			opts: []ECSDeploymentStreamerOpt{WithDedupedRolloutStateReasons()},
+			wantedReasons: []string{
+				"ECS deployment ecs-svc/1 in progress.",
+				"",
+				"",
+				"ECS deployment circuit breaker: rolling back to deploymentId ecs-svc/0.",
+				"",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			deployment := &awsecs.Deployment{
+				Id:             aws.String("ecs-svc/1"),
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(0),
+				RolloutState:   aws.String("IN_PROGRESS"),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			}
+			m := mockECS{
+				out: &ecs.Service{
+					Deployments: []*awsecs.Deployment{deployment},
+				},
+			}
+			streamer := NewECSDeploymentStreamer(m, "my-cluster", "my-svc", time.Now(), tc.opts...)
+
+			// WHEN
+			for _, reason := range reasons {
+				deployment.RolloutStateReason = aws.String(reason)
+				_, err := streamer.Fetch()
+				require.NoError(t, err)
+			}
+
+			// THEN
+			var actual []string
+			for _, ev := range streamer.eventsToFlush {
+				actual = append(actual, ev.Deployments[0].RolloutStateReason)
+			}
+			require.Equal(t, tc.wantedReasons, actual)
+		})
+	}
 }
@@ -0,0 +1,80 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"context"
+	"fmt"
+)
+
+const ecsDeploymentRolledBackReason = "deployment was rolled back"
+
+// DeploymentFailure is the error returned by Wait when the deployment of a service does not succeed.
+type DeploymentFailure struct {
+	Service string
+	Outcome ECSDeploymentOutcome
+
+	// Reason is a single reason explaining the failure, see ECSDeploymentStreamer.FailureReason.
+	Reason string
+	// Category is the category of the failure service event used as Reason, or ECSFailureCategoryUnknown
+	// if the reason does not come from a classified service event.
+	Category ECSFailureCategory
+	// Failures are all the failure service events observed while waiting, oldest first.
+	Failures []ECSServiceFailure
+	// RolloutCause is why ECS failed the rollout, empty if the deployment was failed by the streamer.
+	RolloutCause ECSRolloutFailureCause
+	// StoppedReasons are the distinct reasons why the tasks of the deployment stopped, most recent first.
+	// They are only set if the streamer is created WithStoppedTaskReasons.
+	StoppedReasons []string
+}
+
+// Error implements the error interface.
+func (e *DeploymentFailure) Error() string {
+	return fmt.Sprintf("deployment of service %s failed: %s", e.Service, e.Reason)
+}
+
+// Wait streams the deployment until it is done and returns nil if it succeeded or if there were no changes to deploy.
+// If the deployment did not succeed, Wait returns a *DeploymentFailure that holds the failures observed while waiting.
+// If the context is canceled or the service can't be fetched, Wait returns the error from Stream.
+// The streamer is closed once Wait returns, like with Stream.
+func Wait(ctx context.Context, streamer *ECSDeploymentStreamer) error {
+	events := streamer.Subscribe()
+	collected := make(chan []ECSServiceFailure)
+	go func() {
+		var failures []ECSServiceFailure
+		for ev := range events {
+			failures = append(failures, ev.LatestFailures...)
+		}
+		collected <- failures
+	}()
+
+	err := Stream(ctx, streamer) // Closes the subscribed channel once it returns.
+	failures := <-collected
+	if err != nil {
+		return err
+	}
+
+	outcome := streamer.Outcome()
+	if outcome.isSuccessful() {
+		return nil
+	}
+	failure := &DeploymentFailure{
+		Service:      streamer.Service(),
+		Outcome:      outcome,
+		Reason:       streamer.FailureReason(),
+		Category:     ECSFailureCategoryUnknown,
+		Failures:     failures,
+		RolloutCause: streamer.RolloutFailureCause(),
+	}
+	if reasons := streamer.StoppedTaskReasons(); len(reasons) > 0 {
+		failure.StoppedReasons = reasons
+	}
+	if outcome == ECSDeploymentRolledBack {
+		failure.Reason = ecsDeploymentRolledBackReason
+	}
+	if category := streamer.FailureCategory(); category != "" && outcome != ECSDeploymentRolledBack {
+		failure.Category = category
+	}
+	return failure
+}
@@ -0,0 +1,181 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/stretchr/testify/require"
+)
+
+// mockECSSequence returns the next service description on each call, and the last one once they're exhausted.
+type mockECSSequence struct {
+	outs  []*ecs.Service
+	calls int
+}
+
+func (m *mockECSSequence) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	out := m.outs[m.calls]
+	if m.calls < len(m.outs)-1 {
+		m.calls++
+	}
+	return out, nil
+}
+
+func TestWait(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	service := func(running int64, rolloutState, rolloutReason string, events ...string) *ecs.Service {
+		out := &ecs.Service{
+			Deployments: []*awsecs.Deployment{
+				{
+					DesiredCount:       aws.Int64(2),
+					RunningCount:       aws.Int64(running),
+					Status:             aws.String("PRIMARY"),
+					RolloutState:       aws.String(rolloutState),
+					RolloutStateReason: aws.String(rolloutReason),
+					TaskDefinition:     aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+					CreatedAt:          aws.Time(startDate),
+				},
+			},
+		}
+		for _, msg := range events {
+			out.Events = append(out.Events, &awsecs.ServiceEvent{
+				Id:        aws.String(msg),
+				Message:   aws.String(msg),
+				CreatedAt: aws.Time(startDate.Add(time.Minute)),
+			})
+		}
+		return out
+	}
+	const (
+		registerFailure = "(service my-svc) failed to register targets in (target-group 1234) with (error some-error)"
+		placeFailure    = "(service my-svc) was unable to place a task because no container instance met all of its requirements."
+	)
+	testCases := map[string]struct {
+		outs []*ecs.Service
+		ctx  func() context.Context
+
+		wantedErr     error
+		wantedFailure *DeploymentFailure
+	}{
+		"returns nil once the deployment succeeds": {
+			outs: []*ecs.Service{
+				service(1, "IN_PROGRESS", ""),
+				service(2, "COMPLETED", ""),
+			},
+		},
+		"returns nil if there were no changes to deploy": {
+			outs: []*ecs.Service{
+				func() *ecs.Service {
+					out := service(2, "COMPLETED", "")
+					out.Deployments[0].CreatedAt = aws.Time(startDate.Add(-time.Hour))
+					return out
+				}(),
+			},
+		},
+		"returns the context error if it is canceled": {
+			outs: []*ecs.Service{
+				service(1, "IN_PROGRESS", ""),
+			},
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			wantedErr: context.Canceled,
+		},
+		"returns a failure categorized by the latest classified failure event": {
+			outs: []*ecs.Service{
+				service(1, "IN_PROGRESS", "", placeFailure),
+				service(0, "FAILED", "ECS deployment circuit breaker: tasks failed to start.", registerFailure),
+			},
+			wantedFailure: &DeploymentFailure{
+				Service:  "my-svc",
+				Outcome:  ECSDeploymentFailed,
+				Reason:   registerFailure,
+				Category: ECSFailureCategoryNetworking,
+				Failures: []ECSServiceFailure{
+					{Message: placeFailure, Category: ECSFailureCategoryUnknown},
+					{Message: registerFailure, Category: ECSFailureCategoryNetworking},
+				},
+				RolloutCause: ECSRolloutFailureCauseCircuitBreaker,
+			},
+		},
+		"returns an uncategorized failure if the reason is not the classified failure event": {
+			outs: []*ecs.Service{
+				service(1, "IN_PROGRESS", "", registerFailure),
+				service(0, "FAILED", "ECS deployment timed out."),
+			},
+			wantedFailure: &DeploymentFailure{
+				Service:  "my-svc",
+				Outcome:  ECSDeploymentFailed,
+				Reason:   ecsDeploymentTimedOutReason,
+				Category: ECSFailureCategoryUnknown,
+				Failures: []ECSServiceFailure{
+					{Message: registerFailure, Category: ECSFailureCategoryNetworking},
+				},
+				RolloutCause: ECSRolloutFailureCauseTimeout,
+			},
+		},
+		"returns a failure with the rollout state reason if no failure event is classified": {
+			outs: []*ecs.Service{
+				service(0, "FAILED", "ECS deployment circuit breaker: tasks failed to start.", placeFailure),
+			},
+			wantedFailure: &DeploymentFailure{
+				Service:  "my-svc",
+				Outcome:  ECSDeploymentFailed,
+				Reason:   "ECS deployment circuit breaker: tasks failed to start.",
+				Category: ECSFailureCategoryUnknown,
+				Failures: []ECSServiceFailure{
+					{Message: placeFailure, Category: ECSFailureCategoryUnknown},
+				},
+				RolloutCause: ECSRolloutFailureCauseCircuitBreaker,
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// GIVEN
+			ctx := context.Background()
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+			streamer := NewECSDeploymentStreamer(&mockECSSequence{outs: tc.outs}, "my-cluster", "my-svc", startDate)
+			streamer.now = func() time.Time { return startDate } // Fetch again without waiting.
+
+			// WHEN
+			err := Wait(ctx, streamer)
+
+			// THEN
+			switch {
+			case tc.wantedErr != nil:
+				require.True(t, errors.Is(err, tc.wantedErr))
+			case tc.wantedFailure != nil:
+				var failure *DeploymentFailure
+				require.True(t, errors.As(err, &failure))
+				require.Equal(t, tc.wantedFailure, failure)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeploymentFailure_Error(t *testing.T) {
+	// GIVEN
+	err := &DeploymentFailure{
+		Service: "my-svc",
+		Outcome: ECSDeploymentRolledBack,
+		Reason:  ecsDeploymentRolledBackReason,
+	}
+
+	// THEN
+	require.EqualError(t, err, "deployment of service my-svc failed: deployment was rolled back")
+}
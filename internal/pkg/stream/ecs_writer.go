@@ -0,0 +1,275 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ecsStatusLineMaxFailureLen = 60 // Maximum number of characters of the failure appended to a status line.
+
+// Types and phases of the lines written by FormatECSServiceJSON.
+const (
+	ecsJSONTypeStart      = "start"
+	ecsJSONTypeSnapshot   = "snapshot"
+	ecsJSONTypeCompletion = "completion"
+	ecsJSONTypeError      = "error"
+
+	ecsJSONPhaseInProgress = "in_progress"
+)
+
+// Attributes of the CloudEvents written by a formatter created with NewECSCloudEventFormatter.
+const (
+	ecsCloudEventSpecVersion     = "1.0"
+	ecsCloudEventDataContentType = "application/json"
+
+	// ECSCloudEventTypeStart is the type of the CloudEvent of a start event, see WithStartEvent.
+	ECSCloudEventTypeStart = "com.aws.copilot.deploy.start"
+	// ECSCloudEventTypeProgress is the type of the CloudEvent of a snapshot while the deployment is in progress.
+	ECSCloudEventTypeProgress = "com.aws.copilot.deploy.progress"
+	// ECSCloudEventTypeCompletion is the type of the CloudEvent of the last snapshot once the deployment is done.
+	ECSCloudEventTypeCompletion = "com.aws.copilot.deploy.completion"
+)
+
+// ECSServiceFormatter formats a snapshot of the service as text to write.
+type ECSServiceFormatter func(ECSService) string
+
+// ecsEventWriter writes formatted snapshots of a service, skipping the ones that are unchanged.
+type ecsEventWriter struct {
+	w        io.Writer
+	format   ECSServiceFormatter
+	lastText string
+}
+
+// WithEventWriter writes every snapshot of the service to w as formatted text followed by a new line,
+// as an alternative to subscribing to the streamer. Snapshots formatted into the same text as the
+// previously written one are skipped. If format is nil, FormatECSService is used.
+// Writers can be combined with subscribers, and the option can be repeated to write to multiple writers.
+// Errors from w are ignored.
+func WithEventWriter(w io.Writer, format ECSServiceFormatter) ECSDeploymentStreamerOpt {
+	if format == nil {
+		format = FormatECSService
+	}
+	return func(s *ECSDeploymentStreamer) {
+		s.writers = append(s.writers, &ecsEventWriter{
+			w:      w,
+			format: format,
+		})
+	}
+}
+
+// FormatECSService is the default ECSServiceFormatter.
+// It formats the deployments of the service on a single line, followed by a line per notice and failure.
+// For example:
+//
+//	PRIMARY (rev 3): 2/3 running, 1 pending, 0 failed; ACTIVE (rev 2): 1/1 running, 0 pending, 0 failed
+//	WARNING: primary deployment changed from revision 2 to 3, another deployment may have started concurrently
+//	FAILURE: (service my-svc) failed to launch a task.
+func FormatECSService(svc ECSService) string {
+	var deployments []string
+	for _, d := range svc.Deployments {
+		deployments = append(deployments, fmt.Sprintf("%s (rev %s): %d/%d running, %d pending, %d failed",
+			d.Status, d.TaskDefRevision, d.RunningCount, d.DesiredCount, d.PendingCount, d.FailedCount))
+	}
+	lines := []string{strings.Join(deployments, "; ")}
+	for _, notice := range svc.Notices {
+		lines = append(lines, fmt.Sprintf("%s: %s", notice.Severity, notice.Message))
+	}
+	for _, msg := range svc.LatestFailureEvents {
+		lines = append(lines, fmt.Sprintf("FAILURE: %s", msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatECSServiceVerbose is an ECSServiceFormatter that formats the snapshot like FormatECSService,
+// followed by a line with the number of running tasks by task definition revision, most recent revision first,
+// and a line with the drain progress of the previous deployments if the snapshot has any. For example:
+//
+//	PRIMARY (rev 7): 4/5 running, 1 pending, 0 failed; ACTIVE (rev 6): 1/1 running, 0 pending, 0 failed
+//	RUNNING: 4 on rev 7, 1 on rev 6
+//	DRAINING: rev 6 from 4 to 1
+func FormatECSServiceVerbose(svc ECSService) string {
+	counts := svc.RunningCountByRevision()
+	if len(counts) == 0 {
+		return FormatECSService(svc)
+	}
+	revisions := make([]string, 0, len(counts))
+	for revision := range counts {
+		revisions = append(revisions, revision)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		a, errA := strconv.Atoi(revisions[i])
+		b, errB := strconv.Atoi(revisions[j])
+		if errA != nil || errB != nil {
+			return revisions[i] > revisions[j]
+		}
+		return a > b
+	})
+	var running []string
+	for _, revision := range revisions {
+		running = append(running, fmt.Sprintf("%d on rev %s", counts[revision], revision))
+	}
+	text := fmt.Sprintf("%s\nRUNNING: %s", FormatECSService(svc), strings.Join(running, ", "))
+	if len(svc.Draining) == 0 {
+		return text
+	}
+	var draining []string
+	for _, d := range svc.Draining {
+		draining = append(draining, fmt.Sprintf("rev %s from %d to %d", d.TaskDefRevision, d.FromCount, d.RunningCount))
+	}
+	return fmt.Sprintf("%s\nDRAINING: %s", text, strings.Join(draining, ", "))
+}
+
+// StatusLine returns a concise single line describing the primary deployment of the service named name,
+// suitable to overwrite the previous status in a terminal with a carriage return. For example:
+//
+//	webapp: 3/5 running, 1 pending, rev 7 [IN_PROGRESS]
+//
+// The state in brackets is the outcome of the deployment once it completed. The most recent failure,
+// if any, is appended and truncated so that the line stays short.
+func (s ECSService) StatusLine(name string) string {
+	primary, ok := s.Primary()
+	var status string
+	switch {
+	case !ok:
+		status = fmt.Sprintf("%s: waiting for the deployment to start", name)
+	case s.WaitingFor != "":
+		status = fmt.Sprintf("%s: waiting for deployment %s on rev %s to finish", name, s.WaitingFor, primary.TaskDefRevision)
+	case primary.DesiredCount == 0:
+		status = fmt.Sprintf("%s: no desired tasks, rev %s", name, primary.TaskDefRevision)
+	default:
+		status = fmt.Sprintf("%s: %d/%d running, %d pending, rev %s",
+			name, primary.RunningCount, primary.DesiredCount, primary.PendingCount, primary.TaskDefRevision)
+	}
+	state := primary.RolloutState
+	if s.Completion != nil {
+		state = string(s.Completion.Outcome)
+	}
+	if state != "" {
+		status = fmt.Sprintf("%s [%s]", status, state)
+	}
+	if len(s.LatestFailureEvents) > 0 { // Failure events are sorted from newest to oldest.
+		status = fmt.Sprintf("%s - %s", status, truncate(s.LatestFailureEvents[0], ecsStatusLineMaxFailureLen))
+	}
+	return status
+}
+
+// truncate shortens msg to at most max characters, ending with an ellipsis if it was truncated.
+func truncate(msg string, max int) string {
+	runes := []rune(msg)
+	if len(runes) <= max {
+		return msg
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// ecsServiceJSONLine is the object written by FormatECSServiceJSON for a snapshot of the service.
+type ecsServiceJSONLine struct {
+	Type  string `json:"type"`
+	Phase string `json:"phase"`
+	ECSService
+}
+
+// FormatECSServiceJSON is an ECSServiceFormatter that formats a snapshot as a single line JSON object,
+// so that a writer created WithEventWriter emits newline-delimited JSON.
+// The "type" of the object is "start" for the start event of a streamer created WithStartEvent,
+// "completion" for the last snapshot of the deployment and "snapshot" otherwise,
+// and its "phase" is "in_progress" until the deployment completes with the lowercased outcome, such as "succeeded".
+// The fields of the snapshot are included in the object.
+func FormatECSServiceJSON(svc ECSService) string {
+	line := ecsServiceJSONLine{
+		Type:       ecsJSONTypeSnapshot,
+		Phase:      ecsJSONPhaseInProgress,
+		ECSService: svc,
+	}
+	if svc.Start != nil {
+		line.Type = ecsJSONTypeStart
+	}
+	if svc.Completion != nil {
+		line.Type = ecsJSONTypeCompletion
+		line.Phase = strings.ToLower(string(svc.Completion.Outcome))
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		// Keep the output valid JSON lines even if the snapshot can't be marshaled.
+		data, _ = json.Marshal(map[string]string{
+			"type":    ecsJSONTypeError,
+			"message": fmt.Sprintf("marshal service snapshot: %v", err),
+		})
+	}
+	return string(data)
+}
+
+// ECSCloudEvent is a snapshot of the service wrapped in a CloudEvent following the CloudEvents 1.0 JSON format.
+type ECSCloudEvent struct {
+	SpecVersion     string     `json:"specversion"`
+	ID              string     `json:"id"`
+	Source          string     `json:"source"`
+	Type            string     `json:"type"`
+	Time            time.Time  `json:"time"`
+	DataContentType string     `json:"datacontenttype"`
+	Data            ECSService `json:"data"`
+}
+
+// NewECSCloudEventFormatter returns an ECSServiceFormatter that formats each snapshot as a single line
+// CloudEvent in JSON, so that a writer created WithEventWriter emits events for an event-driven pipeline.
+// The source identifies the deployment producer as a URI-reference, such as "/copilot/my-app/test/my-svc".
+// Each formatted event gets an ID that is unique for the formatter, so a formatter shouldn't be shared across sources.
+// The type of the event is ECSCloudEventTypeStart, ECSCloudEventTypeProgress or ECSCloudEventTypeCompletion.
+func NewECSCloudEventFormatter(source string) ECSServiceFormatter {
+	return newECSCloudEventFormatter(source, time.Now)
+}
+
+func newECSCloudEventFormatter(source string, now func() time.Time) ECSServiceFormatter {
+	var mu sync.Mutex
+	var count int
+	idPrefix := strconv.FormatInt(now().UnixNano(), 36)
+	return func(svc ECSService) string {
+		mu.Lock()
+		count++
+		id := idPrefix + "-" + strconv.Itoa(count)
+		mu.Unlock()
+
+		event := ECSCloudEvent{
+			SpecVersion:     ecsCloudEventSpecVersion,
+			ID:              id,
+			Source:          source,
+			Type:            ECSCloudEventTypeProgress,
+			Time:            now().UTC(),
+			DataContentType: ecsCloudEventDataContentType,
+			Data:            svc,
+		}
+		if svc.Start != nil {
+			event.Type = ECSCloudEventTypeStart
+		}
+		if svc.Completion != nil {
+			event.Type = ECSCloudEventTypeCompletion
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			// Keep the output valid JSON lines even if the snapshot can't be marshaled.
+			data, _ = json.Marshal(map[string]string{
+				"type":    ecsJSONTypeError,
+				"message": fmt.Sprintf("marshal service snapshot: %v", err),
+			})
+		}
+		return string(data)
+	}
+}
+
+func (w *ecsEventWriter) write(svc ECSService) {
+	text := w.format(svc)
+	if text == w.lastText {
+		return
+	}
+	w.lastText = text
+	fmt.Fprintln(w.w, text)
+}
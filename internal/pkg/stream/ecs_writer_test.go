@@ -0,0 +1,252 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatECSService(t *testing.T) {
+	// GIVEN
+	svc := ECSService{
+		Deployments: []ECSDeployment{
+			{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 3, RunningCount: 2, PendingCount: 1},
+			{Status: "ACTIVE", TaskDefRevision: "2", DesiredCount: 1, RunningCount: 1},
+		},
+		Notices: []ECSNotice{
+			{Severity: ECSNoticeWarning, Message: "some warning"},
+		},
+		LatestFailureEvents: []string{"(service my-svc) failed to launch a task."},
+	}
+
+	// WHEN
+	text := FormatECSService(svc)
+
+	// THEN
+	require.Equal(t, `PRIMARY (rev 3): 2/3 running, 1 pending, 0 failed; ACTIVE (rev 2): 1/1 running, 0 pending, 0 failed
+WARNING: some warning
+FAILURE: (service my-svc) failed to launch a task.`, text)
+}
+
+func TestFormatECSServiceVerbose(t *testing.T) {
+	// GIVEN
+	svc := ECSService{
+		Deployments: []ECSDeployment{
+			{Status: "PRIMARY", TaskDefRevision: "10", DesiredCount: 5, RunningCount: 4, PendingCount: 1},
+			{Status: "ACTIVE", TaskDefRevision: "9", DesiredCount: 1, RunningCount: 1},
+			{Status: "ACTIVE", TaskDefRevision: "8", DesiredCount: 0, RunningCount: 0},
+		},
+	}
+
+	// WHEN
+	text := FormatECSServiceVerbose(svc)
+
+	// THEN
+	require.Equal(t, `PRIMARY (rev 10): 4/5 running, 1 pending, 0 failed; ACTIVE (rev 9): 1/1 running, 0 pending, 0 failed; ACTIVE (rev 8): 0/0 running, 0 pending, 0 failed
+RUNNING: 4 on rev 10, 1 on rev 9`, text)
+
+	// WHEN
+	svc.Draining = []ECSDrainProgress{
+		{TaskDefRevision: "9", FromCount: 4, RunningCount: 1},
+		{TaskDefRevision: "8", FromCount: 2, RunningCount: 0},
+	}
+	text = FormatECSServiceVerbose(svc)
+
+	// THEN
+	require.Equal(t, `PRIMARY (rev 10): 4/5 running, 1 pending, 0 failed; ACTIVE (rev 9): 1/1 running, 0 pending, 0 failed; ACTIVE (rev 8): 0/0 running, 0 pending, 0 failed
+RUNNING: 4 on rev 10, 1 on rev 9
+DRAINING: rev 9 from 4 to 1, rev 8 from 2 to 0`, text)
+}
+
+func TestECSService_StatusLine(t *testing.T) {
+	testCases := map[string]struct {
+		svc ECSService
+
+		wanted string
+	}{
+		"no deployments": {
+			wanted: "webapp: waiting for the deployment to start",
+		},
+		"deployment in progress": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "7", DesiredCount: 5, RunningCount: 3, PendingCount: 1, RolloutState: "IN_PROGRESS"},
+					{Status: "ACTIVE", TaskDefRevision: "6", DesiredCount: 1, RunningCount: 1},
+				},
+			},
+			wanted: "webapp: 3/5 running, 1 pending, rev 7 [IN_PROGRESS]",
+		},
+		"waiting for a prior deployment": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "6", DesiredCount: 5, RunningCount: 3, RolloutState: "IN_PROGRESS"},
+				},
+				WaitingFor: "ecs-svc/1234",
+			},
+			wanted: "webapp: waiting for deployment ecs-svc/1234 on rev 6 to finish [IN_PROGRESS]",
+		},
+		"zero desired tasks": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "7"},
+				},
+			},
+			wanted: "webapp: no desired tasks, rev 7",
+		},
+		"completed deployment": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "7", DesiredCount: 5, RunningCount: 5, RolloutState: "COMPLETED"},
+				},
+				Completion: &ECSDeploymentCompletion{Outcome: ECSDeploymentSucceeded},
+			},
+			wanted: "webapp: 5/5 running, 0 pending, rev 7 [SUCCEEDED]",
+		},
+		"truncates the most recent failure": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "7", DesiredCount: 5, RunningCount: 3, PendingCount: 1},
+				},
+				LatestFailureEvents: []string{
+					"(service webapp) failed to register targets in (target-group 1234) with (error some-error)",
+					"(service webapp) was unable to place a task.",
+				},
+			},
+			wanted: "webapp: 3/5 running, 1 pending, rev 7 - (service webapp) failed to register targets in (target-gr...",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.wanted, tc.svc.StatusLine("webapp"))
+		})
+	}
+}
+
+func TestFormatECSServiceJSON(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		svc ECSService
+
+		wantedLine string
+	}{
+		"in progress snapshot": {
+			svc: ECSService{
+				Deployments: []ECSDeployment{
+					{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1, PendingCount: 1},
+				},
+				LatestFailures: []ECSServiceFailure{
+					{Message: "(service my-svc) failed to launch a task.", Category: ECSFailureCategoryUnknown},
+				},
+			},
+			wantedLine: `{"type":"snapshot","phase":"in_progress","deployments":[{"status":"PRIMARY","taskDefRevision":"3","desiredCount":2,"runningCount":1,"failedCount":0,"pendingCount":1}],` +
+				`"latestFailures":[{"message":"(service my-svc) failed to launch a task.","category":"unknown"}],"eventStaleness":0}`,
+		},
+		"start event": {
+			svc: ECSService{
+				Start: &ECSDeploymentStart{
+					DeploymentCreationTime: startDate,
+					WatchStartedAt:         startDate.Add(time.Second),
+				},
+			},
+			wantedLine: `{"type":"start","phase":"in_progress","deployments":null,"eventStaleness":0,` +
+				`"start":{"deploymentCreationTime":"2020-11-23T18:00:00Z","watchStartedAt":"2020-11-23T18:00:01Z"}}`,
+		},
+		"failed completion": {
+			svc: ECSService{
+				Completion: &ECSDeploymentCompletion{
+					Outcome:     ECSDeploymentFailed,
+					StartedAt:   startDate,
+					CompletedAt: startDate.Add(time.Second),
+					Elapsed:     time.Second,
+				},
+			},
+			wantedLine: `{"type":"completion","phase":"failed","deployments":null,` +
+				`"completion":{"outcome":"FAILED","startedAt":"2020-11-23T18:00:00Z","completedAt":"2020-11-23T18:00:01Z","elapsed":1000000000,"minRunningCount":0,"maxRunningCount":0},"eventStaleness":0}`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// WHEN
+			line := FormatECSServiceJSON(tc.svc)
+
+			// THEN
+			require.Equal(t, tc.wantedLine, line)
+		})
+	}
+}
+
+func TestNewECSCloudEventFormatter(t *testing.T) {
+	startDate := time.Date(2020, time.November, 23, 18, 0, 0, 0, time.UTC)
+	format := newECSCloudEventFormatter("/copilot/my-app/test/my-svc", func() time.Time { return startDate })
+	snapshots := []ECSService{
+		{Start: &ECSDeploymentStart{DeploymentCreationTime: startDate, WatchStartedAt: startDate}},
+		{Deployments: []ECSDeployment{{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 2, RunningCount: 1}}},
+		{Completion: &ECSDeploymentCompletion{Outcome: ECSDeploymentSucceeded, StartedAt: startDate, CompletedAt: startDate}},
+	}
+
+	// WHEN
+	var events []map[string]interface{}
+	for _, svc := range snapshots {
+		line := format(svc)
+		require.NotContains(t, line, "\n")
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+
+	// THEN
+	wantedTypes := []string{ECSCloudEventTypeStart, ECSCloudEventTypeProgress, ECSCloudEventTypeCompletion}
+	ids := make(map[interface{}]bool)
+	for i, event := range events {
+		require.Equal(t, "1.0", event["specversion"])
+		require.Equal(t, "/copilot/my-app/test/my-svc", event["source"])
+		require.Equal(t, wantedTypes[i], event["type"])
+		require.Equal(t, "2020-11-23T18:00:00Z", event["time"])
+		require.Equal(t, "application/json", event["datacontenttype"])
+		require.NotEmpty(t, event["id"])
+		ids[event["id"]] = true
+	}
+	require.Len(t, ids, len(events), "each event should have a unique id")
+	data := events[1]["data"].(map[string]interface{})
+	require.Equal(t, "3", data["deployments"].([]interface{})[0].(map[string]interface{})["taskDefRevision"])
+}
+
+func TestECSDeploymentStreamer_NotifyWriters(t *testing.T) {
+	// GIVEN
+	var defaultOut, customOut strings.Builder
+	streamer := NewECSDeploymentStreamer(mockECS{}, "my-cluster", "my-svc", time.Now(),
+		WithEventWriter(&defaultOut, nil),
+		WithEventWriter(&customOut, func(svc ECSService) string {
+			return svc.Deployments[0].Status
+		}))
+	sub := streamer.Subscribe()
+	primary := ECSDeployment{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 1}
+	streamer.eventsToFlush = []ECSService{
+		{Deployments: []ECSDeployment{primary}},
+		{Deployments: []ECSDeployment{primary}},
+		{Deployments: []ECSDeployment{{Status: "PRIMARY", TaskDefRevision: "3", DesiredCount: 1, RunningCount: 1}}},
+	}
+
+	// WHEN
+	notified := make(chan struct{})
+	go func() {
+		streamer.Notify()
+		close(notified)
+	}()
+	for i := 0; i < 3; i++ {
+		<-sub
+	}
+	<-notified
+
+	// THEN
+	require.Equal(t, `PRIMARY (rev 3): 0/1 running, 0 pending, 0 failed
+PRIMARY (rev 3): 1/1 running, 0 pending, 0 failed
+`, defaultOut.String())
+	require.Equal(t, "PRIMARY\n", customOut.String())
+}
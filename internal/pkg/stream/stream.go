@@ -31,14 +31,33 @@ type Streamer interface {
 	Done() <-chan struct{}
 }
 
+// scheduledEmitter is implemented by streamers that publish event updates on a fixed schedule
+// instead of after each Fetch, such as an ECSDeploymentStreamer created WithEmitSchedule.
+type scheduledEmitter interface {
+	emitInterval() time.Duration // Zero if the streamer publishes event updates after each Fetch.
+	emitScheduled()
+}
+
 // Stream streams event updates by calling Fetch followed with Notify until there are no more events left.
+// Streamers that publish event updates on a fixed schedule are also notified at each tick of the schedule.
 // If the context is canceled or Fetch errors, then Stream short-circuits and returns the error.
 func Stream(ctx context.Context, streamer Streamer) error {
 	defer streamer.Close()
 
+	var tick <-chan time.Time // Never ready unless the streamer publishes on a schedule.
+	emitter, ok := streamer.(scheduledEmitter)
+	if ok && emitter.emitInterval() > 0 {
+		ticker := time.NewTicker(emitter.emitInterval())
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 	var next time.Time
 	var err error
 	for {
+		if err := ctx.Err(); err != nil {
+			// Check for cancellation first, select picks randomly among ready cases.
+			return err
+		}
 		var fetchDelay time.Duration // By default there is no delay.
 		if now := time.Now(); next.After(now) {
 			fetchDelay = next.Sub(now)
@@ -51,6 +70,8 @@ func Stream(ctx context.Context, streamer Streamer) error {
 			// No more events to Fetch, flush and exit successfully.
 			streamer.Notify()
 			return nil
+		case <-tick:
+			emitter.emitScheduled()
 		case <-time.After(fetchDelay):
 			next, err = streamer.Fetch()
 			if err != nil {
@@ -60,6 +60,21 @@ func (s *errStreamer) Done() <-chan struct{} {
 	return s.done
 }
 
+// scheduledStreamer counts the number of times it's notified on its schedule.
+type scheduledStreamer struct {
+	counterStreamer
+	interval  time.Duration
+	tickCount int
+}
+
+func (s *scheduledStreamer) emitInterval() time.Duration {
+	return s.interval
+}
+
+func (s *scheduledStreamer) emitScheduled() {
+	s.tickCount += 1
+}
+
 func TestStream(t *testing.T) {
 	t.Run("short-circuits immediately if context is canceled", func(t *testing.T) {
 		// GIVEN
@@ -136,4 +151,27 @@ func TestStream(t *testing.T) {
 		require.Greater(t, streamer.fetchCount, 1, "expected more than one call to Fetch within timeout")
 		require.Greater(t, streamer.notifyCount, 1, "expected more than one call to Notify within timeout")
 	})
+
+	t.Run("notifies streamers on their schedule independently of Fetch", func(t *testing.T) {
+		t.Parallel()
+		// GIVEN
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		streamer := &scheduledStreamer{
+			counterStreamer: counterStreamer{
+				next: func() time.Time {
+					return time.Now().Add(time.Hour)
+				},
+			},
+			interval: 50 * time.Millisecond,
+		}
+
+		// WHEN
+		err := Stream(ctx, streamer)
+
+		// THEN
+		require.EqualError(t, err, ctx.Err().Error(), "the error returned should be context canceled")
+		require.Equal(t, 1, streamer.fetchCount, "expected a single call to Fetch within timeout")
+		require.Greater(t, streamer.tickCount, 1, "expected more than one tick within timeout")
+	})
 }
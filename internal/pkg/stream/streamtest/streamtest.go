@@ -0,0 +1,106 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package streamtest provides helpers to test code that consumes the streamers of the stream package.
+package streamtest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/stream"
+)
+
+// ECSServiceStep is a scripted response of an ECSServiceDescriber.
+type ECSServiceStep struct {
+	Service *ecs.Service
+	Err     error
+}
+
+// ECSServiceDescriber is a fake stream.ECSServiceDescriber that returns its steps in order, one per call.
+// Once all the steps are returned, the last step is returned for every subsequent call.
+// Without any step, the service is reported as missing with ecs.ErrServiceNotFound.
+type ECSServiceDescriber struct {
+	mu    sync.Mutex
+	steps []ECSServiceStep
+	calls int
+}
+
+// NewECSServiceDescriber creates an ECSServiceDescriber that returns steps in order.
+func NewECSServiceDescriber(steps ...ECSServiceStep) *ECSServiceDescriber {
+	return &ECSServiceDescriber{
+		steps: steps,
+	}
+}
+
+// Service returns the next scripted step.
+func (d *ECSServiceDescriber) Service(clusterName, serviceName string) (*ecs.Service, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if len(d.steps) == 0 {
+		return nil, ecs.ErrServiceNotFound
+	}
+	i := d.calls - 1
+	if i >= len(d.steps) {
+		i = len(d.steps) - 1
+	}
+	return d.steps[i].Service, d.steps[i].Err
+}
+
+// Calls returns the number of times the service was described.
+func (d *ECSServiceDescriber) Calls() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.calls
+}
+
+// RequireOutcome drives the streamer by calling Fetch followed with Notify, without waiting between fetches,
+// until its Done channel is closed and returns the service descriptions received by a subscriber.
+// The test fails immediately if Fetch errors, if the streamer isn't done before the timeout,
+// or if the deployment completes with another outcome than the wanted one.
+// The streamer is closed once RequireOutcome returns.
+func RequireOutcome(t testing.TB, streamer *stream.ECSDeploymentStreamer, wanted stream.ECSDeploymentOutcome, timeout time.Duration) []stream.ECSService {
+	t.Helper()
+
+	sub := streamer.Subscribe()
+	collected := make(chan []stream.ECSService)
+	go func() {
+		var events []stream.ECSService
+		for ev := range sub {
+			events = append(events, ev)
+		}
+		collected <- events
+	}()
+
+	deadline := time.Now().Add(timeout)
+	var err error
+	for !isDone(streamer) && err == nil && time.Now().Before(deadline) {
+		_, err = streamer.Fetch()
+		streamer.Notify()
+	}
+	streamer.Close()
+	events := <-collected
+
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !isDone(streamer) {
+		t.Fatalf("streamer is not done after %s", timeout)
+	}
+	if got := streamer.Outcome(); got != wanted {
+		t.Fatalf("deployment outcome is %q, wanted %q", got, wanted)
+	}
+	return events
+}
+
+func isDone(streamer *stream.ECSDeploymentStreamer) bool {
+	select {
+	case <-streamer.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright Amazon.com, Inc. or its affiliates. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package streamtest
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	awsecs "github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/aws/ecs"
+	"github.com/aws/copilot-cli/internal/pkg/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// fatalTB records the first fatal failure of a test and stops the goroutine that reported it.
+type fatalTB struct {
+	testing.TB
+	msg string
+}
+
+func (t *fatalTB) Helper() {}
+
+func (t *fatalTB) Fatalf(format string, args ...interface{}) {
+	t.msg = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func service(running int64) *ecs.Service {
+	return &ecs.Service{
+		Deployments: []*awsecs.Deployment{
+			{
+				DesiredCount:   aws.Int64(2),
+				RunningCount:   aws.Int64(running),
+				Status:         aws.String("PRIMARY"),
+				TaskDefinition: aws.String("arn:aws:ecs:us-west-2:1111:task-definition/myapp-test-mysvc:2"),
+			},
+		},
+	}
+}
+
+func TestECSServiceDescriber_Service(t *testing.T) {
+	// GIVEN
+	first, last := service(1), service(2)
+	describer := NewECSServiceDescriber(
+		ECSServiceStep{Service: first},
+		ECSServiceStep{Err: errors.New("some error")},
+		ECSServiceStep{Service: last},
+	)
+
+	// WHEN
+	var outs []*ecs.Service
+	var errs []error
+	for i := 0; i < 4; i++ {
+		out, err := describer.Service("my-cluster", "my-svc")
+		outs, errs = append(outs, out), append(errs, err)
+	}
+
+	// THEN
+	require.Equal(t, []*ecs.Service{first, nil, last, last}, outs)
+	require.EqualError(t, errs[1], "some error")
+	require.NoError(t, errs[3])
+	require.Equal(t, 4, describer.Calls())
+}
+
+func TestRequireOutcome(t *testing.T) {
+	newStreamer := func() *stream.ECSDeploymentStreamer {
+		describer := NewECSServiceDescriber(
+			ECSServiceStep{Service: service(0)},
+			ECSServiceStep{Service: service(1)},
+			ECSServiceStep{Service: service(2)},
+		)
+		return stream.NewECSDeploymentStreamer(describer, "my-cluster", "my-svc", time.Now())
+	}
+	t.Run("returns the collected events once the deployment completes with the wanted outcome", func(t *testing.T) {
+		// WHEN
+		events := RequireOutcome(t, newStreamer(), stream.ECSDeploymentSucceeded, time.Second)
+
+		// THEN
+		require.Len(t, events, 3)
+		require.Equal(t, 2, events[2].Deployments[0].RunningCount)
+		require.NotNil(t, events[2].Completion)
+	})
+	t.Run("fails the test if the deployment completes with another outcome", func(t *testing.T) {
+		// GIVEN
+		tb := &fatalTB{TB: t}
+		done := make(chan struct{})
+
+		// WHEN
+		go func() {
+			defer close(done)
+			RequireOutcome(tb, newStreamer(), stream.ECSDeploymentFailed, time.Second)
+		}()
+		<-done
+
+		// THEN
+		require.Equal(t, `deployment outcome is "SUCCEEDED", wanted "FAILED"`, tb.msg)
+	})
+}